@@ -2,8 +2,14 @@ package config
 
 import (
 	"fmt"
+	"net"
+	"net/url"
 	"os"
 	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
 
 	uniqueid "github.com/albinj12/unique-id"
 	"github.com/go-playground/validator/v10"
@@ -27,9 +33,17 @@ func SetConfigNames(dirName, filename string) {
 
 type Config struct {
 	instance      Provider
-	Servers       []Server      `yaml:"servers" validate:"dive"`
-	UISettings    UISettings    `yaml:"ui_settings" validate:"required"`
-	Notifications Notifications `yaml:"notifications"`
+	Servers       []Server         `yaml:"servers" validate:"dive"`
+	UISettings    UISettings       `yaml:"ui_settings" validate:"required"`
+	Notifications Notifications    `yaml:"notifications"`
+	Connection    Connection       `yaml:"connection"`
+	Ignores       []Ignore         `yaml:"ignores,omitempty" validate:"dive"`
+	Highlights    []HighlightRule  `yaml:"highlights,omitempty" validate:"dive"`
+	Logging       Logging          `yaml:"logging"`
+	LinkPreviews  LinkPreviews     `yaml:"link_previews"`
+	EventFilter   EventFilter      `yaml:"event_filter"`
+	Windows       []WindowSettings `yaml:"windows,omitempty" validate:"dive"`
+	InputHistory  InputHistory     `yaml:"input_history"`
 }
 
 func NewConfig(provider Provider) *Config {
@@ -54,6 +68,21 @@ func GetUserCacheDir() string {
 	return filepath.Join(cacheDir, GetConfigDirName())
 }
 
+// GetHistoryDir returns the directory that history imported from other clients is stored in
+func GetHistoryDir() string {
+	return filepath.Join(GetUserConfigDir(), "history")
+}
+
+// GetInputHistoryFile returns the file that lines typed into the input are saved to
+func GetInputHistoryFile() string {
+	return filepath.Join(GetUserConfigDir(), "input_history.json")
+}
+
+// GetLinkPreviewDir returns the directory that link previews are cached in
+func GetLinkPreviewDir() string {
+	return filepath.Join(GetUserCacheDir(), "previews")
+}
+
 func GetConfigDirName() string {
 	return configDirName
 }
@@ -72,6 +101,93 @@ type Server struct {
 	Profile      Profile `yaml:"profile" validate:"required"`
 	ID           string  `yaml:"id"`
 	AutoConnect  bool    `yaml:"auto_connect"`
+	Proxy        string  `yaml:"proxy,omitempty" validate:"omitempty,proxy_url"`
+	// Alternates are tried in turn after Hostname when a connection fails
+	Alternates []ServerAddress `yaml:"alternates,omitempty" validate:"dive"`
+	// Shuffle randomises the order servers are tried in
+	Shuffle bool `yaml:"shuffle,omitempty"`
+}
+
+// ServerAddress is a single server that can be connected to as part of a network
+type ServerAddress struct {
+	Hostname string `yaml:"hostname" validate:"required,hostname_rfc1123|ip"`
+	Port     int    `yaml:"port" validate:"min=1,max=65535"`
+	TLS      bool   `yaml:"tls"`
+}
+
+// GetAddresses returns the primary server followed by any alternates
+func (s Server) GetAddresses() []ServerAddress {
+	return append([]ServerAddress{{Hostname: s.Hostname, Port: s.Port, TLS: s.TLS}}, s.Alternates...)
+}
+
+// String formats the address as hostname:port, prefixing the port with + for TLS, IPv6 addresses are bracketed
+func (a ServerAddress) String() string {
+	port := strconv.Itoa(a.Port)
+	if a.TLS {
+		port = "+" + port
+	}
+	return net.JoinHostPort(a.Hostname, port)
+}
+
+// ParseServerAddress parses an address in the form hostname[:[+]port], a + before the port enables TLS.  IPv6 addresses
+// need brackets when a port is given, [::1]:+6697, and can be bare without one.  If no port is given the TLS setting
+// is taken from defaultTLS and the standard port is used.
+func ParseServerAddress(address string, defaultTLS bool) (ServerAddress, error) {
+	address = strings.TrimSpace(address)
+	hostname, port, found := address, "", false
+	switch {
+	case strings.HasPrefix(address, "[") && strings.HasSuffix(address, "]"):
+		hostname = address[1 : len(address)-1]
+	case strings.HasPrefix(address, "["):
+		var err error
+		if hostname, port, err = net.SplitHostPort(address); err != nil {
+			return ServerAddress{}, fmt.Errorf("invalid address %q", address)
+		}
+		found = true
+	case strings.Count(address, ":") == 1:
+		hostname, port, found = strings.Cut(address, ":")
+	}
+	if hostname == "" {
+		return ServerAddress{}, fmt.Errorf("no hostname specified")
+	}
+	result := ServerAddress{Hostname: hostname, TLS: defaultTLS}
+	if found {
+		result.TLS = strings.HasPrefix(port, "+")
+		portNum, err := strconv.Atoi(strings.TrimPrefix(port, "+"))
+		if err != nil || portNum < 1 || portNum > 65535 {
+			return ServerAddress{}, fmt.Errorf("invalid port %q", port)
+		}
+		result.Port = portNum
+	} else if result.TLS {
+		result.Port = 6697
+	} else {
+		result.Port = 6667
+	}
+	return result, nil
+}
+
+// Connection holds settings that apply to every server connection
+type Connection struct {
+	// Proxy is used for any server that doesn't specify its own proxy
+	Proxy string `yaml:"proxy,omitempty" validate:"omitempty,proxy_url"`
+	// ReconnectMinDelay is the delay before the first reconnection attempt, doubling each time up to ReconnectMaxDelay
+	ReconnectMinDelay time.Duration `yaml:"reconnect_min_delay"`
+	ReconnectMaxDelay time.Duration `yaml:"reconnect_max_delay" validate:"gtefield=ReconnectMinDelay"`
+	// ReconnectMaxAttempts stops reconnecting after this many failures, 0 retries forever
+	ReconnectMaxAttempts int `yaml:"reconnect_max_attempts" validate:"min=0"`
+	// PingInterval is how often the server is pinged to check the connection is alive, negative values disable this
+	PingInterval time.Duration `yaml:"ping_interval"`
+	// PingTimeout is how long to wait for a reply before treating the connection as dead
+	PingTimeout time.Duration `yaml:"ping_timeout"`
+	// FloodBurst is how many lines can be sent at once before outgoing messages are throttled
+	FloodBurst int `yaml:"flood_burst" validate:"min=0"`
+	// FloodInterval is how often another line can be sent once the burst is used up, negative values disable throttling
+	FloodInterval time.Duration `yaml:"flood_interval"`
+	// WhoInterval is how often a channel's users are refreshed with WHO on servers without away-notify, one channel is
+	// refreshed each time, negative values disable this
+	WhoInterval time.Duration `yaml:"who_interval"`
+	// WhoMaxUsers skips sending WHO to channels with more users than this, when joining and refreshing
+	WhoMaxUsers int `yaml:"who_max_users" validate:"min=0"`
 }
 
 type UISettings struct {
@@ -81,8 +197,24 @@ type UISettings struct {
 	UploadURL       string `yaml:"upload-url,omitempty" validate:"omitempty,http_url"`
 	UploadAPIKey    string `yaml:"upload-api-key,omitempty"`
 	UploadMethod    string `yaml:"upload-method,omitempty" validate:"omitempty,oneof=POST PUT post put"`
+	// BanMask is the style of mask used when banning or quieting a nickname, defaults to BanMaskHost
+	BanMask string `yaml:"ban_mask,omitempty" validate:"omitempty,oneof=nick host userhost domain"`
+	// NickCompletionSuffix is added after a nickname tab completed at the start of the line, defaults to ": ", use
+	// NickCompletionSuffixNone to add nothing
+	NickCompletionSuffix string `yaml:"nick_completion_suffix"`
 }
 
+const (
+	// BanMaskNick bans nick!*@*
+	BanMaskNick = "nick"
+	// BanMaskHost bans *!*@host
+	BanMaskHost = "host"
+	// BanMaskUserHost bans *!*user@host
+	BanMaskUserHost = "userhost"
+	// BanMaskDomain bans *!*@*.domain, or the /24 of an IPv4 address
+	BanMaskDomain = "domain"
+)
+
 type Profile struct {
 	Nickname string `yaml:"nickname" validate:"required,min=1,max=30"`
 }
@@ -101,6 +233,151 @@ type NotificationTrigger struct {
 	DebounceDuration time.Duration `yaml:"debounce_duration"`
 }
 
+const (
+	IgnoreMessages = "messages"
+	IgnoreNotices  = "notices"
+	IgnoreCTCP     = "ctcp"
+	IgnoreInvites  = "invites"
+	IgnoreJoins    = "joins"
+)
+
+// IgnoreTypes lists the kinds of event that can be ignored, joins includes parts
+var IgnoreTypes = []string{IgnoreMessages, IgnoreNotices, IgnoreCTCP, IgnoreInvites, IgnoreJoins}
+
+// Ignore hides events from anyone matching Mask, either a nick!user@host wildcard mask or $a:account
+type Ignore struct {
+	Mask string `yaml:"mask" validate:"required"`
+	// Network limits the ignore to the server with this ID
+	Network string `yaml:"network,omitempty"`
+	// Channel limits the ignore to a single channel
+	Channel string `yaml:"channel,omitempty"`
+	// Types limits which events are ignored, all types are ignored when empty
+	Types []string `yaml:"types,omitempty" validate:"dive,oneof=messages notices ctcp invites joins"`
+}
+
+const (
+	// MembershipShow shows every join, part and quit
+	MembershipShow = "show"
+	// MembershipSmart only shows joins, parts and quits from users who have spoken recently
+	MembershipSmart = "smart"
+	// MembershipHide hides every join, part and quit
+	MembershipHide = "hide"
+)
+
+// MembershipModes lists the ways joins, parts and quits can be shown
+var MembershipModes = []string{MembershipShow, MembershipSmart, MembershipHide}
+
+// EventFilter controls how joins, parts and quits are shown, messages are filtered when they're displayed so the
+// settings apply to history too.  Individual windows can override Membership in their WindowSettings.
+type EventFilter struct {
+	// Membership is how joins, parts and quits are shown in channels without their own setting
+	Membership string `yaml:"membership" validate:"omitempty,oneof=show smart hide"`
+	// SmartDuration is how recently a user must have spoken, before leaving or after joining, for the smart filter
+	// to show the event
+	SmartDuration time.Duration `yaml:"smart_duration" validate:"min=0"`
+	// Collapse combines consecutive joins, parts and quits into a single line
+	Collapse bool `yaml:"collapse"`
+	// Windows is where the setting for individual channels used to be stored, they're moved to Config.Windows when
+	// the config is loaded
+	Windows []WindowEventFilter `yaml:"windows,omitempty"`
+}
+
+// WindowEventFilter is the old per-channel membership setting, replaced by WindowSettings.Membership
+type WindowEventFilter struct {
+	Network    string `yaml:"network"`
+	Channel    string `yaml:"channel"`
+	Membership string `yaml:"membership"`
+}
+
+// InputHistory controls the lines recalled with the up and down arrows in the input
+type InputHistory struct {
+	// Global shares a single history between every window instead of keeping one for each window
+	Global bool `yaml:"global"`
+	// Size is the number of lines kept for each history, 0 uses the default of 100
+	Size int `yaml:"size" validate:"min=0"`
+	// NoSave keeps the history in memory only, so nothing typed is written to disk
+	NoSave bool `yaml:"no_save,omitempty"`
+}
+
+const (
+	// NotifyAll checks every message against the notification triggers
+	NotifyAll = "all"
+	// NotifyHighlights only checks highlighted messages against the notification triggers
+	NotifyHighlights = "highlights"
+	// NotifyNone never shows notifications
+	NotifyNone = "none"
+)
+
+// NotifyLevels lists the notification levels a window can have
+var NotifyLevels = []string{NotifyAll, NotifyHighlights, NotifyNone}
+
+// WindowSettings overrides settings for a single channel or query, empty values use the global settings
+type WindowSettings struct {
+	// Network is the ID of the server the window is on
+	Network string `yaml:"network" validate:"required"`
+	Window  string `yaml:"window" validate:"required"`
+	// Notify limits which messages can show notifications, defaults to NotifyAll
+	Notify string `yaml:"notify,omitempty" validate:"omitempty,oneof=all highlights none"`
+	// Muted stops messages and events marking the window as unread, highlights still do
+	Muted bool `yaml:"muted,omitempty"`
+	// Membership is how joins, parts and quits are shown, overriding EventFilter.Membership
+	Membership string `yaml:"membership,omitempty" validate:"omitempty,oneof=show smart hide"`
+	// Highlights are extra words that highlight messages in the window
+	Highlights []string `yaml:"highlights,omitempty"`
+	// NoLog stops the window being logged when logging is enabled
+	NoLog bool `yaml:"no_log,omitempty"`
+}
+
+// IsDefault checks if the settings don't change anything, so they don't need to be stored
+func (w WindowSettings) IsDefault() bool {
+	return (w.Notify == "" || w.Notify == NotifyAll) && !w.Muted && w.Membership == "" && len(w.Highlights) == 0 &&
+		!w.NoLog
+}
+
+// moveWindowEventFilters moves the membership setting of individual channels from the event filter, where it was
+// stored before windows had their own settings.  A window's own setting wins if both are set.
+func (c *Config) moveWindowEventFilters() {
+	for _, legacy := range c.EventFilter.Windows {
+		index := slices.IndexFunc(c.Windows, func(window WindowSettings) bool {
+			return window.Network == legacy.Network && strings.EqualFold(window.Window, legacy.Channel)
+		})
+		if index == -1 {
+			c.Windows = append(c.Windows, WindowSettings{Network: legacy.Network, Window: legacy.Channel, Membership: legacy.Membership})
+		} else if c.Windows[index].Membership == "" {
+			c.Windows[index].Membership = legacy.Membership
+		}
+	}
+	c.EventFilter.Windows = nil
+}
+
+// HighlightRule highlights messages containing Pattern, or with ExcludeNick set stops messages from nicknames matching
+// Pattern from highlighting at all.  Your current nickname always highlights as a whole word.
+type HighlightRule struct {
+	Pattern string `yaml:"pattern" validate:"required"`
+	// Regex treats Pattern as a regular expression rather than plain text
+	Regex         bool `yaml:"regex,omitempty"`
+	WholeWord     bool `yaml:"whole_word,omitempty"`
+	CaseSensitive bool `yaml:"case_sensitive,omitempty"`
+	// Network limits the rule to the server with this ID
+	Network string `yaml:"network,omitempty"`
+	// Channel limits the rule to a single channel or query
+	Channel     string `yaml:"channel,omitempty"`
+	ExcludeNick bool   `yaml:"exclude_nick,omitempty"`
+}
+
+// ValidateHighlightRule checks the rule has a pattern, and that it compiles if it is a regex
+func ValidateHighlightRule(rule HighlightRule) error {
+	if rule.Pattern == "" {
+		return fmt.Errorf("no pattern specified")
+	}
+	if rule.Regex {
+		if _, err := regexp.Compile(rule.Pattern); err != nil {
+			return fmt.Errorf("invalid highlight regex %q: %w", rule.Pattern, err)
+		}
+	}
+	return nil
+}
+
 func (c *Config) Load() error {
 	slog.Debug("Loading config")
 	if err := c.instance.Load(c); err != nil {
@@ -109,9 +386,18 @@ func (c *Config) Load() error {
 
 	c.applyDefaults()
 
-	if err := validator.New(validator.WithRequiredStructEnabled()).Struct(c); err != nil {
+	validate := validator.New(validator.WithRequiredStructEnabled())
+	if err := validate.RegisterValidation("proxy_url", validateProxyURL); err != nil {
+		return err
+	}
+	if err := validate.Struct(c); err != nil {
 		return fmt.Errorf("config validation failed: %w", err)
 	}
+	for i := range c.Highlights {
+		if err := ValidateHighlightRule(c.Highlights[i]); err != nil {
+			return fmt.Errorf("config validation failed: %w", err)
+		}
+	}
 	return nil
 }
 
@@ -119,6 +405,74 @@ func (c *Config) Save() error {
 	return c.instance.Save(c)
 }
 
+const (
+	LogFormatIrssi   = "irssi"
+	LogFormatWeechat = "weechat"
+	LogFormatJSONL   = "jsonl"
+)
+
+// Logging controls writing messages to text files, one file per window per day
+type Logging struct {
+	Enabled bool `yaml:"enabled"`
+	// Directory is where logs are written, defaults to a logs directory next to the config file
+	Directory string `yaml:"directory,omitempty"`
+	Format    string `yaml:"format,omitempty" validate:"omitempty,oneof=irssi weechat jsonl"`
+}
+
+// GetDirectory returns the directory logs should be written to
+func (l Logging) GetDirectory() string {
+	if l.Directory == "" {
+		return filepath.Join(GetUserConfigDir(), "logs")
+	}
+	return l.Directory
+}
+
+// LinkPreviews controls fetching the titles and images of links posted in messages
+type LinkPreviews struct {
+	Enabled bool `yaml:"enabled"`
+	// MaxSize is the most that will be downloaded from each link, in bytes
+	MaxSize int64 `yaml:"max_size" validate:"min=0"`
+	// Timeout is how long to wait for each link to respond
+	Timeout time.Duration `yaml:"timeout" validate:"min=0"`
+	// Allow limits previews to these domains and their subdomains, every domain is allowed when empty
+	Allow []string `yaml:"allow,omitempty"`
+	// Deny stops previews for these domains and their subdomains, even if they're allowed
+	Deny []string `yaml:"deny,omitempty"`
+	// ChatHistory fetches previews for history replayed by the server or imported from logs
+	ChatHistory bool `yaml:"chat_history,omitempty"`
+}
+
+// NickCompletionSuffixNone can be used as the nick completion suffix to add nothing after completed nicknames
+const NickCompletionSuffixNone = "none"
+
+// ProxyDirect can be used as a server's proxy to bypass the default proxy
+const ProxyDirect = "direct"
+
+// ProxySchemes lists the proxy URL schemes that can be used to connect to a server
+var ProxySchemes = []string{"socks5", "socks5h", "http"}
+
+// ValidateProxy checks that the proxy is either ProxyDirect or a URL with a supported scheme
+func ValidateProxy(proxy string) error {
+	if proxy == ProxyDirect {
+		return nil
+	}
+	u, err := url.Parse(proxy)
+	if err != nil {
+		return fmt.Errorf("invalid proxy url: %w", err)
+	}
+	if !slices.Contains(ProxySchemes, u.Scheme) {
+		return fmt.Errorf("unsupported proxy scheme %q", u.Scheme)
+	}
+	if u.Hostname() == "" {
+		return fmt.Errorf("proxy url has no host")
+	}
+	return nil
+}
+
+func validateProxyURL(fl validator.FieldLevel) bool {
+	return ValidateProxy(fl.Field().String()) == nil
+}
+
 // applyDefaults sets default values for optional configuration fields
 func (c *Config) applyDefaults() {
 	// Set default timestamp format
@@ -131,6 +485,47 @@ func (c *Config) applyDefaults() {
 		c.UISettings.Theme = "auto"
 	}
 
+	if c.UISettings.NickCompletionSuffix == "" {
+		c.UISettings.NickCompletionSuffix = ": "
+	}
+
+	// Set default link preview limits
+	if c.LinkPreviews.MaxSize == 0 {
+		c.LinkPreviews.MaxSize = 2 * 1024 * 1024
+	}
+	if c.LinkPreviews.Timeout == 0 {
+		c.LinkPreviews.Timeout = 10 * time.Second
+	}
+
+	// Set default reconnection, liveness and flood protection settings
+	if c.Connection.ReconnectMinDelay == 0 {
+		c.Connection.ReconnectMinDelay = 2 * time.Second
+	}
+	if c.Connection.ReconnectMaxDelay == 0 {
+		c.Connection.ReconnectMaxDelay = max(1*time.Minute, c.Connection.ReconnectMinDelay)
+	}
+	if c.Connection.PingInterval == 0 {
+		c.Connection.PingInterval = 30 * time.Second
+	}
+	if c.Connection.PingTimeout == 0 {
+		c.Connection.PingTimeout = 60 * time.Second
+	}
+	// Servers typically allow a small burst then penalise each line by 2 seconds
+	if c.Connection.FloodBurst == 0 {
+		c.Connection.FloodBurst = 5
+	}
+	if c.Connection.FloodInterval == 0 {
+		c.Connection.FloodInterval = 2 * time.Second
+	}
+	if c.Connection.WhoInterval == 0 {
+		c.Connection.WhoInterval = 30 * time.Second
+	}
+	if c.Connection.WhoMaxUsers == 0 {
+		c.Connection.WhoMaxUsers = 500
+	}
+
+	c.moveWindowEventFilters()
+
 	// Generate IDs for servers that don't have them
 	for i := range c.Servers {
 		if c.Servers[i].ID == "" {
@@ -146,6 +541,31 @@ func (c *Config) applyDefaults() {
 				c.Servers[i].Port = 6667
 			}
 		}
+
+		for j := range c.Servers[i].Alternates {
+			if c.Servers[i].Alternates[j].Port == 0 {
+				if c.Servers[i].Alternates[j].TLS {
+					c.Servers[i].Alternates[j].Port = 6697
+				} else {
+					c.Servers[i].Alternates[j].Port = 6667
+				}
+			}
+		}
+	}
+
+	if c.EventFilter.Membership == "" {
+		c.EventFilter.Membership = MembershipShow
+	}
+	if c.EventFilter.SmartDuration == 0 {
+		c.EventFilter.SmartDuration = 10 * time.Minute
+	}
+
+	if c.InputHistory.Size == 0 {
+		c.InputHistory.Size = 100
+	}
+
+	if c.Logging.Format == "" {
+		c.Logging.Format = LogFormatIrssi
 	}
 
 	// Set default debounce duration for notification triggers
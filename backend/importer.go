@@ -0,0 +1,56 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"github.com/greboid/tithon/config"
+	"github.com/greboid/tithon/irc"
+	"os"
+)
+
+// runImport imports logs from other clients into the history shown when channels and queries are opened, it returns
+// the exit code
+func runImport(args []string) int {
+	flags := flag.NewFlagSet("import", flag.ContinueOnError)
+	format := flags.String("format", "", "Format of the logs: irssi, weechat, znc or jsonl")
+	network := flags.String("network", "", "ID or hostname of the server the logs are from, guessed from the path if not set")
+	channel := flags.String("channel", "", "Channel or nickname the logs are for, guessed from the path if not set")
+	flags.Usage = func() {
+		_, _ = fmt.Fprintf(flags.Output(), "Usage: %s import -format format [-network network] [-channel channel] file...\n", os.Args[0])
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if *format == "" || flags.NArg() == 0 {
+		flags.Usage()
+		return 2
+	}
+	provider, err := config.NewDefaultConfigProvider()
+	if err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "Unable to load config: %s\n", err)
+		return 1
+	}
+	conf := config.NewConfig(provider)
+	if err = conf.Load(); err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "Unable to load config: %s\n", err)
+		return 1
+	}
+	// Servers are given an ID when the config is loaded, it's saved so history is imported under the ID used later
+	if err = conf.Save(); err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "Unable to save config: %s\n", err)
+		return 1
+	}
+	history := irc.NewHistory(config.GetHistoryDir())
+	exitCode := 0
+	for _, path := range flags.Args() {
+		server, importedChannel, added, err := irc.ImportLog(history, conf.Servers, *format, path, *network, *channel)
+		if err != nil {
+			_, _ = fmt.Fprintf(os.Stderr, "Unable to import %s: %s\n", path, err)
+			exitCode = 1
+			continue
+		}
+		fmt.Printf("Imported %d messages from %s into %s %s\n", added, path, server.Hostname, importedChannel)
+	}
+	return exitCode
+}
@@ -2,6 +2,7 @@ package irc
 
 import (
 	uniqueid "github.com/albinj12/unique-id"
+	"sync"
 	"time"
 )
 
@@ -9,6 +10,13 @@ type Channel struct {
 	*Window
 	topic        *Topic
 	channelModes []*ChannelMode // Store channel modes
+	created      time.Time
+	whoRefreshed time.Time
+	// joinWho is set when the channel has just been joined, its users are requested once NAMES has listed them
+	joinWho      bool
+	listLock     sync.Mutex
+	lists        map[string][]*ListEntry
+	pendingLists map[string][]*ListEntry
 }
 
 func NewChannel(connection *Server, name string) *Channel {
@@ -17,15 +25,15 @@ func NewChannel(connection *Server, name string) *Channel {
 		Window: &Window{
 			id:         s,
 			name:       name,
-			title:      "No topic Set",
 			messages:   make([]*Message, 0),
 			connection: connection,
 			hasUsers:   true,
 			isChannel:  true,
 		},
-		topic:        NewTopic("No topic Set", "", time.Time{}),
+		topic:        NewTopic("", "", time.Time{}),
 		channelModes: make([]*ChannelMode, 0),
 	}
+	channel.Window.title = channel.topic.GetDisplayTopic()
 	channel.Window.tabCompleter = NewChannelTabCompleter(channel)
 	return channel
 }
@@ -79,3 +87,50 @@ func (c *Channel) RemoveChannelMode(mode string) {
 		}
 	}
 }
+
+// replaceChannelModes replaces all the setting modes with the ones the server says are set, list modes are kept
+func (c *Channel) replaceChannelModes(modes []*ChannelMode) {
+	var kept []*ChannelMode
+	for _, m := range c.channelModes {
+		if m.Type == 'A' {
+			kept = append(kept, m)
+		}
+	}
+	c.channelModes = append(kept, modes...)
+}
+
+// GetCreated returns when the channel was created, or the zero time if the server hasn't said
+func (c *Channel) GetCreated() time.Time {
+	return c.created
+}
+
+func (c *Channel) setCreated(created time.Time) {
+	c.created = created
+}
+
+func (c *Channel) getWhoRefreshed() time.Time {
+	c.stateSync.Lock()
+	defer c.stateSync.Unlock()
+	return c.whoRefreshed
+}
+
+func (c *Channel) setWhoRefreshed(refreshed time.Time) {
+	c.stateSync.Lock()
+	defer c.stateSync.Unlock()
+	c.whoRefreshed = refreshed
+}
+
+// takeJoinWho checks if the channel's users should be requested after joining, clearing it so it's only done once
+func (c *Channel) takeJoinWho() bool {
+	c.stateSync.Lock()
+	defer c.stateSync.Unlock()
+	joinWho := c.joinWho
+	c.joinWho = false
+	return joinWho
+}
+
+func (c *Channel) setJoinWho() {
+	c.stateSync.Lock()
+	defer c.stateSync.Unlock()
+	c.joinWho = true
+}
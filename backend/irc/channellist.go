@@ -0,0 +1,201 @@
+package irc
+
+import (
+	"cmp"
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const (
+	// ChannelListSortUsers sorts the channel list by the number of users, busiest first
+	ChannelListSortUsers = "users"
+	// ChannelListSortName sorts the channel list alphabetically
+	ChannelListSortName = "name"
+)
+
+// ChannelListViewer shows the result of a LIST to the user
+type ChannelListViewer interface {
+	ShowChannelList(server *Server)
+}
+
+// ChannelListEntry is a channel sent in reply to LIST
+type ChannelListEntry struct {
+	Name  string
+	Users int
+	Topic string
+}
+
+// ChannelListFilter limits the channels returned by LIST.  The network is asked to filter when its ELIST token says
+// it can, every entry is checked again as they arrive for networks that can't.
+type ChannelListFilter struct {
+	Masks    []string
+	MoreThan int
+	LessThan int
+	patterns []*regexp.Regexp
+}
+
+// parseChannelListFilter parses a space separated filter, >N and <N limit the number of users and anything else is a
+// channel mask.  Masks without wildcards that aren't channel names match channels containing them.
+func parseChannelListFilter(input string, chanTypes string) (ChannelListFilter, error) {
+	filter := ChannelListFilter{}
+	for _, token := range strings.Fields(input) {
+		switch token[0] {
+		case '>', '<':
+			users, err := strconv.Atoi(token[1:])
+			if err != nil || users < 0 {
+				return ChannelListFilter{}, fmt.Errorf("invalid number of users: %s", token)
+			}
+			if token[0] == '>' {
+				filter.MoreThan = users
+			} else {
+				filter.LessThan = users
+			}
+		default:
+			if !strings.ContainsAny(token, "*?") && !strings.ContainsRune(chanTypes, rune(token[0])) {
+				token = "*" + token + "*"
+			}
+			filter.Masks = append(filter.Masks, token)
+			filter.patterns = append(filter.patterns, compileMask(token))
+		}
+	}
+	return filter, nil
+}
+
+// String returns the filter in the form it was parsed from
+func (f ChannelListFilter) String() string {
+	var tokens []string
+	tokens = append(tokens, f.Masks...)
+	if f.MoreThan > 0 {
+		tokens = append(tokens, ">"+strconv.Itoa(f.MoreThan))
+	}
+	if f.LessThan > 0 {
+		tokens = append(tokens, "<"+strconv.Itoa(f.LessThan))
+	}
+	return strings.Join(tokens, " ")
+}
+
+// params returns the LIST parameter for the conditions the network supports, based on its ELIST token
+func (f ChannelListFilter) params(elist string) []string {
+	var conditions []string
+	for _, mask := range f.Masks {
+		if strings.ContainsAny(elist, "Mm") || !strings.ContainsAny(mask, "*?") {
+			conditions = append(conditions, mask)
+		}
+	}
+	if strings.ContainsAny(elist, "Uu") {
+		if f.MoreThan > 0 {
+			conditions = append(conditions, ">"+strconv.Itoa(f.MoreThan))
+		}
+		if f.LessThan > 0 {
+			conditions = append(conditions, "<"+strconv.Itoa(f.LessThan))
+		}
+	}
+	if len(conditions) == 0 {
+		return nil
+	}
+	return []string{strings.Join(conditions, ",")}
+}
+
+// matches checks if a channel meets all the conditions of the filter
+func (f ChannelListFilter) matches(entry ChannelListEntry) bool {
+	if f.MoreThan > 0 && entry.Users <= f.MoreThan {
+		return false
+	}
+	if f.LessThan > 0 && entry.Users >= f.LessThan {
+		return false
+	}
+	if len(f.patterns) == 0 {
+		return true
+	}
+	return slices.ContainsFunc(f.patterns, func(pattern *regexp.Regexp) bool {
+		return pattern.MatchString(entry.Name)
+	})
+}
+
+// ChannelList holds the channels sent in reply to the last LIST
+type ChannelList struct {
+	lock    sync.Mutex
+	filter  ChannelListFilter
+	entries []ChannelListEntry
+	loading bool
+}
+
+func (l *ChannelList) start(filter ChannelListFilter) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	l.filter = filter
+	l.entries = nil
+	l.loading = true
+}
+
+// add stores a channel if it matches the filter and returns the number of channels stored
+func (l *ChannelList) add(entry ChannelListEntry) int {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	if l.filter.matches(entry) {
+		l.entries = append(l.entries, entry)
+	}
+	return len(l.entries)
+}
+
+func (l *ChannelList) end() {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	l.loading = false
+}
+
+// IsLoading checks if the server is still sending channels
+func (l *ChannelList) IsLoading() bool {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	return l.loading
+}
+
+// GetFilter returns the filter used for the last LIST
+func (l *ChannelList) GetFilter() ChannelListFilter {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	return l.filter
+}
+
+// Search returns at most limit channels whose name or topic contains the text, sorted by sortBy, along with the
+// number of channels that matched
+func (l *ChannelList) Search(text string, sortBy string, limit int) ([]ChannelListEntry, int) {
+	l.lock.Lock()
+	text = strings.ToLower(text)
+	var results []ChannelListEntry
+	for _, entry := range l.entries {
+		if text == "" || strings.Contains(strings.ToLower(entry.Name), text) ||
+			strings.Contains(strings.ToLower(entry.Topic), text) {
+			results = append(results, entry)
+		}
+	}
+	l.lock.Unlock()
+	slices.SortStableFunc(results, func(a, b ChannelListEntry) int {
+		if sortBy == ChannelListSortName {
+			return cmp.Compare(strings.ToLower(a.Name), strings.ToLower(b.Name))
+		}
+		return cmp.Or(cmp.Compare(b.Users, a.Users), cmp.Compare(strings.ToLower(a.Name), strings.ToLower(b.Name)))
+	})
+	return results[:min(limit, len(results))], len(results)
+}
+
+// GetChannelList returns the channels sent in reply to the last LIST
+func (c *Server) GetChannelList() *ChannelList {
+	return &c.channelList
+}
+
+// RequestChannelList asks the server for the channels matching the filter, replacing the previous list.  It's a single
+// line asked for by the user, so it isn't queued behind bulk traffic.
+func (c *Server) RequestChannelList(input string) error {
+	filter, err := parseChannelListFilter(input, c.getChanTypes())
+	if err != nil {
+		return err
+	}
+	c.channelList.start(filter)
+	return c.send(PriorityInteractive, "LIST", filter.params(c.ISupport("ELIST"))...)
+}
@@ -0,0 +1,83 @@
+package irc
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseChannelListFilter(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    string
+		wantErr bool
+	}{
+		{name: "Empty", input: "", want: ""},
+		{name: "Users", input: ">10 <200", want: ">10 <200"},
+		{name: "Channel mask", input: "#go*", want: "#go*"},
+		{name: "Channel name", input: "#tithon", want: "#tithon"},
+		{name: "Word", input: "linux  >5", want: "*linux* >5"},
+		{name: "Invalid users", input: ">many", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filter, err := parseChannelListFilter(tt.input, "#&")
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, filter.String())
+		})
+	}
+}
+
+func TestChannelListFilter_params(t *testing.T) {
+	filter, err := parseChannelListFilter("#tithon #go* >10", "#")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"#tithon,#go*,>10"}, filter.params("CMNTU"))
+	assert.Equal(t, []string{"#tithon"}, filter.params(""), "only plain channel names should be sent without ELIST")
+	empty, err := parseChannelListFilter("", "#")
+	require.NoError(t, err)
+	assert.Nil(t, empty.params("MU"))
+}
+
+func TestChannelListFilter_matches(t *testing.T) {
+	filter, err := parseChannelListFilter("#go* linux >10 <100", "#")
+	require.NoError(t, err)
+	assert.True(t, filter.matches(ChannelListEntry{Name: "#GoLang", Users: 50}))
+	assert.True(t, filter.matches(ChannelListEntry{Name: "##linux", Users: 11}))
+	assert.False(t, filter.matches(ChannelListEntry{Name: "#golang", Users: 10}))
+	assert.False(t, filter.matches(ChannelListEntry{Name: "#golang", Users: 100}))
+	assert.False(t, filter.matches(ChannelListEntry{Name: "#rust", Users: 50}))
+}
+
+func TestChannelList_Search(t *testing.T) {
+	list := &ChannelList{}
+	list.start(ChannelListFilter{MoreThan: 1})
+	assert.True(t, list.IsLoading())
+	list.add(ChannelListEntry{Name: "#b", Users: 10, Topic: "Go programming"})
+	list.add(ChannelListEntry{Name: "#a", Users: 10})
+	list.add(ChannelListEntry{Name: "#c", Users: 50, Topic: "Rust"})
+	assert.Equal(t, 3, list.add(ChannelListEntry{Name: "#d", Users: 1}), "channels not matching the filter aren't stored")
+	list.end()
+	assert.False(t, list.IsLoading())
+
+	results, total := list.Search("", ChannelListSortUsers, 10)
+	assert.Equal(t, 3, total)
+	assert.Equal(t, []ChannelListEntry{
+		{Name: "#c", Users: 50, Topic: "Rust"},
+		{Name: "#a", Users: 10},
+		{Name: "#b", Users: 10, Topic: "Go programming"},
+	}, results)
+
+	results, total = list.Search("", ChannelListSortName, 2)
+	assert.Equal(t, 3, total)
+	assert.Equal(t, []string{"#a", "#b"}, []string{results[0].Name, results[1].Name})
+
+	results, total = list.Search("go", ChannelListSortName, 10)
+	assert.Equal(t, 1, total)
+	assert.Equal(t, "#b", results[0].Name)
+}
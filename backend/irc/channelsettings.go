@@ -0,0 +1,161 @@
+package irc
+
+import (
+	"cmp"
+	"slices"
+	"strings"
+)
+
+// channelModeNames describe the channel modes common to most networks, others are shown by their letter
+var channelModeNames = map[string]string{
+	"i": "Invite only",
+	"k": "Key",
+	"l": "User limit",
+	"m": "Moderated",
+	"n": "No external messages",
+	"p": "Private",
+	"s": "Secret",
+	"t": "Only operators can change the topic",
+}
+
+// ChannelSetting is a channel mode that isn't a list or a privilege, with its current value on a channel
+type ChannelSetting struct {
+	Mode      string
+	Name      string
+	Type      rune
+	Set       bool
+	Parameter string
+}
+
+// HasParameter checks if the mode needs a parameter when it's set
+func (s ChannelSetting) HasParameter() bool {
+	return s.Type == 'B' || s.Type == 'C'
+}
+
+// ChannelPrivilege is a privilege mode from PREFIX, along with the number of users in a channel that have it
+type ChannelPrivilege struct {
+	Mode   string
+	Prefix string
+	Users  int
+}
+
+// GetChannelSettings returns every setting mode in the network's CHANMODES along with its value on the channel
+func (c *Server) GetChannelSettings(channel *Channel) []ChannelSetting {
+	parts := c.getChanModes()
+	if parts == nil {
+		return nil
+	}
+	var settings []ChannelSetting
+	for i, modeType := range []rune{'B', 'C', 'D'} {
+		for _, char := range parts[i+1] {
+			setting := ChannelSetting{Mode: string(char), Name: channelModeNames[string(char)], Type: modeType}
+			if setting.Name == "" {
+				setting.Name = "Mode " + setting.Mode
+			}
+			if current := channel.GetChannelMode(setting.Mode); current != nil && current.Set {
+				setting.Set = true
+				setting.Parameter = current.Parameter
+			}
+			settings = append(settings, setting)
+		}
+	}
+	return settings
+}
+
+// GetChannelPrivileges returns the network's privilege modes, highest first, and how many users have each
+func (c *Server) GetChannelPrivileges(channel *Channel) []ChannelPrivilege {
+	prefixes := c.GetModePrefixes()
+	privileges := make([]ChannelPrivilege, len(prefixes[0]))
+	for i := range prefixes[0] {
+		privileges[i] = ChannelPrivilege{Mode: prefixes[0][i : i+1], Prefix: prefixes[1][i : i+1]}
+	}
+	for _, user := range channel.GetUsers() {
+		for i := range privileges {
+			if strings.Contains(user.GetNickListModes(), privileges[i].Prefix) {
+				privileges[i].Users++
+			}
+		}
+	}
+	return privileges
+}
+
+// SendChannelSettings changes the modes on a channel that differ between the current and wanted settings, using as
+// few MODE commands as the network allows
+func (c *Server) SendChannelSettings(channel string, current []ChannelSetting, wanted []ChannelSetting) error {
+	for _, params := range settingChanges(current, wanted, c.getModesLimit()) {
+		if err := c.send(PriorityInteractive, "MODE", append([]string{channel}, params...)...); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type settingChange struct {
+	add       bool
+	mode      string
+	parameter string
+}
+
+// settingChanges returns the MODE parameters needed to change the current settings into the wanted ones, with at
+// most limit modes that take a parameter in each
+func settingChanges(current []ChannelSetting, wanted []ChannelSetting, limit int) [][]string {
+	var changes []settingChange
+	for _, want := range wanted {
+		index := slices.IndexFunc(current, func(setting ChannelSetting) bool {
+			return setting.Mode == want.Mode
+		})
+		if index == -1 {
+			continue
+		}
+		have := current[index]
+		switch {
+		case want.Set && want.HasParameter() && want.Parameter == "":
+			continue
+		case want.Set && !have.Set:
+			changes = append(changes, settingChange{add: true, mode: want.Mode, parameter: want.Parameter})
+		case !want.Set && have.Set:
+			change := settingChange{mode: want.Mode}
+			if want.Type == 'B' {
+				// Keys need a parameter to be removed, most servers ignore it
+				change.parameter = cmp.Or(have.Parameter, "*")
+			}
+			changes = append(changes, change)
+		case want.Set && want.HasParameter() && want.Parameter != have.Parameter:
+			if want.Type == 'B' {
+				changes = append(changes, settingChange{mode: want.Mode, parameter: cmp.Or(have.Parameter, "*")})
+			}
+			changes = append(changes, settingChange{add: true, mode: want.Mode, parameter: want.Parameter})
+		}
+	}
+	var lines [][]string
+	var modes strings.Builder
+	var params []string
+	sign := ""
+	flush := func() {
+		if modes.Len() > 0 {
+			lines = append(lines, append([]string{modes.String()}, params...))
+		}
+		modes.Reset()
+		params = nil
+		sign = ""
+	}
+	for _, change := range changes {
+		if change.parameter != "" && len(params) >= limit {
+			flush()
+		}
+		changeSign := "-"
+		if change.add {
+			changeSign = "+"
+		}
+		if changeSign != sign {
+			modes.WriteString(changeSign)
+			sign = changeSign
+		}
+		modes.WriteString(change.mode)
+		if change.parameter != "" {
+			params = append(params, change.parameter)
+		}
+	}
+	flush()
+	return lines
+}
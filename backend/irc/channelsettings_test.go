@@ -0,0 +1,82 @@
+package irc
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSettingChanges(t *testing.T) {
+	current := []ChannelSetting{
+		{Mode: "k", Type: 'B', Set: true, Parameter: "old"},
+		{Mode: "l", Type: 'C'},
+		{Mode: "i", Type: 'D'},
+		{Mode: "n", Type: 'D', Set: true},
+		{Mode: "t", Type: 'D', Set: true},
+	}
+	tests := []struct {
+		name   string
+		wanted []ChannelSetting
+		limit  int
+		want   [][]string
+	}{
+		{name: "No changes", wanted: current, limit: 3, want: nil},
+		{
+			name: "Toggle booleans",
+			wanted: []ChannelSetting{
+				{Mode: "i", Type: 'D', Set: true},
+				{Mode: "n", Type: 'D'},
+				{Mode: "t", Type: 'D'},
+			},
+			limit: 3,
+			want:  [][]string{{"+i-nt"}},
+		},
+		{
+			name: "Change key and set limit",
+			wanted: []ChannelSetting{
+				{Mode: "k", Type: 'B', Set: true, Parameter: "new"},
+				{Mode: "l", Type: 'C', Set: true, Parameter: "20"},
+				{Mode: "t", Type: 'D'},
+			},
+			limit: 3,
+			want:  [][]string{{"-k+kl-t", "old", "new", "20"}},
+		},
+		{
+			name: "Remove key",
+			wanted: []ChannelSetting{
+				{Mode: "k", Type: 'B'},
+			},
+			limit: 3,
+			want:  [][]string{{"-k", "old"}},
+		},
+		{
+			name: "Parameter without a value is ignored",
+			wanted: []ChannelSetting{
+				{Mode: "l", Type: 'C', Set: true},
+				{Mode: "i", Type: 'D', Set: true},
+			},
+			limit: 3,
+			want:  [][]string{{"+i"}},
+		},
+		{
+			name: "Split over the limit",
+			wanted: []ChannelSetting{
+				{Mode: "k", Type: 'B', Set: true, Parameter: "new"},
+				{Mode: "l", Type: 'C', Set: true, Parameter: "20"},
+			},
+			limit: 2,
+			want:  [][]string{{"-k+k", "old", "new"}, {"+l", "20"}},
+		},
+		{
+			name:   "Unknown modes are ignored",
+			wanted: []ChannelSetting{{Mode: "z", Type: 'D', Set: true}},
+			limit:  3,
+			want:   nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, settingChanges(current, tt.wanted, tt.limit))
+		})
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"github.com/greboid/tithon/config"
 	"log/slog"
 	"regexp"
+	"slices"
 	"strings"
 )
 
@@ -21,6 +22,12 @@ type Command interface {
 	Execute(*ServerManager, *Window, string) error
 }
 
+// ArgumentCompleter is implemented by commands that can tab complete their arguments, args are the arguments before
+// the one being completed
+type ArgumentCompleter interface {
+	CompleteArgument(server *Server, args []string) []string
+}
+
 type Notifier interface {
 	showNotification(notification Notification)
 }
@@ -44,12 +51,35 @@ func NewCommandManager(conf *config.Config, showSettings chan bool) *CommandMana
 		&ChangeTopic{},
 		&SendNotice{},
 		&Whois{},
+		&Who{},
+		&Whowas{},
 		&Mode{},
+		&Kick{name: "kick", help: "Kicks users from a channel. Usage: /kick [channel] <nick>[,<nick>...] [reason]"},
+		&Kick{name: "kickban", help: "Bans then kicks users from a channel. Usage: /kickban [channel] <nick>[,<nick>...] [reason]", ban: true, conf: conf},
+		&ChannelMaskCommand{name: "ban", help: "Bans users or masks from a channel. Usage: /ban [channel] <nick|mask>...", mode: "b", add: true, conf: conf},
+		&ChannelMaskCommand{name: "unban", help: "Removes bans from a channel. Usage: /unban [channel] <nick|mask>...", mode: "b", conf: conf},
+		&ChannelMaskCommand{name: "quiet", help: "Stops users or masks talking in a channel. Usage: /quiet [channel] <nick|mask>...", mode: "q", add: true, conf: conf},
+		&ChannelMaskCommand{name: "unquiet", help: "Removes quiets from a channel. Usage: /unquiet [channel] <nick|mask>...", mode: "q", conf: conf},
+		&ChannelUserMode{name: "op", help: "Gives users operator status. Usage: /op [channel] <nick>...", mode: "o", add: true},
+		&ChannelUserMode{name: "deop", help: "Removes operator status from users. Usage: /deop [channel] <nick>...", mode: "o"},
+		&ChannelUserMode{name: "halfop", help: "Gives users half-operator status. Usage: /halfop [channel] <nick>...", mode: "h", add: true},
+		&ChannelUserMode{name: "dehalfop", help: "Removes half-operator status from users. Usage: /dehalfop [channel] <nick>...", mode: "h"},
+		&ChannelUserMode{name: "voice", help: "Gives users voice. Usage: /voice [channel] <nick>...", mode: "v", add: true},
+		&ChannelUserMode{name: "devoice", help: "Removes voice from users. Usage: /devoice [channel] <nick>...", mode: "v"},
+		&Invite{},
 		&Notify{nm: cm},
 		&QueryCommand{},
 		&AddServer{},
 		&Disconnect{},
 		&Reconnect{},
+		&ClearQueue{},
+		&IgnoreCommand{},
+		&Unignore{},
+		&EventsCommand{},
+		&WindowCommand{},
+		&Lastlog{},
+		&ExportCommand{},
+		&ListCommand{},
 		&CloseCommand{},
 		&CTCPCommand{},
 		&Settings{
@@ -63,6 +93,10 @@ func NewCommandManager(conf *config.Config, showSettings chan bool) *CommandMana
 }
 
 func (cm *CommandManager) Execute(connections *ServerManager, window *Window, input string) {
+	if window != nil && window.IsMentions() {
+		cm.showError(window, "Commands can't be used in this window")
+		return
+	}
 	if !strings.HasPrefix(input, "/") {
 		input = "/msg " + input
 	}
@@ -90,6 +124,44 @@ func (cm *CommandManager) Execute(connections *ServerManager, window *Window, in
 	cm.showError(window, fmt.Sprintf("Command '%s' not found. Use /help to see all available commands.", input))
 }
 
+// completeCommand returns the names of every command, prefixed with a /
+func (cm *CommandManager) completeCommand() []string {
+	if cm == nil {
+		return nil
+	}
+	names := make([]string, len(cm.commands))
+	for i := range cm.commands {
+		names[i] = "/" + cm.commands[i].GetName()
+	}
+	slices.Sort(names)
+	return names
+}
+
+// completeArgument returns the values an argument to the named command can take, if the command knows them
+func (cm *CommandManager) completeArgument(server *Server, name string, args []string) []string {
+	if cm == nil {
+		return nil
+	}
+	for i := range cm.commands {
+		if cm.commands[i].GetName() != name {
+			continue
+		}
+		if completer, ok := cm.commands[i].(ArgumentCompleter); ok {
+			return completer.CompleteArgument(server, args)
+		}
+		return nil
+	}
+	return nil
+}
+
+// nickCompletionSuffix returns the text added after a nickname completed at the start of the line
+func (cm *CommandManager) nickCompletionSuffix() string {
+	if cm == nil || cm.conf == nil || cm.conf.UISettings.NickCompletionSuffix == config.NickCompletionSuffixNone {
+		return ""
+	}
+	return cm.conf.UISettings.NickCompletionSuffix
+}
+
 func (cm *CommandManager) SetNotificationManager(nm NotificationManager) {
 	cm.nm = nm
 }
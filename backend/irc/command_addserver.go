@@ -2,6 +2,9 @@ package irc
 
 import (
 	"errors"
+	"fmt"
+	"github.com/greboid/tithon/config"
+	"net"
 	"strconv"
 	"strings"
 )
@@ -13,7 +16,7 @@ func (c AddServer) GetName() string {
 }
 
 func (c AddServer) GetHelp() string {
-	return "Adds a new server and connects to it. Usage: /addserver hostname[:port] [nickname] [--notls] [--password=serverpassword] [--sasl=username:password]"
+	return "Adds a new server and connects to it. Usage: /addserver hostname[:port] [nickname] [--notls] [--password=serverpassword] [--sasl=username:password] [--proxy=socks5://host:port] [--alternates=host[:[+]port],...] [--shuffle]"
 }
 
 func (c AddServer) Execute(cm *ServerManager, _ *Window, input string) error {
@@ -38,6 +41,9 @@ func (c AddServer) Execute(cm *ServerManager, _ *Window, input string) error {
 	password := ""
 	saslLogin := ""
 	saslPassword := ""
+	proxy := ""
+	var alternates []string
+	shuffle := false
 
 	for _, arg := range args {
 		if arg == "--notls" {
@@ -51,29 +57,48 @@ func (c AddServer) Execute(cm *ServerManager, _ *Window, input string) error {
 				saslLogin = parts[0]
 				saslPassword = parts[1]
 			}
+		} else if strings.HasPrefix(arg, "--proxy=") {
+			proxy = strings.TrimPrefix(arg, "--proxy=")
+			if err := config.ValidateProxy(proxy); err != nil {
+				return err
+			}
+		} else if strings.HasPrefix(arg, "--alternates=") {
+			alternates = strings.Split(strings.TrimPrefix(arg, "--alternates="), ",")
+		} else if arg == "--shuffle" {
+			shuffle = true
+		}
+	}
+	if port == -1 {
+		if tls {
+			port = 6697
+		} else {
+			port = 6667
 		}
 	}
-	if tls && port == -1 {
-		port = 6697
-	} else {
-		port = 6667
+	addresses := []config.ServerAddress{{Hostname: hostname, Port: port, TLS: tls}}
+	for i := range alternates {
+		address, err := config.ParseServerAddress(alternates[i], tls)
+		if err != nil {
+			return fmt.Errorf("invalid alternate server: %w", err)
+		}
+		addresses = append(addresses, address)
 	}
 	profile := NewProfile(nickname)
-	cm.AddConnection("", hostname, port, tls, password, saslLogin, saslPassword, profile, true)
+	cm.AddConnection("", addresses, shuffle, password, saslLogin, saslPassword, proxy, profile, true)
 
 	return nil
 }
 
+// parseHostPort splits a hostname and port, IPv6 addresses need brackets when a port is given, the port is -1 when
+// it's missing or invalid
 func parseHostPort(hostPort string) (string, int) {
-	parts := strings.SplitN(hostPort, ":", 2)
-	hostname := parts[0]
-	port := -1
-
-	if len(parts) > 1 {
-		if p, err := strconv.Atoi(parts[1]); err == nil {
-			port = p
-		}
+	hostname, portText, err := net.SplitHostPort(hostPort)
+	if err != nil {
+		return strings.TrimSuffix(strings.TrimPrefix(hostPort, "["), "]"), -1
+	}
+	port, err := strconv.Atoi(portText)
+	if err != nil {
+		return hostname, -1
 	}
-
 	return hostname, port
 }
@@ -0,0 +1,29 @@
+package irc
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseHostPort(t *testing.T) {
+	tests := []struct {
+		hostPort     string
+		wantHostname string
+		wantPort     int
+	}{
+		{hostPort: "irc.example.com", wantHostname: "irc.example.com", wantPort: -1},
+		{hostPort: "irc.example.com:6667", wantHostname: "irc.example.com", wantPort: 6667},
+		{hostPort: "irc.example.com:abc", wantHostname: "irc.example.com", wantPort: -1},
+		{hostPort: "[2001:db8::1]:6697", wantHostname: "2001:db8::1", wantPort: 6697},
+		{hostPort: "[::1]", wantHostname: "::1", wantPort: -1},
+		{hostPort: "::1", wantHostname: "::1", wantPort: -1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.hostPort, func(t *testing.T) {
+			hostname, port := parseHostPort(tt.hostPort)
+			assert.Equal(t, tt.wantHostname, hostname)
+			assert.Equal(t, tt.wantPort, port)
+		})
+	}
+}
@@ -0,0 +1,48 @@
+package irc
+
+import (
+	"github.com/greboid/tithon/config"
+	"strings"
+)
+
+// ChannelMaskCommand sets or unsets a list mode such as a ban or quiet, nicknames are turned into masks using the
+// configured ban mask style
+type ChannelMaskCommand struct {
+	name string
+	help string
+	mode string
+	add  bool
+	conf *config.Config
+}
+
+func (c ChannelMaskCommand) GetName() string {
+	return c.name
+}
+
+func (c ChannelMaskCommand) GetHelp() string {
+	return c.help
+}
+
+func (c ChannelMaskCommand) Execute(_ *ServerManager, window *Window, input string) error {
+	channel, rest, err := getOpTarget(window, input)
+	if err != nil {
+		return err
+	}
+	targets := strings.Fields(rest)
+	if len(targets) == 0 {
+		return ErrNoNick
+	}
+	return sendBans(window.connection, c.conf, channel, c.add, c.mode, targets)
+}
+
+// sendBans checks the network supports the list mode, then sets or unsets it for the mask of each target
+func sendBans(server *Server, conf *config.Config, channel string, add bool, mode string, targets []string) error {
+	if err := server.checkChannelMode(mode, 'A'); err != nil {
+		return err
+	}
+	masks := make([]string, len(targets))
+	for i := range targets {
+		masks[i] = banMask(targets[i], server.findUser(channel, targets[i]), conf.UISettings.BanMask)
+	}
+	return server.SendChannelModes(channel, add, mode, masks)
+}
@@ -0,0 +1,30 @@
+package irc
+
+import (
+	"errors"
+	"fmt"
+)
+
+type ClearQueue struct{}
+
+func (c ClearQueue) GetName() string {
+	return "clearqueue"
+}
+
+func (c ClearQueue) GetHelp() string {
+	return "Discards any messages waiting to be sent to the current server. Usage: /clearqueue"
+}
+
+func (c ClearQueue) Execute(_ *ServerManager, window *Window, _ string) error {
+	if window == nil {
+		return errors.New("no window specified")
+	}
+	connection := window.GetServer()
+	if connection == nil {
+		return errors.New("not connected to a server")
+	}
+
+	count := connection.ClearSendQueue()
+	window.AddMessage(NewEvent(EventHelp, connection.timestampFormat, false, fmt.Sprintf("Discarded %d queued messages", count)))
+	return nil
+}
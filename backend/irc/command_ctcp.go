@@ -15,6 +15,13 @@ func (c *CTCPCommand) GetHelp() string {
 	return "Send a CTCP query to a user. Usage: /ctcp <nick> <command> [parameters]"
 }
 
+func (c *CTCPCommand) CompleteArgument(_ *Server, args []string) []string {
+	if len(args) != 1 {
+		return nil
+	}
+	return []string{"CLIENTINFO", "PING", "SOURCE", "TIME", "USERINFO", "VERSION"}
+}
+
 func (c *CTCPCommand) Execute(sm *ServerManager, window *Window, input string) error {
 	if window.connection == nil {
 		return ErrNoServer
@@ -0,0 +1,111 @@
+package irc
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/greboid/tithon/config"
+)
+
+// membershipDefault removes a channel's own setting so it uses the global one
+const membershipDefault = "default"
+
+type EventsCommand struct{}
+
+func (c EventsCommand) GetName() string {
+	return "events"
+}
+
+func (c EventsCommand) GetHelp() string {
+	return "Sets how joins, parts and quits are shown in this channel, or every channel with --global, smart only shows " +
+		"them for users who spoke recently. Shows the current settings when no mode is given. " +
+		"Usage: /events [--global] [--collapse=on|off] [--smart-time=duration] [" + strings.Join(config.MembershipModes, "|") + "|" + membershipDefault + "]"
+}
+
+func (c EventsCommand) CompleteArgument(_ *Server, _ []string) []string {
+	return append([]string{"--global", "--collapse=on", "--collapse=off", "--smart-time="}, append(slices.Clone(config.MembershipModes), membershipDefault)...)
+}
+
+func (c EventsCommand) Execute(cm *ServerManager, window *Window, input string) error {
+	if window == nil {
+		return ErrNoServer
+	}
+	filter := cm.GetEventFilter()
+	if filter == nil {
+		return errors.New("event filter unavailable")
+	}
+	global := false
+	mode := ""
+	for _, arg := range strings.Fields(input) {
+		switch {
+		case arg == "--global":
+			global = true
+		case strings.HasPrefix(arg, "--collapse="):
+			value := strings.TrimPrefix(arg, "--collapse=")
+			if value != "on" && value != "off" {
+				return fmt.Errorf("invalid collapse setting %s", value)
+			}
+			if err := filter.SetCollapse(value == "on"); err != nil {
+				return err
+			}
+		case strings.HasPrefix(arg, "--smart-time="):
+			duration, err := time.ParseDuration(strings.TrimPrefix(arg, "--smart-time="))
+			if err != nil {
+				return fmt.Errorf("invalid duration: %w", err)
+			}
+			if err = filter.SetSmartDuration(duration); err != nil {
+				return err
+			}
+		case strings.HasPrefix(arg, "--"):
+			return fmt.Errorf("unknown option %s", arg)
+		case mode == "":
+			mode = arg
+		default:
+			return errors.New("only one mode can be set")
+		}
+	}
+	if mode != "" {
+		if err := setMembership(filter, window, mode, global); err != nil {
+			return err
+		}
+	}
+	showEventFilter(filter, window)
+	return nil
+}
+
+func setMembership(filter *EventFilter, window *Window, mode string, global bool) error {
+	if global {
+		if mode == membershipDefault {
+			return errors.New("the global setting has no default")
+		}
+		return filter.SetMembership(mode)
+	}
+	if !window.IsChannel() {
+		return errors.New("not on a channel, use --global to change every channel")
+	}
+	if mode == membershipDefault {
+		mode = ""
+	}
+	settings := window.GetSettings()
+	settings.Membership = mode
+	return window.GetServer().UpdateWindowSettings(settings)
+}
+
+func showEventFilter(filter *EventFilter, window *Window) {
+	timestampFormat := window.GetServer().timestampFormat
+	settings := filter.GetSettings()
+	collapse := "off"
+	if settings.Collapse {
+		collapse = "on"
+	}
+	window.AddMessage(NewEvent(EventHelp, timestampFormat, false, fmt.Sprintf(
+		"Joins, parts and quits: %s globally, smart filter time %s, collapse %s",
+		settings.Membership, settings.SmartDuration, collapse,
+	)))
+	if membership := window.GetSettings().Membership; membership != "" {
+		window.AddMessage(NewEvent(EventHelp, timestampFormat, false, fmt.Sprintf("%s: %s", window.GetName(), membership)))
+	}
+}
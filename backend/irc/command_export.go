@@ -0,0 +1,69 @@
+package irc
+
+import (
+	"errors"
+	"fmt"
+	"github.com/greboid/tithon/config"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+type ExportCommand struct{}
+
+func (c ExportCommand) GetName() string {
+	return "export"
+}
+
+func (c ExportCommand) GetHelp() string {
+	return "Exports this window's messages to a file in the cache directory, optionally limited to a duration such as 2h " +
+		"or 7d, a date, or a range of dates. Usage: /export [html|text|json] [range] [filename]"
+}
+
+func (c ExportCommand) Execute(cm *ServerManager, window *Window, input string) error {
+	if window == nil {
+		return ErrNoServer
+	}
+	now := time.Now()
+	format := ExportHTML
+	args := strings.Fields(input)
+	if len(args) > 0 && IsExportFormat(args[0]) {
+		format = args[0]
+		args = args[1:]
+	}
+	var exportRange ExportRange
+	if len(args) > 0 {
+		if parsed, err := ParseExportRange(args[0], now); err == nil {
+			exportRange = parsed
+			args = args[1:]
+		}
+	}
+	filename := ExportFilename(window, format, now)
+	if len(args) > 1 {
+		return errors.New("too many arguments")
+	} else if len(args) == 1 {
+		filename = filepath.Base(args[0])
+		if filepath.Ext(filename) == "" {
+			filename += "." + exportExtension(format)
+		}
+	}
+	messages := ExportMessages(window, exportRange)
+	if len(messages) == 0 {
+		return errors.New("no messages to export")
+	}
+	data, err := Export(format, window, messages, cm.GetHTMLExporter())
+	if err != nil {
+		return err
+	}
+	directory := filepath.Join(config.GetUserCacheDir(), "exports")
+	if err = os.MkdirAll(directory, 0700); err != nil {
+		return err
+	}
+	path := filepath.Join(directory, filename)
+	if err = os.WriteFile(path, data, 0600); err != nil {
+		return err
+	}
+	window.AddMessage(NewEvent(EventHelp, window.GetServer().timestampFormat, false, fmt.Sprintf("Exported %d messages to %s", len(messages), path)))
+	return nil
+}
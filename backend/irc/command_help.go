@@ -18,6 +18,17 @@ func (c Help) GetHelp() string {
 	return "Shows help for all commands or a specific command"
 }
 
+func (c Help) CompleteArgument(_ *Server, args []string) []string {
+	if len(args) > 0 {
+		return nil
+	}
+	var names []string
+	for _, name := range c.cm.completeCommand() {
+		names = append(names, strings.TrimPrefix(name, "/"))
+	}
+	return names
+}
+
 func (c Help) Execute(_ *ServerManager, window *Window, input string) error {
 	if window == nil {
 		return ErrNoServer
@@ -0,0 +1,91 @@
+package irc
+
+import (
+	"errors"
+	"fmt"
+	"github.com/greboid/tithon/config"
+	"strings"
+)
+
+type IgnoreCommand struct{}
+
+func (c IgnoreCommand) GetName() string {
+	return "ignore"
+}
+
+func (c IgnoreCommand) GetHelp() string {
+	return "Ignores anyone matching a nick!user@host mask or $a:account, or lists ignores when no mask is given. " +
+		"Usage: /ignore [--network] [--channel[=#channel]] [--types=" + strings.Join(config.IgnoreTypes, ",") + "] [mask]"
+}
+
+func (c IgnoreCommand) Execute(cm *ServerManager, window *Window, input string) error {
+	if window == nil {
+		return ErrNoServer
+	}
+	ignoreList := cm.GetIgnoreList()
+	if ignoreList == nil {
+		return errors.New("ignore list unavailable")
+	}
+	entry := config.Ignore{}
+	for _, arg := range strings.Fields(input) {
+		if arg == "--network" {
+			entry.Network = window.GetServer().GetID()
+		} else if arg == "--channel" {
+			if !window.IsChannel() {
+				return errors.New("not on a channel")
+			}
+			entry.Channel = window.GetName()
+		} else if strings.HasPrefix(arg, "--channel=") {
+			entry.Channel = strings.TrimPrefix(arg, "--channel=")
+		} else if strings.HasPrefix(arg, "--types=") {
+			entry.Types = strings.Split(strings.TrimPrefix(arg, "--types="), ",")
+		} else if strings.HasPrefix(arg, "--") {
+			return fmt.Errorf("unknown option %s", arg)
+		} else if entry.Mask == "" {
+			entry.Mask = arg
+		} else {
+			return errors.New("only one mask can be ignored at a time")
+		}
+	}
+	if entry.Mask == "" {
+		showIgnoreList(window, ignoreList.Entries())
+		return nil
+	}
+	if err := ignoreList.Add(entry); err != nil {
+		return err
+	}
+	window.AddMessage(NewEvent(EventHelp, window.GetServer().timestampFormat, false, "Ignoring "+describeIgnore(NormaliseIgnore(entry))))
+	return nil
+}
+
+func showIgnoreList(window *Window, entries []config.Ignore) {
+	timestampFormat := window.GetServer().timestampFormat
+	if len(entries) == 0 {
+		window.AddMessage(NewEvent(EventHelp, timestampFormat, false, "Ignore list is empty"))
+		return
+	}
+	window.AddMessage(NewEvent(EventHelp, timestampFormat, false, "Ignore list:"))
+	for i := range entries {
+		window.AddMessage(NewEvent(EventHelp, timestampFormat, false, describeIgnore(entries[i])))
+	}
+}
+
+// NormaliseIgnore returns the entry with its mask expanded to match how it is stored
+func NormaliseIgnore(entry config.Ignore) config.Ignore {
+	entry.Mask = NormaliseMask(entry.Mask)
+	return entry
+}
+
+func describeIgnore(entry config.Ignore) string {
+	description := entry.Mask
+	if entry.Network != "" {
+		description += " on network " + entry.Network
+	}
+	if entry.Channel != "" {
+		description += " in " + entry.Channel
+	}
+	if len(entry.Types) > 0 {
+		description += " (" + strings.Join(entry.Types, ", ") + ")"
+	}
+	return description
+}
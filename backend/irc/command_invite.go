@@ -0,0 +1,40 @@
+package irc
+
+import (
+	"strings"
+)
+
+type Invite struct{}
+
+func (c Invite) GetName() string {
+	return "invite"
+}
+
+func (c Invite) GetHelp() string {
+	return "Invites users to a channel. Usage: /invite <nick> [<nick>...] [channel]"
+}
+
+func (c Invite) Execute(_ *ServerManager, window *Window, input string) error {
+	if window == nil || window.connection == nil {
+		return ErrNoServer
+	}
+	nicknames := strings.Fields(input)
+	var channel string
+	if len(nicknames) > 0 && window.connection.IsTargetChannel(nicknames[len(nicknames)-1]) {
+		channel = nicknames[len(nicknames)-1]
+		nicknames = nicknames[:len(nicknames)-1]
+	} else if window.IsChannel() {
+		channel = window.GetName()
+	} else {
+		return ErrNoChannel
+	}
+	if len(nicknames) == 0 {
+		return ErrNoNick
+	}
+	for i := range nicknames {
+		if err := window.connection.SendInvite(nicknames[i], channel); err != nil {
+			return err
+		}
+	}
+	return nil
+}
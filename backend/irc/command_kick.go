@@ -0,0 +1,45 @@
+package irc
+
+import (
+	"github.com/greboid/tithon/config"
+	"strings"
+)
+
+// Kick removes one or more comma separated users from a channel, optionally banning them first
+type Kick struct {
+	name string
+	help string
+	ban  bool
+	conf *config.Config
+}
+
+func (c Kick) GetName() string {
+	return c.name
+}
+
+func (c Kick) GetHelp() string {
+	return c.help
+}
+
+func (c Kick) Execute(_ *ServerManager, window *Window, input string) error {
+	channel, rest, err := getOpTarget(window, input)
+	if err != nil {
+		return err
+	}
+	targets, reason, _ := strings.Cut(rest, " ")
+	var nicknames []string
+	for _, nickname := range strings.Split(targets, ",") {
+		if nickname != "" {
+			nicknames = append(nicknames, nickname)
+		}
+	}
+	if len(nicknames) == 0 {
+		return ErrNoNick
+	}
+	if c.ban {
+		if err = sendBans(window.connection, c.conf, channel, true, "b", nicknames); err != nil {
+			return err
+		}
+	}
+	return window.connection.SendKick(channel, nicknames, strings.TrimSpace(reason))
+}
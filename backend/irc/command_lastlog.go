@@ -0,0 +1,80 @@
+package irc
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// lastlogLimit is the maximum number of lines /lastlog will print
+const lastlogLimit = 100
+
+type Lastlog struct{}
+
+func (c Lastlog) GetName() string {
+	return "lastlog"
+}
+
+func (c Lastlog) GetHelp() string {
+	return "Shows earlier lines matching a search term from this window, or every window with -all. " +
+		"Usage: /lastlog [-regex] [-nick nickname] [-all] term"
+}
+
+func (c Lastlog) Execute(cm *ServerManager, window *Window, input string) error {
+	if window == nil {
+		return ErrNoServer
+	}
+	query := SearchQuery{In: window, Limit: lastlogLimit}
+	args := strings.Fields(input)
+parse:
+	for len(args) > 0 && strings.HasPrefix(args[0], "-") {
+		switch args[0] {
+		case "-regex":
+			query.Regex = true
+		case "-all":
+			query.In = nil
+		case "-nick":
+			if len(args) < 2 {
+				return errors.New("no nickname specified")
+			}
+			query.Nick = args[1]
+			args = args[1:]
+		case "--":
+			args = args[1:]
+			break parse
+		default:
+			return fmt.Errorf("unknown option %s", args[0])
+		}
+		args = args[1:]
+	}
+	query.Text = strings.Join(args, " ")
+	if query.Text == "" && query.Nick == "" {
+		return errors.New("no search term specified")
+	}
+	results, err := cm.Search(query)
+	if err != nil {
+		return err
+	}
+	showLastlog(window, query, results)
+	return nil
+}
+
+func showLastlog(window *Window, query SearchQuery, results []SearchResult) {
+	timestampFormat := window.GetServer().timestampFormat
+	if len(results) == 0 {
+		window.AddMessage(NewEvent(EventHelp, timestampFormat, false, "No lines found"))
+		return
+	}
+	if len(results) == lastlogLimit {
+		window.AddMessage(NewEvent(EventHelp, timestampFormat, false, fmt.Sprintf("Lastlog: showing the last %d matching lines", lastlogLimit)))
+	} else {
+		window.AddMessage(NewEvent(EventHelp, timestampFormat, false, fmt.Sprintf("Lastlog: %d matching lines", len(results))))
+	}
+	for i := range results {
+		line := fmt.Sprintf("%s <%s> %s", results[i].Message.GetTimestamp(), results[i].Message.GetNickname(), results[i].Message.GetPlainDisplayMessage())
+		if query.In == nil {
+			line = "[" + results[i].Window.GetName() + "] " + line
+		}
+		window.AddMessage(NewEvent(EventHelp, timestampFormat, false, line))
+	}
+}
@@ -0,0 +1,25 @@
+package irc
+
+type ListCommand struct{}
+
+func (c ListCommand) GetName() string {
+	return "list"
+}
+
+func (c ListCommand) GetHelp() string {
+	return "Lists the channels on the network, optionally filtered by channel masks or the number of users. " +
+		"Usage: /list [mask...] [>users] [<users]"
+}
+
+func (c ListCommand) Execute(cm *ServerManager, window *Window, input string) error {
+	if window == nil || window.GetServer() == nil {
+		return ErrNoServer
+	}
+	if err := window.GetServer().RequestChannelList(input); err != nil {
+		return err
+	}
+	if viewer := cm.GetChannelListViewer(); viewer != nil {
+		viewer.ShowChannelList(window.GetServer())
+	}
+	return nil
+}
@@ -2,6 +2,7 @@ package irc
 
 import (
 	"errors"
+	"strings"
 )
 
 type Reconnect struct{}
@@ -11,10 +12,10 @@ func (c Reconnect) GetName() string {
 }
 
 func (c Reconnect) GetHelp() string {
-	return "Reconnects to the current server. Usage: /reconnect"
+	return "Reconnects to the current server straight away, or stops any further reconnection attempts. Usage: /reconnect [-cancel]"
 }
 
-func (c Reconnect) Execute(_ *ServerManager, window *Window, _ string) error {
+func (c Reconnect) Execute(_ *ServerManager, window *Window, input string) error {
 	if window == nil {
 		return errors.New("no window specified")
 	}
@@ -23,8 +24,14 @@ func (c Reconnect) Execute(_ *ServerManager, window *Window, _ string) error {
 		return errors.New("not connected to a server")
 	}
 
-	connection.CancelReconnection()
-	connection.connection.Reconnect()
+	switch strings.TrimSpace(input) {
+	case "":
+		connection.ReconnectNow()
+	case "-cancel", "-stop":
+		connection.CancelReconnection()
+	default:
+		return errors.New("unknown option, usage: /reconnect [-cancel]")
+	}
 
 	return nil
 }
@@ -1,6 +1,7 @@
 package irc
 
 import (
+	"errors"
 	"strings"
 )
 
@@ -11,16 +12,23 @@ func (c ChangeTopic) GetName() string {
 }
 
 func (c ChangeTopic) GetHelp() string {
-	return "Changes the topic"
+	return "Shows or changes the topic, -clear removes it. Usage: /topic [-clear] [channel] [topic]"
 }
 
 func (c ChangeTopic) Execute(_ *ServerManager, window *Window, input string) error {
-	if window == nil {
-		return ErrNoServer
+	input, clearTopic := strings.CutPrefix(input, "-clear")
+	if clearTopic && input != "" && input[0] != ' ' {
+		return errors.New("unknown option")
 	}
-	split := strings.SplitN(input, " ", 2)
-	if len(split) != 2 {
-		return ErrNoChannel
+	channel, topic, err := getOpTarget(window, input)
+	if err != nil {
+		return err
 	}
-	return window.GetServer().SendTopic(split[0], split[1])
+	if clearTopic {
+		return window.GetServer().SendTopic(channel, "")
+	}
+	if topic == "" {
+		return window.GetServer().RequestTopic(channel)
+	}
+	return window.GetServer().SendTopic(channel, topic)
 }
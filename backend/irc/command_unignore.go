@@ -0,0 +1,32 @@
+package irc
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+type Unignore struct{}
+
+func (c Unignore) GetName() string {
+	return "unignore"
+}
+
+func (c Unignore) GetHelp() string {
+	return "Removes a mask from the ignore list. Usage: /unignore mask"
+}
+
+func (c Unignore) Execute(cm *ServerManager, window *Window, input string) error {
+	if window == nil {
+		return ErrNoServer
+	}
+	mask := strings.TrimSpace(input)
+	if mask == "" {
+		return errors.New("no mask specified")
+	}
+	if cm.GetIgnoreList().Remove(mask) == 0 {
+		return fmt.Errorf("%s is not ignored", NormaliseMask(mask))
+	}
+	window.AddMessage(NewEvent(EventHelp, window.GetServer().timestampFormat, false, "No longer ignoring "+NormaliseMask(mask)))
+	return nil
+}
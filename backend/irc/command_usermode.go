@@ -0,0 +1,36 @@
+package irc
+
+import (
+	"strings"
+)
+
+// ChannelUserMode gives or takes a channel privilege such as op or voice from one or more users
+type ChannelUserMode struct {
+	name string
+	help string
+	mode string
+	add  bool
+}
+
+func (c ChannelUserMode) GetName() string {
+	return c.name
+}
+
+func (c ChannelUserMode) GetHelp() string {
+	return c.help
+}
+
+func (c ChannelUserMode) Execute(_ *ServerManager, window *Window, input string) error {
+	channel, rest, err := getOpTarget(window, input)
+	if err != nil {
+		return err
+	}
+	nicknames := strings.Fields(rest)
+	if len(nicknames) == 0 {
+		return ErrNoNick
+	}
+	if err = window.connection.checkChannelMode(c.mode, 'P'); err != nil {
+		return err
+	}
+	return window.connection.SendChannelModes(channel, c.add, c.mode, nicknames)
+}
@@ -0,0 +1,30 @@
+package irc
+
+import (
+	"errors"
+	"strings"
+)
+
+type Who struct{}
+
+func (c Who) GetName() string {
+	return "who"
+}
+
+func (c Who) GetHelp() string {
+	return "Lists the users in a channel or matching a mask, defaults to the current channel. Usage: /who [channel|mask]"
+}
+
+func (c Who) Execute(_ *ServerManager, window *Window, input string) error {
+	if window == nil || window.GetServer() == nil {
+		return ErrNoServer
+	}
+	mask := strings.TrimSpace(input)
+	if mask == "" && window.IsChannel() {
+		mask = window.GetName()
+	}
+	if mask == "" || strings.Contains(mask, " ") {
+		return errors.New("a single channel or mask is required")
+	}
+	return window.GetServer().RequestWho(mask, true, PriorityInteractive)
+}
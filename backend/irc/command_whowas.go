@@ -0,0 +1,26 @@
+package irc
+
+import (
+	"strings"
+)
+
+type Whowas struct{}
+
+func (c Whowas) GetName() string {
+	return "whowas"
+}
+
+func (c Whowas) GetHelp() string {
+	return "Looks up information about a nickname that's no longer in use. Usage: /whowas <nick>"
+}
+
+func (c Whowas) Execute(_ *ServerManager, window *Window, input string) error {
+	if window == nil || window.GetServer() == nil {
+		return ErrNoServer
+	}
+	nickname := strings.TrimSpace(input)
+	if nickname == "" {
+		return ErrNoNick
+	}
+	return window.GetServer().RequestWhowas(nickname)
+}
@@ -0,0 +1,142 @@
+package irc
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/greboid/tithon/config"
+)
+
+type WindowCommand struct{}
+
+func (c WindowCommand) GetName() string {
+	return "window"
+}
+
+func (c WindowCommand) GetHelp() string {
+	return "Shows or changes the settings for this channel or query: notify (" + strings.Join(config.NotifyLevels, "|") +
+		"), muted (on|off), events (" + strings.Join(config.MembershipModes, "|") + "|" + membershipDefault + "), " +
+		"highlights (comma separated words) and logging (on|off). Usage: /window [set <setting> [value]|reset]"
+}
+
+func (c WindowCommand) Execute(_ *ServerManager, window *Window, input string) error {
+	if window == nil {
+		return ErrNoServer
+	}
+	if !window.IsChannel() && !window.IsQuery() {
+		return errors.New("only channels and queries have settings")
+	}
+	args := strings.Fields(input)
+	if len(args) == 0 {
+		showWindowSettings(window)
+		return nil
+	}
+	var settings config.WindowSettings
+	switch args[0] {
+	case "set":
+		if len(args) < 2 {
+			return errors.New("no setting specified")
+		}
+		var err error
+		settings, err = setWindowSetting(window.GetSettings(), args[1], strings.Join(args[2:], " "))
+		if err != nil {
+			return err
+		}
+	case "reset":
+		settings = config.WindowSettings{Window: window.GetName()}
+	default:
+		return fmt.Errorf("unknown action %s", args[0])
+	}
+	if err := window.GetServer().UpdateWindowSettings(settings); err != nil {
+		return err
+	}
+	showWindowSettings(window)
+	return nil
+}
+
+func (c WindowCommand) CompleteArgument(_ *Server, args []string) []string {
+	switch {
+	case len(args) == 0:
+		return []string{"set", "reset"}
+	case args[0] != "set":
+		return nil
+	case len(args) == 1:
+		return []string{"notify", "muted", "events", "highlights", "logging"}
+	case len(args) > 2:
+		return nil
+	}
+	switch args[1] {
+	case "notify":
+		return config.NotifyLevels
+	case "muted", "logging":
+		return []string{"on", "off"}
+	case "events":
+		return append(slices.Clone(config.MembershipModes), membershipDefault)
+	}
+	return nil
+}
+
+// setWindowSetting changes one of a window's settings using the value given to /window set
+func setWindowSetting(settings config.WindowSettings, name string, value string) (config.WindowSettings, error) {
+	switch name {
+	case "notify":
+		settings.Notify = value
+	case "muted":
+		switch value {
+		case "on":
+			settings.Muted = true
+		case "off", "":
+			settings.Muted = false
+		default:
+			return settings, fmt.Errorf("invalid muted setting %s", value)
+		}
+	case "events":
+		if value == membershipDefault {
+			value = ""
+		}
+		settings.Membership = value
+	case "logging":
+		switch value {
+		case "on", "":
+			settings.NoLog = false
+		case "off":
+			settings.NoLog = true
+		default:
+			return settings, fmt.Errorf("invalid logging setting %s", value)
+		}
+	case "highlights":
+		settings.Highlights = nil
+		for _, word := range strings.Split(value, ",") {
+			if word = strings.TrimSpace(word); word != "" {
+				settings.Highlights = append(settings.Highlights, word)
+			}
+		}
+	default:
+		return settings, fmt.Errorf("unknown setting %s", name)
+	}
+	return settings, nil
+}
+
+func showWindowSettings(window *Window) {
+	settings := window.GetSettings()
+	muted := "off"
+	if settings.Muted {
+		muted = "on"
+	}
+	logging := "on"
+	if settings.NoLog {
+		logging = "off"
+	}
+	window.AddMessage(NewEvent(EventHelp, window.GetServer().timestampFormat, false, fmt.Sprintf(
+		"%s: notify %s, muted %s, events %s, highlights %s, logging %s",
+		window.GetName(),
+		cmp.Or(settings.Notify, config.NotifyAll),
+		muted,
+		cmp.Or(settings.Membership, membershipDefault),
+		cmp.Or(strings.Join(settings.Highlights, ", "), "none"),
+		logging,
+	)))
+}
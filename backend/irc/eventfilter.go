@@ -0,0 +1,244 @@
+package irc
+
+import (
+	"errors"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/greboid/tithon/config"
+)
+
+var (
+	// membershipText matches joins, parts and quits as written by Tithon, irssi and weechat, eg
+	// "nick!user@host has joined #channel" or "nick [user@host] has quit [reason]"
+	membershipText = regexp.MustCompile(`^([^\s!\[(]+)(?:!\S+|\s[\[(]\S*[\])])? has (joined|parted|left|quit)\b`)
+	// zncMembershipText matches joins, parts and quits as written by ZNC, eg "Joins: nick (user@host)"
+	zncMembershipText = regexp.MustCompile(`^(Joins|Parts|Quits): (\S+)`)
+)
+
+// parseMembershipEvent works out if the text of an event is a join, part or quit, so they can be filtered in history
+// imported from logs, and returns the type of event and the nickname it's about
+func parseMembershipEvent(text string) (EventType, string, bool) {
+	if match := membershipText.FindStringSubmatch(text); match != nil {
+		switch match[2] {
+		case "joined":
+			return EventJoin, match[1], true
+		case "parted", "left":
+			return EventPart, match[1], true
+		default:
+			return EventQuit, match[1], true
+		}
+	}
+	if match := zncMembershipText.FindStringSubmatch(text); match != nil {
+		switch match[1] {
+		case "Joins":
+			return EventJoin, match[2], true
+		case "Parts":
+			return EventPart, match[2], true
+		default:
+			return EventQuit, match[2], true
+		}
+	}
+	return 0, "", false
+}
+
+// EventFilter decides which joins, parts and quits are shown in channels, changes to the global settings are passed
+// to the save callback so they can be persisted
+type EventFilter struct {
+	mutex    sync.RWMutex
+	settings config.EventFilter
+	save     func(config.EventFilter)
+}
+
+func NewEventFilter(settings config.EventFilter, save func(config.EventFilter)) *EventFilter {
+	return &EventFilter{settings: settings, save: save}
+}
+
+// GetSettings returns the global settings
+func (f *EventFilter) GetSettings() config.EventFilter {
+	if f == nil {
+		return config.EventFilter{Membership: config.MembershipShow}
+	}
+	f.mutex.RLock()
+	defer f.mutex.RUnlock()
+	return f.settings
+}
+
+// SetMembership sets how joins, parts and quits are shown in every channel without its own setting
+func (f *EventFilter) SetMembership(membership string) error {
+	if f == nil {
+		return errors.New("event filter unavailable")
+	}
+	if !slices.Contains(config.MembershipModes, membership) {
+		return errors.New("unknown mode: " + membership)
+	}
+	f.mutex.Lock()
+	f.settings.Membership = membership
+	f.mutex.Unlock()
+	f.persist()
+	return nil
+}
+
+// SetCollapse sets whether consecutive joins, parts and quits are combined into a single line
+func (f *EventFilter) SetCollapse(collapse bool) error {
+	if f == nil {
+		return errors.New("event filter unavailable")
+	}
+	f.mutex.Lock()
+	f.settings.Collapse = collapse
+	f.mutex.Unlock()
+	f.persist()
+	return nil
+}
+
+// SetSmartDuration sets how recently a user must have spoken for the smart filter to show their joins, parts and quits
+func (f *EventFilter) SetSmartDuration(duration time.Duration) error {
+	if f == nil {
+		return errors.New("event filter unavailable")
+	}
+	if duration <= 0 {
+		return errors.New("duration must be positive")
+	}
+	f.mutex.Lock()
+	f.settings.SmartDuration = duration
+	f.mutex.Unlock()
+	f.persist()
+	return nil
+}
+
+// SetEventFilter sets the filter used to hide joins, parts and quits in this server's channels
+func (c *Server) SetEventFilter(filter *EventFilter) {
+	c.eventFilter = filter
+}
+
+func (f *EventFilter) persist() {
+	if f.save != nil {
+		f.save(f.GetSettings())
+	}
+}
+
+// Filter returns the messages that should be shown in a window, hiding joins, parts and quits according to the
+// window's own setting, or the global setting if it's empty, and collapsing runs of them if enabled
+func (f *EventFilter) Filter(membership string, messages []*Message) []*Message {
+	if f == nil {
+		return messages
+	}
+	settings := f.GetSettings()
+	if membership == "" {
+		membership = settings.Membership
+	}
+	switch membership {
+	case config.MembershipHide:
+		messages = slices.DeleteFunc(messages, (*Message).isMembership)
+	case config.MembershipSmart:
+		messages = smartFilter(messages, settings.SmartDuration)
+	}
+	if settings.Collapse {
+		messages = collapseMembership(messages)
+	}
+	return messages
+}
+
+// hides checks if a join, part or quit just added after the previous messages will be hidden, so it doesn't mark the
+// window as unread.  Joins the smart filter shows because the user speaks afterwards count as hidden, the message they
+// send marks the window instead.
+func (f *EventFilter) hides(membership string, message *Message, previous []*Message) bool {
+	if f == nil || !message.isMembership() {
+		return false
+	}
+	settings := f.GetSettings()
+	if membership == "" {
+		membership = settings.Membership
+	}
+	switch membership {
+	case config.MembershipHide:
+		return true
+	case config.MembershipSmart:
+		from := message.timestamp.Add(-settings.SmartDuration)
+		for i := len(previous) - 1; i >= 0 && !previous[i].timestamp.Before(from); i-- {
+			if isChatType(previous[i].messageType) && strings.EqualFold(previous[i].nickname, message.subject) {
+				return false
+			}
+		}
+		return true
+	default:
+		return false
+	}
+}
+
+// smartFilter hides joins, parts and quits from users who haven't spoken within duration before the event, or for
+// joins after it
+func smartFilter(messages []*Message, duration time.Duration) []*Message {
+	spoken := map[string][]time.Time{}
+	for _, message := range messages {
+		switch message.messageType {
+		case Normal, Action, Notice, Highlight, HighlightAction, HighlightNotice:
+			if message.nickname != "" {
+				nickname := strings.ToLower(message.nickname)
+				spoken[nickname] = append(spoken[nickname], message.timestamp)
+			}
+		}
+	}
+	return slices.DeleteFunc(messages, func(message *Message) bool {
+		if !message.isMembership() {
+			return false
+		}
+		from, to := message.timestamp.Add(-duration), message.timestamp
+		if message.eventType == EventJoin {
+			to = message.timestamp.Add(duration)
+		}
+		return !slices.ContainsFunc(spoken[strings.ToLower(message.subject)], func(when time.Time) bool {
+			return !when.Before(from) && !when.After(to)
+		})
+	})
+}
+
+// collapseMembership replaces each run of consecutive joins, parts and quits with a single summary event
+func collapseMembership(messages []*Message) []*Message {
+	var collapsed []*Message
+	for start := 0; start < len(messages); {
+		end := start + 1
+		if messages[start].isMembership() {
+			for end < len(messages) && messages[end].isMembership() {
+				end++
+			}
+		}
+		if end-start > 1 {
+			collapsed = append(collapsed, newMembershipSummary(messages[start:end]))
+		} else {
+			collapsed = append(collapsed, messages[start])
+		}
+		start = end
+	}
+	return collapsed
+}
+
+// newMembershipSummary creates an event describing several joins, parts and quits, eg "alice, bob joined; carol
+// quit".  It takes the ID of the first event so it keeps its place when the window is redrawn.
+func newMembershipSummary(events []*Message) *Message {
+	actions := map[EventType]string{EventJoin: "joined", EventPart: "parted", EventQuit: "quit"}
+	var order []EventType
+	nicknames := map[EventType][]string{}
+	for _, event := range events {
+		if !slices.Contains(order, event.eventType) {
+			order = append(order, event.eventType)
+		}
+		if !slices.Contains(nicknames[event.eventType], event.subject) {
+			nicknames[event.eventType] = append(nicknames[event.eventType], event.subject)
+		}
+	}
+	var parts []string
+	for _, eventType := range order {
+		parts = append(parts, strings.Join(nicknames[eventType], ", ")+" "+actions[eventType])
+	}
+	first := events[0]
+	summary := newMessage(first.timestampFormat, false, "", strings.Join(parts, "; "), Event, map[string]string{
+		"time": first.timestamp.UTC().Format(v3TimestampFormat),
+	}, nil)
+	summary.id = first.id
+	summary.window = first.window
+	return summary
+}
@@ -0,0 +1,183 @@
+package irc
+
+import (
+	"testing"
+	"time"
+
+	"github.com/greboid/tithon/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseMembershipEvent(t *testing.T) {
+	tests := []struct {
+		text      string
+		wantType  EventType
+		wantNick  string
+		wantFound bool
+	}{
+		{text: "alice!al@example.com has joined #tithon", wantType: EventJoin, wantNick: "alice", wantFound: true},
+		{text: "alice!al@example.com has parted #tithon", wantType: EventPart, wantNick: "alice", wantFound: true},
+		{text: "alice!al@example.com has quit Leaving", wantType: EventQuit, wantNick: "alice", wantFound: true},
+		{text: "alice [al@example.com] has joined #tithon", wantType: EventJoin, wantNick: "alice", wantFound: true},
+		{text: "alice [al@example.com] has left #tithon [bye]", wantType: EventPart, wantNick: "alice", wantFound: true},
+		{text: "alice (al@example.com) has quit (Ping timeout)", wantType: EventQuit, wantNick: "alice", wantFound: true},
+		{text: "Joins: alice (al@example.com)", wantType: EventJoin, wantNick: "alice", wantFound: true},
+		{text: "Parts: alice (al@example.com) (bye)", wantType: EventPart, wantNick: "alice", wantFound: true},
+		{text: "Quits: alice (al@example.com) (Quit: bye)", wantType: EventQuit, wantNick: "alice", wantFound: true},
+		{text: "You have joined #tithon"},
+		{text: "bob!b@example.com has kicked alice from #tithon"},
+		{text: "alice is now known as bob"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.text, func(t *testing.T) {
+			eventType, nick, found := parseMembershipEvent(tt.text)
+			assert.Equal(t, tt.wantFound, found)
+			assert.Equal(t, tt.wantType, eventType)
+			assert.Equal(t, tt.wantNick, nick)
+		})
+	}
+}
+
+func TestEventFilter_Settings(t *testing.T) {
+	var saved config.EventFilter
+	filter := NewEventFilter(config.EventFilter{Membership: config.MembershipShow, SmartDuration: time.Minute}, func(settings config.EventFilter) {
+		saved = settings
+	})
+
+	require.NoError(t, filter.SetMembership(config.MembershipSmart))
+	require.NoError(t, filter.SetCollapse(true))
+	require.NoError(t, filter.SetSmartDuration(5*time.Minute))
+	want := config.EventFilter{Membership: config.MembershipSmart, SmartDuration: 5 * time.Minute, Collapse: true}
+	assert.Equal(t, want, filter.GetSettings())
+	assert.Equal(t, want, saved)
+
+	assert.Error(t, filter.SetMembership(""))
+	assert.Error(t, filter.SetMembership("sometimes"))
+	assert.Error(t, filter.SetSmartDuration(0))
+}
+
+func filterTestMessages(start time.Time) []*Message {
+	at := func(minutes int, message *Message) *Message {
+		message.timestamp = start.Add(time.Duration(minutes) * time.Minute)
+		return message
+	}
+	return []*Message{
+		at(0, NewMessage("", false, "alice", "hello", nil, nil)),
+		at(1, NewMembershipEvent(EventQuit, "", "alice", "alice!a@example.com has quit")),
+		at(2, NewMembershipEvent(EventJoin, "", "bob", "bob!b@example.com has joined #tithon")),
+		at(3, NewMembershipEvent(EventPart, "", "carol", "carol!c@example.com has parted #tithon")),
+		at(4, NewMembershipEvent(EventJoin, "", "dave", "dave!d@example.com has joined #tithon")),
+		at(5, NewMessage("", false, "dave", "hi", nil, nil)),
+		at(6, NewEvent(EventJoin, "", true, "You have joined #tithon")),
+		at(30, NewMembershipEvent(EventJoin, "", "erin", "erin!e@example.com has joined #tithon")),
+		at(50, NewMessage("", false, "erin", "late", nil, nil)),
+	}
+}
+
+func messageTexts(messages []*Message) []string {
+	var texts []string
+	for _, message := range messages {
+		texts = append(texts, message.GetMessage())
+	}
+	return texts
+}
+
+func TestEventFilter_Filter(t *testing.T) {
+	start := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name       string
+		settings   config.EventFilter
+		membership string
+		want       []string
+	}{
+		{
+			name:     "Show",
+			settings: config.EventFilter{Membership: config.MembershipShow, SmartDuration: 10 * time.Minute},
+			want: []string{
+				"hello", "alice!a@example.com has quit", "bob!b@example.com has joined #tithon",
+				"carol!c@example.com has parted #tithon", "dave!d@example.com has joined #tithon", "hi",
+				"You have joined #tithon", "erin!e@example.com has joined #tithon", "late",
+			},
+		},
+		{
+			name:     "Hide",
+			settings: config.EventFilter{Membership: config.MembershipHide, SmartDuration: 10 * time.Minute},
+			want:     []string{"hello", "hi", "You have joined #tithon", "late"},
+		},
+		{
+			name:     "Smart",
+			settings: config.EventFilter{Membership: config.MembershipSmart, SmartDuration: 10 * time.Minute},
+			want: []string{
+				"hello", "alice!a@example.com has quit", "dave!d@example.com has joined #tithon", "hi",
+				"You have joined #tithon", "late",
+			},
+		},
+		{
+			name:       "Window setting",
+			settings:   config.EventFilter{Membership: config.MembershipShow},
+			membership: config.MembershipHide,
+			want:       []string{"hello", "hi", "You have joined #tithon", "late"},
+		},
+		{
+			name:     "Collapse",
+			settings: config.EventFilter{Membership: config.MembershipShow, Collapse: true},
+			want: []string{
+				"hello", "alice quit; bob, dave joined; carol parted", "hi", "You have joined #tithon",
+				"erin!e@example.com has joined #tithon", "late",
+			},
+		},
+		{
+			name:     "Smart and collapse",
+			settings: config.EventFilter{Membership: config.MembershipSmart, SmartDuration: 10 * time.Minute, Collapse: true},
+			want:     []string{"hello", "alice quit; dave joined", "hi", "You have joined #tithon", "late"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filter := NewEventFilter(tt.settings, nil)
+			assert.Equal(t, tt.want, messageTexts(filter.Filter(tt.membership, filterTestMessages(start))))
+		})
+	}
+}
+
+func TestCollapseMembership_KeepsFirstEvent(t *testing.T) {
+	messages := filterTestMessages(time.Date(2025, 1, 1, 12, 0, 0, 0, time.Local))[1:5]
+	collapsed := collapseMembership(messages)
+	require.Len(t, collapsed, 1)
+	assert.Equal(t, messages[0].GetID(), collapsed[0].GetID())
+	assert.Equal(t, messages[0].GetTime().Truncate(time.Millisecond), collapsed[0].GetTime())
+	assert.Equal(t, MessageType(Event), collapsed[0].GetType())
+}
+
+func TestEventFilter_Nil(t *testing.T) {
+	var filter *EventFilter
+	messages := filterTestMessages(time.Now())
+	assert.Equal(t, messages, filter.Filter("", messages))
+}
+
+func TestWindow_AddMessage_HiddenEvents(t *testing.T) {
+	tests := []struct {
+		name       string
+		membership string
+		nickname   string
+		want       string
+	}{
+		{name: "Show", membership: config.MembershipShow, nickname: "bob", want: UnreadEvent},
+		{name: "Hide", membership: config.MembershipHide, nickname: "alice", want: ""},
+		{name: "Smart quiet user", membership: config.MembershipSmart, nickname: "bob", want: ""},
+		{name: "Smart recent speaker", membership: config.MembershipSmart, nickname: "Alice", want: UnreadEvent},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := &Server{Window: &Window{id: "libera"}}
+			server.SetEventFilter(NewEventFilter(config.EventFilter{Membership: tt.membership, SmartDuration: 10 * time.Minute}, nil))
+			window := &Window{name: "#tithon", isChannel: true, connection: server, state: Active}
+			window.addMessage(NewMessage(time.TimeOnly, false, "alice", "hello", nil, nil))
+			window.state = ""
+
+			window.addMessage(NewMembershipEvent(EventQuit, time.TimeOnly, tt.nickname, tt.nickname+"!u@example.com has quit"))
+			assert.Equal(t, tt.want, window.GetState())
+		})
+	}
+}
@@ -0,0 +1,160 @@
+package irc
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	ExportHTML = "html"
+	ExportText = "text"
+	ExportJSON = "json"
+)
+
+// HTMLExporter renders messages as a standalone HTML page styled the same as the messages shown in the client
+type HTMLExporter interface {
+	ExportHTML(window *Window, messages []*Message) ([]byte, error)
+}
+
+// ExportRange limits an export to the messages sent from From until To, zero values are unbounded
+type ExportRange struct {
+	From time.Time
+	To   time.Time
+}
+
+func (r ExportRange) contains(when time.Time) bool {
+	if !r.From.IsZero() && when.Before(r.From) {
+		return false
+	}
+	if !r.To.IsZero() && !when.Before(r.To) {
+		return false
+	}
+	return true
+}
+
+// ParseExportRange parses either a duration back from now such as 30m, 2h or 7d, a single date, or two dates
+// separated by .. with both dates included
+func ParseExportRange(input string, now time.Time) (ExportRange, error) {
+	if input == "" {
+		return ExportRange{}, nil
+	}
+	if from, to, found := strings.Cut(input, ".."); found {
+		var exportRange ExportRange
+		var err error
+		if from != "" {
+			if exportRange.From, err = time.ParseInLocation(time.DateOnly, from, time.Local); err != nil {
+				return ExportRange{}, fmt.Errorf("invalid date: %s", from)
+			}
+		}
+		if to != "" {
+			if exportRange.To, err = time.ParseInLocation(time.DateOnly, to, time.Local); err != nil {
+				return ExportRange{}, fmt.Errorf("invalid date: %s", to)
+			}
+			exportRange.To = exportRange.To.AddDate(0, 0, 1)
+		}
+		return exportRange, nil
+	}
+	if day, err := time.ParseInLocation(time.DateOnly, input, time.Local); err == nil {
+		return ExportRange{From: day, To: day.AddDate(0, 0, 1)}, nil
+	}
+	if days, found := strings.CutSuffix(input, "d"); found {
+		count, err := strconv.Atoi(days)
+		if err != nil || count <= 0 {
+			return ExportRange{}, fmt.Errorf("invalid range: %s", input)
+		}
+		return ExportRange{From: now.AddDate(0, 0, -count)}, nil
+	}
+	duration, err := time.ParseDuration(input)
+	if err != nil || duration <= 0 {
+		return ExportRange{}, fmt.Errorf("invalid range: %s", input)
+	}
+	return ExportRange{From: now.Add(-duration)}, nil
+}
+
+// ExportMessages returns the messages in the window that were sent within the range
+func ExportMessages(window *Window, exportRange ExportRange) []*Message {
+	var messages []*Message
+	for _, message := range window.GetMessages() {
+		if exportRange.contains(message.GetTime()) {
+			messages = append(messages, message)
+		}
+	}
+	return messages
+}
+
+// Export renders the messages from a window in the given format, the HTMLExporter is only used for HTML exports
+func Export(format string, window *Window, messages []*Message, htmlExporter HTMLExporter) ([]byte, error) {
+	switch format {
+	case ExportHTML:
+		if htmlExporter == nil {
+			return nil, errors.New("html export is unavailable")
+		}
+		return htmlExporter.ExportHTML(window, messages)
+	case ExportText:
+		return exportText(messages), nil
+	case ExportJSON:
+		return exportJSON(messages)
+	default:
+		return nil, fmt.Errorf("unknown export format: %s", format)
+	}
+}
+
+// IsExportFormat checks if the input is one of the supported export formats
+func IsExportFormat(input string) bool {
+	switch input {
+	case ExportHTML, ExportText, ExportJSON:
+		return true
+	default:
+		return false
+	}
+}
+
+// ExportFilename returns the default name of the file a window is exported to
+func ExportFilename(window *Window, format string, now time.Time) string {
+	name := window.GetName()
+	if server := window.GetServer(); server != nil && !window.IsServer() {
+		name = server.GetName() + "-" + name
+	}
+	return sanitiseLogName(name) + "-" + now.Format("20060102-150405") + "." + exportExtension(format)
+}
+
+func exportExtension(format string) string {
+	if format == ExportText {
+		return "txt"
+	}
+	return format
+}
+
+func exportText(messages []*Message) []byte {
+	var output strings.Builder
+	for _, message := range messages {
+		output.WriteString(message.GetTimestamp())
+		output.WriteString(" ")
+		if nick := message.GetDisplayNickname(); nick != "" {
+			output.WriteString("<" + nick + "> ")
+		}
+		output.WriteString(message.GetPlainDisplayMessage())
+		output.WriteString("\n")
+	}
+	return []byte(output.String())
+}
+
+func exportJSON(messages []*Message) ([]byte, error) {
+	lines := make([]jsonLogLine, 0, len(messages))
+	for _, message := range messages {
+		lines = append(lines, jsonLogLine{
+			Time:      message.GetTime(),
+			Type:      logMessageType(message),
+			Nick:      message.GetNickname(),
+			Message:   message.getPlainMessage(),
+			Me:        message.IsMe(),
+			Highlight: isHighlightType(message.GetType()),
+			Tags:      message.GetTags(),
+		})
+	}
+	return json.MarshalIndent(lines, "", "  ")
+}
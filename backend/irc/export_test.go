@@ -0,0 +1,143 @@
+package irc
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeHTMLExporter struct {
+	window   *Window
+	messages []*Message
+}
+
+func (f *fakeHTMLExporter) ExportHTML(window *Window, messages []*Message) ([]byte, error) {
+	f.window = window
+	f.messages = messages
+	return []byte("<html></html>"), nil
+}
+
+func TestParseExportRange(t *testing.T) {
+	now := time.Date(2025, 3, 10, 12, 0, 0, 0, time.Local)
+	tests := []struct {
+		name    string
+		input   string
+		want    ExportRange
+		wantErr bool
+	}{
+		{name: "Empty", input: "", want: ExportRange{}},
+		{name: "Duration", input: "2h", want: ExportRange{From: now.Add(-2 * time.Hour)}},
+		{name: "Days", input: "7d", want: ExportRange{From: now.AddDate(0, 0, -7)}},
+		{
+			name:  "Single date",
+			input: "2025-03-01",
+			want:  ExportRange{From: time.Date(2025, 3, 1, 0, 0, 0, 0, time.Local), To: time.Date(2025, 3, 2, 0, 0, 0, 0, time.Local)},
+		},
+		{
+			name:  "Date range",
+			input: "2025-03-01..2025-03-03",
+			want:  ExportRange{From: time.Date(2025, 3, 1, 0, 0, 0, 0, time.Local), To: time.Date(2025, 3, 4, 0, 0, 0, 0, time.Local)},
+		},
+		{name: "Open start", input: "..2025-03-03", want: ExportRange{To: time.Date(2025, 3, 4, 0, 0, 0, 0, time.Local)}},
+		{name: "Open end", input: "2025-03-01..", want: ExportRange{From: time.Date(2025, 3, 1, 0, 0, 0, 0, time.Local)}},
+		{name: "Invalid date in range", input: "2025-03-01..tomorrow", wantErr: true},
+		{name: "Invalid days", input: "xd", wantErr: true},
+		{name: "Negative duration", input: "-2h", wantErr: true},
+		{name: "Not a range", input: "bugreport.html", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseExportRange(tt.input, now)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.True(t, tt.want.From.Equal(got.From), "From: want %s got %s", tt.want.From, got.From)
+			assert.True(t, tt.want.To.Equal(got.To), "To: want %s got %s", tt.want.To, got.To)
+		})
+	}
+}
+
+func exportTestMessages() []*Message {
+	return []*Message{
+		NewMessage("15:04", false, "alice", "hello \x02there\x02", map[string]string{"time": "2025-03-01T10:00:00.000Z", "msgid": "1"}, nil),
+		NewMessage("15:04", true, "bob", "\001ACTION waves\001", map[string]string{"time": "2025-03-02T10:00:00.000Z"}, nil),
+		NewEvent(EventJoin, "15:04", false, "carol has joined #test"),
+	}
+}
+
+func TestExportMessages(t *testing.T) {
+	window := &Window{}
+	messages := exportTestMessages()[:2]
+	for i := range messages {
+		window.AddMessage(messages[i])
+	}
+	assert.Equal(t, messages, ExportMessages(window, ExportRange{}))
+	assert.Equal(t, messages[1:], ExportMessages(window, ExportRange{From: time.Date(2025, 3, 2, 0, 0, 0, 0, time.UTC)}))
+	assert.Equal(t, messages[:1], ExportMessages(window, ExportRange{To: time.Date(2025, 3, 2, 0, 0, 0, 0, time.UTC)}))
+	assert.Empty(t, ExportMessages(window, ExportRange{From: time.Date(2025, 4, 1, 0, 0, 0, 0, time.UTC)}))
+}
+
+func TestExport_Text(t *testing.T) {
+	messages := exportTestMessages()
+	data, err := Export(ExportText, &Window{}, messages, nil)
+	require.NoError(t, err)
+	want := messages[0].GetTimestamp() + " <alice> hello there\n" +
+		messages[1].GetTimestamp() + " * bob waves\n" +
+		messages[2].GetTimestamp() + " carol has joined #test\n"
+	assert.Equal(t, want, string(data))
+}
+
+func TestExport_JSON(t *testing.T) {
+	data, err := Export(ExportJSON, &Window{}, exportTestMessages(), nil)
+	require.NoError(t, err)
+	var lines []map[string]any
+	require.NoError(t, json.Unmarshal(data, &lines))
+	require.Len(t, lines, 3)
+	when, err := time.Parse(time.RFC3339, lines[0]["time"].(string))
+	require.NoError(t, err)
+	assert.True(t, time.Date(2025, 3, 1, 10, 0, 0, 0, time.UTC).Equal(when))
+	assert.Equal(t, "message", lines[0]["type"])
+	assert.Equal(t, "alice", lines[0]["nick"])
+	assert.Equal(t, "hello there", lines[0]["message"])
+	assert.Equal(t, map[string]any{"time": "2025-03-01T10:00:00.000Z", "msgid": "1"}, lines[0]["tags"])
+	assert.Equal(t, "action", lines[1]["type"])
+	assert.Equal(t, "waves", lines[1]["message"])
+	assert.Equal(t, true, lines[1]["me"])
+	assert.Equal(t, "event", lines[2]["type"])
+	assert.NotContains(t, lines[2], "nick")
+}
+
+func TestExport_HTML(t *testing.T) {
+	window := &Window{}
+	messages := exportTestMessages()
+	exporter := &fakeHTMLExporter{}
+	data, err := Export(ExportHTML, window, messages, exporter)
+	require.NoError(t, err)
+	assert.Equal(t, "<html></html>", string(data))
+	assert.Equal(t, window, exporter.window)
+	assert.Equal(t, messages, exporter.messages)
+
+	_, err = Export(ExportHTML, window, messages, nil)
+	assert.Error(t, err)
+}
+
+func TestExport_UnknownFormat(t *testing.T) {
+	_, err := Export("pdf", &Window{}, exportTestMessages(), nil)
+	assert.Error(t, err)
+}
+
+func TestExportFilename(t *testing.T) {
+	now := time.Date(2025, 3, 10, 12, 30, 15, 0, time.Local)
+	server := &Server{Window: &Window{id: "server1", name: "libera", isServer: true}}
+	server.Window.connection = server
+	channel := &Window{id: "channel1", name: "#go/nuts", connection: server, isChannel: true}
+
+	assert.Equal(t, "libera-#go_nuts-20250310-123015.txt", ExportFilename(channel, ExportText, now))
+	assert.Equal(t, "libera-20250310-123015.html", ExportFilename(server.Window, ExportHTML, now))
+	assert.Equal(t, "libera-20250310-123015.json", ExportFilename(server.Window, ExportJSON, now))
+}
@@ -0,0 +1,71 @@
+package irc
+
+import (
+	"github.com/ergochat/irc-go/ircmsg"
+	"log/slog"
+	"strconv"
+	"time"
+)
+
+// HandleRPLChannelModeIs replaces the channel's settings with every mode the server says is set
+func HandleRPLChannelModeIs(
+	setPendingUpdate func(),
+	getChannelByName func(string) (*Channel, error),
+	getChannelModeType func(string) rune,
+) func(message ircmsg.Message) {
+	return func(message ircmsg.Message) {
+		if len(message.Params) < 3 {
+			slog.Debug("Invalid channel modes", "message", message)
+			return
+		}
+		channel, err := getChannelByName(message.Params[1])
+		if err != nil {
+			slog.Debug("Received modes for unknown channel", "channel", message.Params[1])
+			return
+		}
+		defer setPendingUpdate()
+		var modes []*ChannelMode
+		param := 3
+		for _, char := range message.Params[2] {
+			mode := string(char)
+			modeType := getChannelModeType(mode)
+			switch modeType {
+			case 'B', 'C':
+				var parameter string
+				if param < len(message.Params) {
+					parameter = message.Params[param]
+					param++
+				}
+				modes = append(modes, NewChannelMode(modeType, mode, parameter, true))
+			case 'D':
+				modes = append(modes, NewChannelMode(modeType, mode, "", true))
+			}
+		}
+		channel.replaceChannelModes(modes)
+	}
+}
+
+// HandleRPLCreationTime records when a channel was created
+func HandleRPLCreationTime(
+	setPendingUpdate func(),
+	getChannelByName func(string) (*Channel, error),
+) func(message ircmsg.Message) {
+	return func(message ircmsg.Message) {
+		if len(message.Params) < 3 {
+			slog.Debug("Invalid channel creation time", "message", message)
+			return
+		}
+		channel, err := getChannelByName(message.Params[1])
+		if err != nil {
+			slog.Debug("Received creation time for unknown channel", "channel", message.Params[1])
+			return
+		}
+		seconds, err := strconv.ParseInt(message.Params[2], 10, 64)
+		if err != nil {
+			slog.Debug("Failed to parse channel creation time", "time", message.Params[2], "error", err)
+			return
+		}
+		defer setPendingUpdate()
+		channel.setCreated(time.Unix(seconds, 0))
+	}
+}
@@ -0,0 +1,70 @@
+package irc
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ergochat/irc-go/ircmsg"
+	"github.com/stretchr/testify/assert"
+)
+
+func testChannelModeType(mode string) rune {
+	switch {
+	case strings.Contains("beI", mode):
+		return 'A'
+	case mode == "k":
+		return 'B'
+	case mode == "l":
+		return 'C'
+	case strings.Contains("imnpst", mode):
+		return 'D'
+	}
+	return '?'
+}
+
+func TestHandleRPLChannelModeIs(t *testing.T) {
+	channel := &Channel{Window: &Window{name: "#test"}}
+	channel.SetChannelMode('A', "b", "*!*@example.com", true)
+	channel.SetChannelMode('D', "m", "", true)
+	channel.SetChannelMode('D', "i", "", false)
+	getChannelByName := func(name string) (*Channel, error) {
+		if name == "#test" {
+			return channel, nil
+		}
+		return nil, assert.AnError
+	}
+	updates := 0
+	handler := HandleRPLChannelModeIs(func() { updates++ }, getChannelByName, testChannelModeType)
+
+	handler(ircmsg.Message{Command: "324", Params: []string{"me", "#test", "+ntkl", "secret", "50"}})
+	assert.Equal(t, 1, updates)
+	assert.Equal(t, []*ChannelMode{
+		{Type: 'A', Mode: "b", Parameter: "*!*@example.com", Set: true},
+		{Type: 'D', Mode: "n", Set: true},
+		{Type: 'D', Mode: "t", Set: true},
+		{Type: 'B', Mode: "k", Parameter: "secret", Set: true},
+		{Type: 'C', Mode: "l", Parameter: "50", Set: true},
+	}, channel.GetChannelModes())
+
+	handler(ircmsg.Message{Command: "324", Params: []string{"me", "#other", "+n"}})
+	handler(ircmsg.Message{Command: "324", Params: []string{"me", "#test"}})
+	assert.Equal(t, 1, updates, "invalid messages and unknown channels shouldn't update")
+	assert.Len(t, channel.GetChannelModes(), 5)
+}
+
+func TestHandleRPLCreationTime(t *testing.T) {
+	channel := &Channel{Window: &Window{name: "#test"}}
+	getChannelByName := func(name string) (*Channel, error) {
+		if name == "#test" {
+			return channel, nil
+		}
+		return nil, assert.AnError
+	}
+	handler := HandleRPLCreationTime(func() {}, getChannelByName)
+
+	handler(ircmsg.Message{Command: "329", Params: []string{"me", "#test", "invalid"}})
+	assert.True(t, channel.GetCreated().IsZero())
+	handler(ircmsg.Message{Command: "329", Params: []string{"me", "#test", "1740823200"}})
+	assert.Equal(t, time.Unix(1740823200, 0), channel.GetCreated())
+}
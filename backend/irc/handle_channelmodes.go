@@ -4,7 +4,6 @@ import (
 	"fmt"
 	"github.com/ergochat/irc-go/ircmsg"
 	"log/slog"
-	"strings"
 )
 
 func HandleChannelModes(
@@ -27,11 +26,11 @@ func HandleChannelModes(
 			mode := getModeNameForMode(change.mode)
 			users := channel.GetUsers()
 			for j := range users {
-				if users[j].nickname == change.nickname {
+				if users[j].GetNickname() == change.nickname {
 					if change.change {
-						users[j].modes += mode
+						users[j].addMode(mode)
 					} else {
-						users[j].modes = strings.Replace(users[j].modes, mode, "", -1)
+						users[j].removeMode(mode)
 					}
 				}
 			}
@@ -136,6 +135,9 @@ func HandleChannelModes(
 				handleUserPrivilegeMode(op, channel, message)
 			case 'A', 'B', 'C', 'D':
 				channel.SetChannelMode(op.modeType, op.mode, op.parameter, op.change)
+				if op.modeType == 'A' {
+					channel.setListEntry(op.mode, &ListEntry{Mask: op.parameter, SetBy: message.Source, SetAt: messageTime(message)}, op.change)
+				}
 
 				var modeStr string
 				if op.change {
@@ -999,3 +999,28 @@ func TestHandleChannelModes(t *testing.T) {
 		})
 	}
 }
+
+func TestHandleChannelModes_UpdatesLists(t *testing.T) {
+	channel := &Channel{Window: &Window{name: "#test"}, channelModes: make([]*ChannelMode, 0)}
+	channel.addPendingListEntry("b", &ListEntry{Mask: "*!*@old.example.com"})
+	channel.endList("b")
+	handler := HandleChannelModes(
+		"15:04:05",
+		func(string) bool { return true },
+		func() {},
+		func(string) (*Channel, error) { return channel, nil },
+		func(string) string { return "@" },
+		func(mode string) rune {
+			if mode == "o" {
+				return 'P'
+			}
+			return 'A'
+		},
+	)
+	handler(ircmsg.Message{Source: "op!op@example.com", Command: "MODE", Params: []string{"#test", "+b-b", "*!*@new.example.com", "*!*@old.example.com"}})
+	entries, _ := channel.GetList("b")
+	if assert.Len(t, entries, 1) {
+		assert.Equal(t, "*!*@new.example.com", entries[0].Mask)
+		assert.Equal(t, "op!op@example.com", entries[0].SetBy)
+	}
+}
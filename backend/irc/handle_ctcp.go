@@ -59,6 +59,8 @@ func HandleCTCPQuery(
 	setPendingUpdate func(),
 	sendRaw func(string),
 	addMessage func(*Message),
+	isValidChannel func(string) bool,
+	isIgnored func(ircmsg.Message, string, IgnoreType) bool,
 ) func(ircmsg.Message) {
 	return func(message ircmsg.Message) {
 		defer setPendingUpdate()
@@ -66,6 +68,9 @@ func HandleCTCPQuery(
 		if !is {
 			return
 		}
+		if isIgnored(message, channelTarget(isValidChannel, message.Params[0]), IgnoreCTCP) {
+			return
+		}
 		if ctcp.Command == "VERSION" {
 			sendRaw(fmt.Sprintf("NOTICE %s :%s", message.Nick(),
 				FormatCTCPReply("VERSION", "Tithon")))
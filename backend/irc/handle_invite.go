@@ -0,0 +1,31 @@
+package irc
+
+import (
+	"fmt"
+	"github.com/ergochat/irc-go/ircmsg"
+	"log/slog"
+	"strings"
+)
+
+func HandleInvite(
+	timestampFormat string,
+	setPendingUpdate func(),
+	currentNick func() string,
+	addMessage func(*Message),
+	isIgnored func(ircmsg.Message, string, IgnoreType) bool,
+) func(ircmsg.Message) {
+	return func(message ircmsg.Message) {
+		if len(message.Params) < 2 {
+			slog.Debug("Invalid invite message", "message", message)
+			return
+		}
+		if !strings.EqualFold(message.Params[0], currentNick()) {
+			return
+		}
+		if isIgnored(message, message.Params[1], IgnoreInvites) {
+			return
+		}
+		defer setPendingUpdate()
+		addMessage(NewEvent(EventJoin, timestampFormat, false, fmt.Sprintf("%s has invited you to %s", message.Source, message.Params[1])))
+	}
+}
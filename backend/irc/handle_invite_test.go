@@ -0,0 +1,79 @@
+package irc
+
+import (
+	"testing"
+
+	"github.com/ergochat/irc-go/ircmsg"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHandleInvite(t *testing.T) {
+	tests := []struct {
+		name        string
+		message     ircmsg.Message
+		ignored     bool
+		wantMessage string
+	}{
+		{
+			name: "Invite to current user",
+			message: ircmsg.Message{
+				Source:  "friend!user@example.com",
+				Command: "INVITE",
+				Params:  []string{"TestNick", "#test"},
+			},
+			wantMessage: "friend!user@example.com has invited you to #test",
+		},
+		{
+			name: "Invite for someone else",
+			message: ircmsg.Message{
+				Source:  "friend!user@example.com",
+				Command: "INVITE",
+				Params:  []string{"other", "#test"},
+			},
+		},
+		{
+			name: "Ignored invite",
+			message: ircmsg.Message{
+				Source:  "spammer!user@example.com",
+				Command: "INVITE",
+				Params:  []string{"testnick", "#spam"},
+			},
+			ignored: true,
+		},
+		{
+			name: "Missing channel",
+			message: ircmsg.Message{
+				Source:  "friend!user@example.com",
+				Command: "INVITE",
+				Params:  []string{"testnick"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var messages []*Message
+			var ignoreType IgnoreType
+			handler := HandleInvite(
+				"15:04:05",
+				func() {},
+				func() string { return "testnick" },
+				func(message *Message) { messages = append(messages, message) },
+				func(_ ircmsg.Message, _ string, t IgnoreType) bool {
+					ignoreType = t
+					return tt.ignored
+				},
+			)
+			handler(tt.message)
+			if tt.ignored {
+				assert.Equal(t, IgnoreInvites, ignoreType)
+			}
+			if tt.wantMessage == "" {
+				assert.Empty(t, messages)
+				return
+			}
+			if assert.Len(t, messages, 1) {
+				assert.Equal(t, tt.wantMessage, messages[0].GetMessage())
+			}
+		})
+	}
+}
@@ -32,7 +32,7 @@ func HandleKick(
 			return
 		}
 		channel.users = slices.DeleteFunc(channel.users, func(user *User) bool {
-			return user.nickname == message.Params[1]
+			return user.GetNickname() == message.Params[1]
 		})
 		channel.AddMessage(NewEvent(EventKick, timestampFormat, message.Nick() == currentNick(), message.Source+" has kicked "+message.Params[1]+" from "+channel.GetName()+kickMessage))
 	}
@@ -0,0 +1,49 @@
+package irc
+
+import (
+	"github.com/ergochat/irc-go/ircfmt"
+	"github.com/ergochat/irc-go/ircmsg"
+	"log/slog"
+	"strconv"
+	"strings"
+)
+
+// channelListUpdateInterval is the number of channels received before the UI is updated, so large lists don't cause
+// an update for every channel
+const channelListUpdateInterval = 250
+
+// HandleListEntry stores a channel sent in reply to LIST
+func HandleListEntry(
+	setPendingUpdate func(),
+	getChannelList func() *ChannelList,
+) func(message ircmsg.Message) {
+	return func(message ircmsg.Message) {
+		if len(message.Params) < 3 {
+			slog.Debug("Invalid list entry", "message", message)
+			return
+		}
+		users, err := strconv.Atoi(message.Params[2])
+		if err != nil {
+			slog.Debug("Invalid user count in list entry", "users", message.Params[2])
+			return
+		}
+		entry := ChannelListEntry{Name: message.Params[1], Users: users}
+		if len(message.Params) > 3 {
+			entry.Topic = strings.TrimSpace(ircfmt.Strip(message.Params[3]))
+		}
+		if getChannelList().add(entry)%channelListUpdateInterval == 0 {
+			setPendingUpdate()
+		}
+	}
+}
+
+// HandleListEnd marks the channel list as complete
+func HandleListEnd(
+	setPendingUpdate func(),
+	getChannelList func() *ChannelList,
+) func(message ircmsg.Message) {
+	return func(message ircmsg.Message) {
+		defer setPendingUpdate()
+		getChannelList().end()
+	}
+}
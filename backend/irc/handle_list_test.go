@@ -0,0 +1,33 @@
+package irc
+
+import (
+	"testing"
+
+	"github.com/ergochat/irc-go/ircmsg"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHandleList(t *testing.T) {
+	list := &ChannelList{}
+	list.start(ChannelListFilter{})
+	getChannelList := func() *ChannelList { return list }
+	updates := 0
+	setPendingUpdate := func() { updates++ }
+	entry := HandleListEntry(setPendingUpdate, getChannelList)
+
+	entry(ircmsg.Message{Command: "322", Params: []string{"me", "#tithon", "12", "\x02Tithon\x02 IRC client "}})
+	entry(ircmsg.Message{Command: "322", Params: []string{"me", "#empty", "3"}})
+	entry(ircmsg.Message{Command: "322", Params: []string{"me", "#invalid", "lots", "Topic"}})
+	entry(ircmsg.Message{Command: "322", Params: []string{"me", "#short"}})
+	assert.Equal(t, 0, updates, "the UI shouldn't update for every channel")
+
+	HandleListEnd(setPendingUpdate, getChannelList)(ircmsg.Message{Command: "323", Params: []string{"me", "End of /LIST"}})
+	assert.Equal(t, 1, updates)
+	assert.False(t, list.IsLoading())
+	results, total := list.Search("", ChannelListSortName, 10)
+	assert.Equal(t, 2, total)
+	assert.Equal(t, []ChannelListEntry{
+		{Name: "#empty", Users: 3},
+		{Name: "#tithon", Users: 12, Topic: "Tithon IRC client"},
+	}, results)
+}
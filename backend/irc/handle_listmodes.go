@@ -0,0 +1,68 @@
+package irc
+
+import (
+	"github.com/ergochat/irc-go/ircmsg"
+	"log/slog"
+	"strconv"
+	"time"
+)
+
+// HandleListModeEntry handles a single entry of a ban, exception, invite or quiet list, offset is the number of extra
+// parameters before the mask, quiet lists include the mode
+func HandleListModeEntry(
+	mode string,
+	offset int,
+	getChannelByName func(string) (*Channel, error),
+) func(message ircmsg.Message) {
+	return func(message ircmsg.Message) {
+		if len(message.Params) < 3+offset {
+			slog.Debug("Invalid list mode entry", "message", message)
+			return
+		}
+		channel, err := getChannelByName(message.Params[1])
+		if err != nil {
+			slog.Debug("List mode entry for unknown channel", "channel", message.Params[1])
+			return
+		}
+		entry := &ListEntry{Mask: message.Params[2+offset]}
+		if len(message.Params) > 3+offset {
+			entry.SetBy = message.Params[3+offset]
+		}
+		if len(message.Params) > 4+offset {
+			if seconds, err := strconv.ParseInt(message.Params[4+offset], 10, 64); err == nil && seconds > 0 {
+				entry.SetAt = time.Unix(seconds, 0)
+			}
+		}
+		channel.addPendingListEntry(mode, entry)
+	}
+}
+
+// HandleListModeEnd replaces the stored list once the server has sent every entry
+func HandleListModeEnd(
+	mode string,
+	setPendingUpdate func(),
+	getChannelByName func(string) (*Channel, error),
+) func(message ircmsg.Message) {
+	return func(message ircmsg.Message) {
+		if len(message.Params) < 2 {
+			return
+		}
+		channel, err := getChannelByName(message.Params[1])
+		if err != nil {
+			slog.Debug("End of list mode for unknown channel", "channel", message.Params[1])
+			return
+		}
+		defer setPendingUpdate()
+		channel.endList(mode)
+	}
+}
+
+// messageTime returns the time the server sent a message, or now if the server didn't say
+func messageTime(message ircmsg.Message) time.Time {
+	if ok, value := message.GetTag("time"); ok {
+		if parsed, err := time.Parse(v3TimestampFormat, value); err == nil {
+			return parsed
+		}
+	}
+	return time.Now()
+}
@@ -0,0 +1,65 @@
+package irc
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ergochat/irc-go/ircmsg"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHandleListMode(t *testing.T) {
+	channel := &Channel{Window: &Window{name: "#test"}}
+	getChannelByName := func(name string) (*Channel, error) {
+		if name == "#test" {
+			return channel, nil
+		}
+		return nil, assert.AnError
+	}
+	updates := 0
+	setPendingUpdate := func() { updates++ }
+	channel.startList("b")
+	assert.True(t, channel.IsListLoading("b"))
+
+	entry := HandleListModeEntry("b", 0, getChannelByName)
+	entry(ircmsg.Message{Command: "367", Params: []string{"me", "#test", "*!*@a.example.com", "op!op@example.com", "1740823200"}})
+	entry(ircmsg.Message{Command: "367", Params: []string{"me", "#test", "*!*@b.example.com"}})
+	entry(ircmsg.Message{Command: "367", Params: []string{"me", "#other", "*!*@c.example.com"}})
+	entry(ircmsg.Message{Command: "367", Params: []string{"me", "#test"}})
+	quiet := HandleListModeEntry("q", 1, getChannelByName)
+	quiet(ircmsg.Message{Command: "728", Params: []string{"me", "#test", "q", "*!*@d.example.com", "op", "1740823200"}})
+
+	entries, loaded := channel.GetList("b")
+	assert.False(t, loaded, "the list shouldn't change until the end of the list")
+	assert.Empty(t, entries)
+
+	HandleListModeEnd("b", setPendingUpdate, getChannelByName)(ircmsg.Message{Command: "368", Params: []string{"me", "#test", "End of ban list"}})
+	HandleListModeEnd("q", setPendingUpdate, getChannelByName)(ircmsg.Message{Command: "729", Params: []string{"me", "#test", "q", "End of quiet list"}})
+	assert.Equal(t, 2, updates)
+	assert.False(t, channel.IsListLoading("b"))
+
+	entries, loaded = channel.GetList("b")
+	assert.True(t, loaded)
+	assert.Equal(t, []*ListEntry{
+		{Mask: "*!*@a.example.com", SetBy: "op!op@example.com", SetAt: time.Unix(1740823200, 0)},
+		{Mask: "*!*@b.example.com"},
+	}, entries)
+	entries, loaded = channel.GetList("q")
+	assert.True(t, loaded)
+	assert.Equal(t, []*ListEntry{{Mask: "*!*@d.example.com", SetBy: "op", SetAt: time.Unix(1740823200, 0)}}, entries)
+
+	HandleListModeEnd("e", setPendingUpdate, getChannelByName)(ircmsg.Message{Command: "349", Params: []string{"me", "#test", "End of exception list"}})
+	entries, loaded = channel.GetList("e")
+	assert.True(t, loaded)
+	require.NotNil(t, entries, "an empty list should still be loaded")
+	assert.Empty(t, entries)
+}
+
+func TestMessageTime(t *testing.T) {
+	when := messageTime(ircmsg.Message{})
+	assert.WithinDuration(t, time.Now(), when, time.Minute)
+	message := ircmsg.Message{}
+	message.SetTag("time", "2025-03-01T10:00:00.000Z")
+	assert.True(t, time.Date(2025, 3, 1, 10, 0, 0, 0, time.UTC).Equal(messageTime(message)))
+}
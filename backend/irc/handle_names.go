@@ -29,21 +29,43 @@ func HandleNamesReply(
 			if names[i] == "" {
 				continue
 			}
-			modes, nickname := stripChannelPrefixes(names[i])
+			modes, name := stripChannelPrefixes(names[i])
+			// With userhost-in-names each name is nick!user@host
+			nickname, ident, host := name, "", ""
+			if nuh, err := ircmsg.ParseNUH(name); err == nil && nuh.Host != "" {
+				nickname, ident, host = nuh.Name, nuh.User, nuh.Host
+			}
 
 			existingUsers := channel.GetUsers()
 			userExists := false
 
 			for j := range existingUsers {
-				if existingUsers[j].nickname == nickname {
-					existingUsers[j].modes = modes
+				if existingUsers[j].GetNickname() == nickname {
+					existingUsers[j].setModes(modes)
+					existingUsers[j].setUserHost(ident, host)
 					userExists = true
 					break
 				}
 			}
 			if !userExists {
-				channel.AddUser(NewUser(nickname, modes))
+				user := NewUser(nickname, modes)
+				user.setUserHost(ident, host)
+				channel.AddUser(user)
 			}
 		}
 	}
 }
+
+// HandleEndOfNames requests the users of a channel we've just joined, now NAMES has listed them all
+func HandleEndOfNames(
+	requestJoinWho func(string) error,
+) func(message ircmsg.Message) {
+	return func(message ircmsg.Message) {
+		if len(message.Params) < 2 {
+			return
+		}
+		if err := requestJoinWho(message.Params[1]); err != nil {
+			slog.Debug("Unable to request channel users", "channel", message.Params[1], "error", err)
+		}
+	}
+}
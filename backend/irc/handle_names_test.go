@@ -3,6 +3,7 @@ package irc
 import (
 	"github.com/ergochat/irc-go/ircmsg"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"testing"
 )
 
@@ -590,3 +591,36 @@ func TestHandleNamesReply(t *testing.T) {
 		})
 	}
 }
+
+func TestHandleNamesReply_UserhostInNames(t *testing.T) {
+	channel := &Channel{Window: &Window{name: "#test", users: []*User{NewUser("bob", "")}, hasUsers: true}}
+	handler := HandleNamesReply(
+		func() {},
+		func(string) (*Channel, error) { return channel, nil },
+		func() []string { return []string{"ov", "@+"} },
+	)
+	handler(ircmsg.Message{
+		Source:  "irc.example.com",
+		Command: "353",
+		Params:  []string{"testnick", "=", "#test", "@alice!~al@example.com +bob!bob@192.0.2.1 charlie"},
+	})
+	users := map[string]*User{}
+	for _, user := range channel.GetUsers() {
+		users[user.nickname] = user
+	}
+	require.Len(t, users, 3)
+	assert.Equal(t, &User{nickname: "alice", modes: "@", ident: "~al", host: "example.com"}, users["alice"])
+	assert.Equal(t, &User{nickname: "bob", modes: "+", ident: "bob", host: "192.0.2.1"}, users["bob"])
+	assert.Equal(t, &User{nickname: "charlie"}, users["charlie"])
+}
+
+func TestHandleEndOfNames(t *testing.T) {
+	var requested []string
+	handler := HandleEndOfNames(func(channel string) error {
+		requested = append(requested, channel)
+		return nil
+	})
+	handler(ircmsg.Message{Command: "366", Params: []string{"me", "#test", "End of /NAMES list."}})
+	handler(ircmsg.Message{Command: "366", Params: []string{"me"}})
+	assert.Equal(t, []string{"#test"}, requested)
+}
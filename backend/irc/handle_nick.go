@@ -20,9 +20,9 @@ func HandleNick(
 		for i := range channels {
 			users := channels[i].GetUsers()
 			for j := range users {
-				if users[j].nickname == message.Nick() {
+				if users[j].GetNickname() == message.Nick() {
 					channels[i].AddMessage(NewEvent(EventNick, timestampFormat, false, message.Nick()+" is now known as "+message.Params[0]))
-					users[j].nickname = message.Params[0]
+					users[j].setNickname(message.Params[0])
 				}
 			}
 		}
@@ -15,13 +15,23 @@ func HandleNotice(
 	getChannelByName func(string) (*Channel, error),
 	getQueryByName func(string) (*Query, error),
 	addQuery func(string) *Query,
+	isIgnored func(ircmsg.Message, string, IgnoreType) bool,
+	getHighlighter func(string) Highlighter,
 ) func(ircmsg.Message) {
 	return func(message ircmsg.Message) {
 		if isCTCP(strings.Join(message.Params[1:], " ")) {
 			return
 		}
+		channel := channelTarget(isValidChannel, message.Params[0])
+		if message.Nick() != currentNick() && isIgnored(message, channel, IgnoreNotices) {
+			return
+		}
 		defer setPendingUpdate()
-		mess := NewNotice(timestampFormat, message.Nick() == currentNick(), message.Nick(), strings.Join(message.Params[1:], " "), nil, currentNick())
+		window := channel
+		if window == "" {
+			window = message.Nick()
+		}
+		mess := NewNotice(timestampFormat, message.Nick() == currentNick(), message.Nick(), strings.Join(message.Params[1:], " "), nil, getHighlighter(window))
 		if message.Source == "" || (strings.Contains(message.Source, ".") && !strings.Contains(message.Source, "@")) {
 			addMessage(mess)
 		} else if isValidChannel(message.Params[0]) {
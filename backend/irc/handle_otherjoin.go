@@ -10,6 +10,8 @@ func HandleOtherJoin(
 	setPendingUpdate func(),
 	currentNick func() string,
 	getChannelByName func(string) (*Channel, error),
+	isIgnored func(ircmsg.Message, string, IgnoreType) bool,
+	splitJoin func(string, string) (string, *EventGroup, bool),
 ) func(message ircmsg.Message) {
 	return func(message ircmsg.Message) {
 		defer setPendingUpdate()
@@ -25,7 +27,20 @@ func HandleOtherJoin(
 			slog.Error("Error getting channel for join", "message", message)
 			return
 		}
-		channel.AddUser(NewUser(message.Nick(), ""))
-		channel.AddMessage(NewEvent(EventJoin, timestampFormat, false, message.Source+" has joined "+channel.GetName()))
+		user := NewUser(message.Nick(), "")
+		if nuh, err := message.NUH(); err == nil {
+			user.setUserHost(nuh.User, nuh.Host)
+		}
+		channel.AddUser(user)
+		if servers, group, isNew := splitJoin(channel.GetName(), message.Nick()); group != nil {
+			if isNew {
+				channel.AddMessage(NewGroupEvent(EventJoin, timestampFormat, "Netjoin "+netsplitDescription(servers), group))
+			}
+			return
+		}
+		if isIgnored(message, channel.GetName(), IgnoreJoins) {
+			return
+		}
+		channel.AddMessage(NewMembershipEvent(EventJoin, timestampFormat, message.Nick(), message.Source+" has joined "+channel.GetName()))
 	}
 }
@@ -161,7 +161,7 @@ func TestHandleOtherJoin(t *testing.T) {
 				return channel, nil
 			}
 
-			handler := HandleOtherJoin(tt.args.timestampFormat, setPendingUpdate, tt.args.currentNick, getChannelByName)
+			handler := HandleOtherJoin(tt.args.timestampFormat, setPendingUpdate, tt.args.currentNick, getChannelByName, notIgnored, (&netsplits{}).join)
 			handler(tt.message)
 
 			assert.True(t, pendingUpdateCalled, "setPendingUpdate should have been called")
@@ -188,6 +188,9 @@ func TestHandleOtherJoin(t *testing.T) {
 				assert.NotNil(t, foundUser, "The expected user should have been added to channel")
 				assert.Equal(t, tt.wantUserAdded, foundUser.GetNickListDisplay(), "Added user nickname should match")
 				assert.Empty(t, foundUser.GetNickListModes(), "User should have no modes initially")
+				nuh, _ := tt.message.NUH()
+				assert.Equal(t, nuh.User, foundUser.ident, "User ident should be taken from the source")
+				assert.Equal(t, nuh.Host, foundUser.host, "User host should be taken from the source")
 			}
 
 			messages := channel.GetMessages()
@@ -198,3 +201,23 @@ func TestHandleOtherJoin(t *testing.T) {
 		})
 	}
 }
+
+func TestHandleOtherJoin_Netjoin(t *testing.T) {
+	channel := &Channel{Window: &Window{name: "#test", hasUsers: true}}
+	splits := &netsplits{}
+	splits.quit("#test", "hub.example.net leaf.example.net", "user1")
+	splits.quit("#test", "hub.example.net leaf.example.net", "user2")
+	handler := HandleOtherJoin("15:04:05", func() {}, func() string { return "me" },
+		func(string) (*Channel, error) { return channel, nil }, notIgnored, splits.join)
+	for _, nick := range []string{"user1", "user2", "user3"} {
+		handler(ircmsg.Message{Source: nick + "!user@example.com", Command: "JOIN", Params: []string{"#test"}})
+	}
+
+	messages := channel.GetMessages()
+	assert.Len(t, messages, 2, "Rejoins after a netsplit should be collapsed into one message")
+	assert.Equal(t, "Netjoin hub.example.net ↔ leaf.example.net", messages[0].GetMessage())
+	assert.Equal(t, []string{"user1", "user2"}, messages[0].GetGroup().GetNicknames())
+	assert.Equal(t, "user3!user@example.com has joined #test", messages[1].GetMessage())
+	assert.Nil(t, messages[1].GetGroup())
+	assert.Len(t, channel.GetUsers(), 3)
+}
@@ -12,6 +12,7 @@ func HandlePart(
 	currentNick func() string,
 	getChannelByName func(string) (*Channel, error),
 	removeChannel func(string),
+	isIgnored func(ircmsg.Message, string, IgnoreType) bool,
 ) func(message ircmsg.Message) {
 	return func(message ircmsg.Message) {
 		defer setPendingUpdate()
@@ -25,8 +26,11 @@ func HandlePart(
 			return
 		}
 		channel.users = slices.DeleteFunc(channel.users, func(user *User) bool {
-			return user.nickname == message.Nick()
+			return user.GetNickname() == message.Nick()
 		})
-		channel.AddMessage(NewEvent(EventJoin, timestampFormat, false, message.Source+" has parted "+channel.GetName()))
+		if isIgnored(message, channel.GetName(), IgnoreJoins) {
+			return
+		}
+		channel.AddMessage(NewMembershipEvent(EventPart, timestampFormat, message.Nick(), message.Source+" has parted "+channel.GetName()))
 	}
 }
@@ -206,6 +206,7 @@ func TestHandlePart(t *testing.T) {
 				currentNick,
 				getChannelByName,
 				removeChannel,
+				notIgnored,
 			)
 
 			handler(tt.message)
@@ -14,24 +14,29 @@ func HandlePrivMsg(
 	currentNick func() string,
 	getServerName func() string,
 	getServerID func() string,
-	checkAndNotify func(string, string, string, string, string) bool,
+	checkAndNotify func(string, string, string, string, string, bool) bool,
 	getQueryByName func(string) (*Query, error),
 	addQuery func(string) *Query,
+	isIgnored func(ircmsg.Message, string, IgnoreType) bool,
+	getHighlighter func(string) Highlighter,
 ) func(message ircmsg.Message) {
 	return func(message ircmsg.Message) {
 		if isCTCP(strings.Join(message.Params[1:], " ")) {
 			return
 		}
 		defer setPendingUpdate()
+		if message.Nick() != currentNick() && isIgnored(message, channelTarget(isValidChannel, message.Params[0]), IgnoreMessages) {
+			return
+		}
 		if isValidChannel(message.Params[0]) {
 			channel, err := getChannelByName(message.Params[0])
 			if err != nil {
 				slog.Warn("Message for unknown channel", "message", message)
 				return
 			}
-			msg := NewMessage(timestampFormat, message.Nick() == currentNick(), message.Nick(), strings.Join(message.Params[1:], " "), message.AllTags(), currentNick())
+			msg := NewMessage(timestampFormat, message.Nick() == currentNick(), message.Nick(), strings.Join(message.Params[1:], " "), message.AllTags(), getHighlighter(channel.GetName()))
 			if msg.tags["chathistory"] != "true" && !msg.IsMe() {
-				checkAndNotify(getServerName(), getServerID(), channel.GetName(), msg.GetNickname(), msg.GetPlainDisplayMessage())
+				checkAndNotify(getServerName(), getServerID(), channel.GetName(), msg.GetNickname(), msg.GetPlainDisplayMessage(), isHighlightType(msg.GetType()))
 			}
 			channel.AddMessage(msg)
 		} else if strings.EqualFold(message.Params[0], currentNick()) {
@@ -40,9 +45,9 @@ func HandlePrivMsg(
 				pm = addQuery(message.Nick())
 			}
 
-			msg := NewMessage(timestampFormat, message.Nick() == currentNick(), message.Nick(), strings.Join(message.Params[1:], " "), message.AllTags(), currentNick())
+			msg := NewMessage(timestampFormat, message.Nick() == currentNick(), message.Nick(), strings.Join(message.Params[1:], " "), message.AllTags(), getHighlighter(pm.GetName()))
 			if msg.tags["chathistory"] != "true" && !msg.IsMe() {
-				checkAndNotify(getServerName(), getServerID(), pm.GetName(), msg.GetNickname(), msg.GetPlainDisplayMessage())
+				checkAndNotify(getServerName(), getServerID(), pm.GetName(), msg.GetNickname(), msg.GetPlainDisplayMessage(), isHighlightType(msg.GetType()))
 			}
 			pm.AddMessage(msg)
 		} else if message.Nick() == currentNick() {
@@ -50,7 +55,7 @@ func HandlePrivMsg(
 			if err != nil {
 				pm = addQuery(message.Nick())
 			}
-			msg := NewMessage(timestampFormat, message.Nick() == currentNick(), message.Nick(), strings.Join(message.Params[1:], " "), message.AllTags(), currentNick())
+			msg := NewMessage(timestampFormat, message.Nick() == currentNick(), message.Nick(), strings.Join(message.Params[1:], " "), message.AllTags(), nil)
 			pm.AddMessage(msg)
 		} else {
 			slog.Warn("Unsupported message target", "message", message)
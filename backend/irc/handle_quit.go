@@ -10,25 +10,36 @@ func HandleQuit(
 	timestampFormat string,
 	setPendingUpdate func(),
 	getChannels func() []*Channel,
+	splitQuit func(string, string, string) (*EventGroup, bool),
 ) func(ircmsg.Message) {
 	return func(message ircmsg.Message) {
 		defer setPendingUpdate()
+		reason := strings.TrimSpace(strings.Join(message.Params, " "))
+		netsplit := isNetsplit(reason)
 		channels := getChannels()
 		for i := range channels {
 			changed := false
 			users := channels[i].GetUsers()
 			users = slices.DeleteFunc(users, func(user *User) bool {
-				if user.nickname == message.Nick() {
+				if user.GetNickname() == message.Nick() {
 					changed = true
 					return true
 				}
 				return false
 			})
-			if changed {
-				channels[i].SetUsers(users)
-				nuh, _ := message.NUH()
-				channels[i].AddMessage(NewEvent(EventNick, timestampFormat, false, nuh.Canonical()+" has quit "+strings.Join(message.Params[1:], " ")))
+			if !changed {
+				continue
 			}
+			channels[i].SetUsers(users)
+			if netsplit {
+				// Only the first user to quit a channel in a netsplit adds a message, the rest are added to its group
+				if group, isNew := splitQuit(channels[i].GetName(), reason, message.Nick()); isNew {
+					channels[i].AddMessage(NewGroupEvent(EventQuit, timestampFormat, "Netsplit "+netsplitDescription(reason), group))
+				}
+				continue
+			}
+			nuh, _ := message.NUH()
+			channels[i].AddMessage(NewMembershipEvent(EventQuit, timestampFormat, message.Nick(), nuh.Canonical()+" has quit "+reason))
 		}
 	}
 }
@@ -194,7 +194,7 @@ func TestHandleQuit(t *testing.T) {
 				return channels
 			}
 
-			handler := HandleQuit(tt.args.timestampFormat, setPendingUpdate, getChannels)
+			handler := HandleQuit(tt.args.timestampFormat, setPendingUpdate, getChannels, (&netsplits{}).quit)
 			handler(tt.message)
 
 			assert.True(t, pendingUpdateCalled, "setPendingUpdate should have been called")
@@ -249,3 +249,23 @@ func TestHandleQuit(t *testing.T) {
 		})
 	}
 }
+
+func TestHandleQuit_Netsplit(t *testing.T) {
+	channels := []*Channel{
+		{Window: &Window{name: "#test1", hasUsers: true, users: []*User{NewUser("user1", ""), NewUser("user2", ""), NewUser("user3", "")}}},
+		{Window: &Window{name: "#test2", hasUsers: true, users: []*User{NewUser("user2", "")}}},
+	}
+	splits := &netsplits{}
+	handler := HandleQuit("15:04:05", func() {}, func() []*Channel { return channels }, splits.quit)
+	for _, nick := range []string{"user1", "user2"} {
+		handler(ircmsg.Message{Source: nick + "!user@example.com", Command: "QUIT", Params: []string{"hub.example.net leaf.example.net"}})
+	}
+
+	messages := channels[0].GetMessages()
+	assert.Len(t, messages, 1, "Quits in a netsplit should be collapsed into one message")
+	assert.Equal(t, "Netsplit hub.example.net ↔ leaf.example.net", messages[0].GetMessage())
+	assert.Equal(t, []string{"user1", "user2"}, messages[0].GetGroup().GetNicknames())
+	assert.Len(t, channels[0].GetUsers(), 1)
+	assert.Len(t, channels[1].GetMessages(), 1)
+	assert.Equal(t, []string{"user2"}, channels[1].GetMessages()[0].GetGroup().GetNicknames())
+}
@@ -31,7 +31,7 @@ func HandleRPLTopicWhoTime(
 			slog.Debug("Received topic for unknown channel")
 			return
 		}
-		existingTopic := channel.GetTopic().GetTopic()
+		existingTopic := channel.GetTopic().GetText()
 		updatedTopic := NewTopic(existingTopic, setBy, setTime)
 		channel.SetTopic(updatedTopic)
 		channel.SetTitle(updatedTopic.GetDisplayTopic())
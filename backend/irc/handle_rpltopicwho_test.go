@@ -202,3 +202,14 @@ func TestHandleRPLTopicWhoTime(t *testing.T) {
 		})
 	}
 }
+
+func TestHandleRPLTopicWhoTime_NoTopic(t *testing.T) {
+	channel := NewChannel(nil, "#test")
+	assert.Equal(t, "No Topic set", channel.GetTitle(), "new channels should show the same title as an unset topic")
+	handler := HandleRPLTopicWhoTime(func() {}, func() string { return "irc.example.com" }, func(string) (*Channel, error) {
+		return channel, nil
+	})
+	handler(ircmsg.Message{Command: "333", Params: []string{"nick", "#test", "moderator", "1609459200"}})
+	assert.Empty(t, channel.GetTopic().GetText(), "the placeholder shouldn't become the topic")
+	assert.Equal(t, "No Topic set", channel.GetTitle())
+}
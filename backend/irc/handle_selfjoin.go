@@ -14,6 +14,7 @@ func HandleSelfJoin(
 	addChannel func(string) *Channel,
 	hasCapability func(string) bool,
 	sendRaw func(string),
+	requestChannelState func(string) error,
 ) func(message ircmsg.Message) {
 	return func(message ircmsg.Message) {
 		defer setPendingUpdate()
@@ -28,6 +29,9 @@ func HandleSelfJoin(
 		channel, err := getChannelByName(message.Params[0])
 		if err != nil {
 			channel = addChannel(message.Params[0])
+			if err = requestChannelState(message.Params[0]); err != nil {
+				slog.Debug("Unable to request channel state", "channel", message.Params[0], "error", err)
+			}
 			if hasCapability("draft/chathistory") {
 				sendRaw(fmt.Sprintf("CHATHISTORY LATEST %s * 100", message.Params[0]))
 			}
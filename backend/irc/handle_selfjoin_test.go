@@ -203,6 +203,7 @@ func TestHandleSelfJoin(t *testing.T) {
 			var channel *Channel
 			var channelCreated bool
 			var chathistoryCommandSent string
+			var stateRequested string
 
 			setPendingUpdate := func() {
 				pendingUpdateCalled = true
@@ -230,8 +231,12 @@ func TestHandleSelfJoin(t *testing.T) {
 			sendRaw := func(command string) {
 				chathistoryCommandSent = command
 			}
+			requestChannelState := func(channel string) error {
+				stateRequested = channel
+				return nil
+			}
 
-			handler := HandleSelfJoin(tt.args.timestampFormat, setPendingUpdate, tt.args.currentNick, getChannelByName, addChannel, tt.args.hasCapability, sendRaw)
+			handler := HandleSelfJoin(tt.args.timestampFormat, setPendingUpdate, tt.args.currentNick, getChannelByName, addChannel, tt.args.hasCapability, sendRaw, requestChannelState)
 			handler(tt.message)
 
 			assert.True(t, pendingUpdateCalled, "setPendingUpdate should have been called")
@@ -239,13 +244,16 @@ func TestHandleSelfJoin(t *testing.T) {
 			if tt.wantOtherNick || tt.wantNoParams {
 				assert.False(t, channelCreated, "No channel should have been created")
 				assert.Empty(t, chathistoryCommandSent, "No chathistory command should have been sent")
+				assert.Empty(t, stateRequested, "Channel state shouldn't have been requested")
 				return
 			}
 
 			if tt.wantCreateChannel {
 				assert.True(t, channelCreated, "Channel should have been created")
+				assert.Equal(t, tt.wantChannelName, stateRequested, "Channel state should have been requested")
 			} else {
 				assert.False(t, channelCreated, "Channel should not have been created")
+				assert.Empty(t, stateRequested, "Channel state shouldn't have been requested")
 			}
 
 			assert.NotNil(t, channel, "Channel should exist")
@@ -0,0 +1,157 @@
+package irc
+
+import (
+	"fmt"
+	"github.com/ergochat/irc-go/ircevent"
+	"github.com/ergochat/irc-go/ircmsg"
+	"log/slog"
+	"strings"
+)
+
+// whoReply is a single user from a WHO or WHOX reply, account is only set by WHOX
+type whoReply struct {
+	channel    string
+	ident      string
+	host       string
+	nickname   string
+	flags      string
+	account    string
+	hasAccount bool
+	realname   string
+}
+
+// parseWhoReply reads a RPL_WHOREPLY or one of our RPL_WHOSPCRPL replies, replies to WHOX requests made by something
+// else are ignored
+func parseWhoReply(message ircmsg.Message) (whoReply, bool) {
+	switch message.Command {
+	case ircevent.RPL_WHOREPLY:
+		if len(message.Params) < 7 {
+			return whoReply{}, false
+		}
+		reply := whoReply{
+			channel:  message.Params[1],
+			ident:    message.Params[2],
+			host:     message.Params[3],
+			nickname: message.Params[5],
+			flags:    message.Params[6],
+		}
+		if len(message.Params) > 7 {
+			// The last parameter is the hop count followed by the realname
+			_, reply.realname, _ = strings.Cut(message.Params[7], " ")
+		}
+		return reply, true
+	case ircevent.RPL_WHOSPCRPL:
+		if len(message.Params) < 9 || message.Params[1] != whoxToken {
+			return whoReply{}, false
+		}
+		reply := whoReply{
+			channel:    message.Params[2],
+			ident:      message.Params[3],
+			host:       message.Params[4],
+			nickname:   message.Params[5],
+			flags:      message.Params[6],
+			account:    message.Params[7],
+			hasAccount: true,
+			realname:   message.Params[8],
+		}
+		if reply.account == "0" {
+			reply.account = ""
+		}
+		return reply, true
+	}
+	return whoReply{}, false
+}
+
+// String formats the reply to show to the user
+func (r whoReply) String() string {
+	text := fmt.Sprintf("WHO %s %s!%s@%s %s", r.channel, r.nickname, r.ident, r.host, r.flags)
+	if r.account != "" {
+		text += " logged in as " + r.account
+	}
+	if r.realname != "" {
+		text += ": " + r.realname
+	}
+	return text
+}
+
+// HandleWhoReply updates the user in every channel we share with them, and shows the reply if the user asked for it.
+// The update is sent at the end of the WHO rather than for each reply.
+func HandleWhoReply(
+	timestampFormat string,
+	findUsers func(string) []*User,
+	currentWho func() whoRequest,
+	addMessage func(*Message),
+) func(message ircmsg.Message) {
+	return func(message ircmsg.Message) {
+		reply, ok := parseWhoReply(message)
+		if !ok {
+			slog.Debug("Ignoring WHO reply", "message", message)
+			return
+		}
+		for _, user := range findUsers(reply.nickname) {
+			user.setWhoReply(reply)
+		}
+		if currentWho().show {
+			addMessage(NewEvent(EventWho, timestampFormat, false, reply.String()))
+		}
+	}
+}
+
+// HandleEndOfWho finishes the current WHO request, updating the nicklists and letting the user know if they asked for
+// it
+func HandleEndOfWho(
+	timestampFormat string,
+	setPendingUpdate func(),
+	endWho func(string) whoRequest,
+	addMessage func(*Message),
+) func(message ircmsg.Message) {
+	return func(message ircmsg.Message) {
+		if len(message.Params) < 2 {
+			return
+		}
+		defer setPendingUpdate()
+		if endWho(message.Params[1]).show {
+			addMessage(NewEvent(EventWho, timestampFormat, false, "End of WHO "+message.Params[1]))
+		}
+	}
+}
+
+// HandleWhowas shows the replies to a WHOWAS
+func HandleWhowas(
+	timestampFormat string,
+	setPendingUpdate func(),
+	addMessage func(*Message),
+) func(message ircmsg.Message) {
+	return func(message ircmsg.Message) {
+		if len(message.Params) < 2 {
+			return
+		}
+		defer setPendingUpdate()
+		switch message.Command {
+		case ircevent.RPL_WHOWASUSER:
+			if len(message.Params) < 6 {
+				return
+			}
+			addMessage(NewEvent(EventWho, timestampFormat, false,
+				fmt.Sprintf("WHOWAS %s was %s@%s: %s", message.Params[1], message.Params[2], message.Params[3], message.Params[5])))
+		case ircevent.RPL_ENDOFWHOWAS:
+			addMessage(NewEvent(EventWho, timestampFormat, false, "End of WHOWAS "+message.Params[1]))
+		case ircevent.ERR_WASNOSUCHNICK:
+			addMessage(NewEvent(EventWho, timestampFormat, false, "WHOWAS: There was no such nickname "+message.Params[1]))
+		}
+	}
+}
+
+// HandleAway keeps away status up to date for servers with away-notify
+func HandleAway(
+	setPendingUpdate func(),
+	getChannels func() []*Channel,
+) func(message ircmsg.Message) {
+	return func(message ircmsg.Message) {
+		defer setPendingUpdate()
+		away := len(message.Params) > 0 && message.Params[0] != ""
+		updateUsers(getChannels(), message.Nick(), func(user *User) {
+			user.setAway(away)
+		})
+	}
+}
@@ -0,0 +1,114 @@
+package irc
+
+import (
+	"testing"
+
+	"github.com/ergochat/irc-go/ircmsg"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHandleWhoReply(t *testing.T) {
+	alice := &User{nickname: "alice", account: "old"}
+	aliceElsewhere := &User{nickname: "Alice"}
+	bob := &User{nickname: "bob"}
+	channels := []*Channel{
+		{Window: &Window{name: "#one", hasUsers: true, users: []*User{alice, bob}}},
+		{Window: &Window{name: "#two", hasUsers: true, users: []*User{aliceElsewhere}}},
+	}
+	tests := []struct {
+		name        string
+		message     ircmsg.Message
+		show        bool
+		wantUser    *User
+		wantMessage string
+	}{
+		{
+			name:     "WHO",
+			message:  ircmsg.Message{Command: "352", Params: []string{"me", "#one", "~al", "example.com", "irc.example.com", "alice", "G@", "0 Alice Smith"}},
+			wantUser: &User{nickname: "alice", ident: "~al", host: "example.com", account: "old", realname: "Alice Smith", away: true},
+		},
+		{
+			name:        "WHOX",
+			message:     ircmsg.Message{Command: "354", Params: []string{"me", whoxToken, "#one", "~al", "example.com", "alice", "H", "alice", "Alice Smith"}},
+			show:        true,
+			wantUser:    &User{nickname: "alice", ident: "~al", host: "example.com", account: "alice", realname: "Alice Smith"},
+			wantMessage: "WHO #one alice!~al@example.com H logged in as alice: Alice Smith",
+		},
+		{
+			name:     "WHOX not logged in",
+			message:  ircmsg.Message{Command: "354", Params: []string{"me", whoxToken, "#one", "~al", "example.com", "alice", "H", "0", "Alice Smith"}},
+			wantUser: &User{nickname: "alice", ident: "~al", host: "example.com", realname: "Alice Smith"},
+		},
+		{
+			name:     "Someone else's WHOX",
+			message:  ircmsg.Message{Command: "354", Params: []string{"me", "1", "#one", "~al", "example.com", "alice", "G", "0", "Alice Smith"}},
+			show:     true,
+			wantUser: &User{nickname: "alice", account: "old"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			*alice = User{nickname: "alice", account: "old"}
+			*aliceElsewhere = User{nickname: "Alice", account: "old"}
+			var messages []*Message
+			queue := &whoRequests{}
+			queue.add(whoRequest{mask: "#one", show: tt.show})
+			handler := HandleWhoReply(
+				"15:04",
+				func(nickname string) []*User { return queue.findUsers(nickname, func() []*Channel { return channels }) },
+				queue.current,
+				func(message *Message) { messages = append(messages, message) },
+			)
+			handler(tt.message)
+			assert.Equal(t, tt.wantUser, alice)
+			assert.Equal(t, tt.wantUser.host, aliceElsewhere.host, "users should be updated in every channel")
+			assert.Equal(t, &User{nickname: "bob"}, bob)
+			if tt.wantMessage == "" {
+				assert.Empty(t, messages)
+				return
+			}
+			require.Len(t, messages, 1)
+			assert.Equal(t, tt.wantMessage, messages[0].GetMessage())
+		})
+	}
+}
+
+func TestHandleEndOfWho(t *testing.T) {
+	queue := &whoRequests{}
+	queue.add(whoRequest{mask: "#one"})
+	queue.add(whoRequest{mask: "#two", show: true})
+	var messages []*Message
+	var updates int
+	handler := HandleEndOfWho("15:04", func() { updates++ }, queue.end, func(message *Message) { messages = append(messages, message) })
+	handler(ircmsg.Message{Command: "315", Params: []string{"me", "#one", "End of WHO list"}})
+	assert.Empty(t, messages)
+	assert.Equal(t, 1, updates, "nicklists should be updated at the end of every WHO")
+	handler(ircmsg.Message{Command: "315", Params: []string{"me", "#two", "End of WHO list"}})
+	require.Len(t, messages, 1)
+	assert.Equal(t, "End of WHO #two", messages[0].GetMessage())
+	assert.True(t, queue.isEmpty())
+}
+
+func TestHandleAway(t *testing.T) {
+	alice := &User{nickname: "alice"}
+	channels := []*Channel{{Window: &Window{name: "#one", hasUsers: true, users: []*User{alice}}}}
+	handler := HandleAway(func() {}, func() []*Channel { return channels })
+	handler(ircmsg.Message{Source: "alice!al@example.com", Command: "AWAY", Params: []string{"Gone to lunch"}})
+	assert.True(t, alice.IsAway())
+	handler(ircmsg.Message{Source: "alice!al@example.com", Command: "AWAY"})
+	assert.False(t, alice.IsAway())
+}
+
+func TestHandleWhowas(t *testing.T) {
+	var messages []string
+	handler := HandleWhowas("15:04", func() {}, func(message *Message) { messages = append(messages, message.GetMessage()) })
+	handler(ircmsg.Message{Command: "314", Params: []string{"me", "alice", "~al", "example.com", "*", "Alice Smith"}})
+	handler(ircmsg.Message{Command: "369", Params: []string{"me", "alice", "End of WHOWAS"}})
+	handler(ircmsg.Message{Command: "406", Params: []string{"me", "bob", "There was no such nickname"}})
+	assert.Equal(t, []string{
+		"WHOWAS alice was ~al@example.com: Alice Smith",
+		"End of WHOWAS alice",
+		"WHOWAS: There was no such nickname bob",
+	}, messages)
+}
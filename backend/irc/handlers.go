@@ -16,6 +16,7 @@ func AddCallbacks(connection *Server, updateTrigger UpdateTrigger,
 			connection.AddChannel,
 			connection.HasCapability,
 			connection.SendRaw,
+			connection.requestChannelState,
 		),
 	)
 	connection.AddCallback(
@@ -25,6 +26,8 @@ func AddCallbacks(connection *Server, updateTrigger UpdateTrigger,
 			updateTrigger.SetPendingUpdate,
 			connection.CurrentNick,
 			connection.GetChannelByName,
+			connection.IsIgnored,
+			connection.netsplits.join,
 		),
 	)
 	connection.AddCallback(
@@ -40,6 +43,8 @@ func AddCallbacks(connection *Server, updateTrigger UpdateTrigger,
 			notificationManager.CheckAndNotify,
 			connection.GetQueryByName,
 			connection.AddQuery,
+			connection.IsIgnored,
+			connection.GetHighlighter,
 		),
 	)
 	connection.AddCallback(
@@ -49,6 +54,8 @@ func AddCallbacks(connection *Server, updateTrigger UpdateTrigger,
 			updateTrigger.SetPendingUpdate,
 			connection.SendRaw,
 			connection.AddMessage,
+			connection.IsValidChannel,
+			connection.IsIgnored,
 		),
 	)
 	connection.AddCallback(
@@ -62,6 +69,8 @@ func AddCallbacks(connection *Server, updateTrigger UpdateTrigger,
 			connection.GetChannelByName,
 			connection.GetQueryByName,
 			connection.AddQuery,
+			connection.IsIgnored,
+			connection.GetHighlighter,
 		),
 	)
 	connection.AddCallback(
@@ -128,6 +137,17 @@ func AddCallbacks(connection *Server, updateTrigger UpdateTrigger,
 			connection.CurrentNick,
 			connection.GetChannelByName,
 			connection.RemoveChannel,
+			connection.IsIgnored,
+		),
+	)
+	connection.AddCallback(
+		"INVITE",
+		HandleInvite(
+			timestampFormat,
+			updateTrigger.SetPendingUpdate,
+			connection.CurrentNick,
+			connection.AddMessage,
+			connection.IsIgnored,
 		),
 	)
 	connection.AddCallback(
@@ -149,6 +169,12 @@ func AddCallbacks(connection *Server, updateTrigger UpdateTrigger,
 			connection.GetModePrefixes,
 		),
 	)
+	connection.AddCallback(
+		ircevent.RPL_ENDOFNAMES,
+		HandleEndOfNames(
+			connection.requestJoinWho,
+		),
+	)
 	connection.AddCallback(
 		ircevent.RPL_UMODEIS,
 		HandleUserModeSet(
@@ -272,12 +298,164 @@ func AddCallbacks(connection *Server, updateTrigger UpdateTrigger,
 			connection.AddMessage,
 		),
 	)
+	connection.AddCallback(
+		ircevent.RPL_CHANNELMODEIS,
+		HandleRPLChannelModeIs(
+			updateTrigger.SetPendingUpdate,
+			connection.GetChannelByName,
+			connection.GetChannelModeType,
+		),
+	)
+	connection.AddCallback(
+		ircevent.RPL_CREATIONTIME,
+		HandleRPLCreationTime(
+			updateTrigger.SetPendingUpdate,
+			connection.GetChannelByName,
+		),
+	)
+	connection.AddCallback(
+		ircevent.RPL_BANLIST,
+		HandleListModeEntry(
+			"b",
+			0,
+			connection.GetChannelByName,
+		),
+	)
+	connection.AddCallback(
+		ircevent.RPL_ENDOFBANLIST,
+		HandleListModeEnd(
+			"b",
+			updateTrigger.SetPendingUpdate,
+			connection.GetChannelByName,
+		),
+	)
+	connection.AddCallback(
+		ircevent.RPL_EXCEPTLIST,
+		HandleListModeEntry(
+			"e",
+			0,
+			connection.GetChannelByName,
+		),
+	)
+	connection.AddCallback(
+		ircevent.RPL_ENDOFEXCEPTLIST,
+		HandleListModeEnd(
+			"e",
+			updateTrigger.SetPendingUpdate,
+			connection.GetChannelByName,
+		),
+	)
+	connection.AddCallback(
+		ircevent.RPL_INVITELIST,
+		HandleListModeEntry(
+			"I",
+			0,
+			connection.GetChannelByName,
+		),
+	)
+	connection.AddCallback(
+		ircevent.RPL_ENDOFINVITELIST,
+		HandleListModeEnd(
+			"I",
+			updateTrigger.SetPendingUpdate,
+			connection.GetChannelByName,
+		),
+	)
+	connection.AddCallback(
+		rplQuietList,
+		HandleListModeEntry(
+			"q",
+			1,
+			connection.GetChannelByName,
+		),
+	)
+	connection.AddCallback(
+		rplEndOfQuietList,
+		HandleListModeEnd(
+			"q",
+			updateTrigger.SetPendingUpdate,
+			connection.GetChannelByName,
+		),
+	)
+	connection.AddCallback(
+		ircevent.RPL_LIST,
+		HandleListEntry(
+			updateTrigger.SetPendingUpdate,
+			connection.GetChannelList,
+		),
+	)
+	connection.AddCallback(
+		ircevent.RPL_LISTEND,
+		HandleListEnd(
+			updateTrigger.SetPendingUpdate,
+			connection.GetChannelList,
+		),
+	)
+	connection.AddCallback(
+		ircevent.RPL_WHOREPLY,
+		HandleWhoReply(
+			timestampFormat,
+			connection.findWhoUsers,
+			connection.whoQueue.current,
+			connection.AddMessage,
+		),
+	)
+	connection.AddCallback(
+		ircevent.RPL_WHOSPCRPL,
+		HandleWhoReply(
+			timestampFormat,
+			connection.findWhoUsers,
+			connection.whoQueue.current,
+			connection.AddMessage,
+		),
+	)
+	connection.AddCallback(
+		ircevent.RPL_ENDOFWHO,
+		HandleEndOfWho(
+			timestampFormat,
+			updateTrigger.SetPendingUpdate,
+			connection.whoQueue.end,
+			connection.AddMessage,
+		),
+	)
+	connection.AddCallback(
+		ircevent.RPL_WHOWASUSER,
+		HandleWhowas(
+			timestampFormat,
+			updateTrigger.SetPendingUpdate,
+			connection.AddMessage,
+		),
+	)
+	connection.AddCallback(
+		ircevent.RPL_ENDOFWHOWAS,
+		HandleWhowas(
+			timestampFormat,
+			updateTrigger.SetPendingUpdate,
+			connection.AddMessage,
+		),
+	)
+	connection.AddCallback(
+		ircevent.ERR_WASNOSUCHNICK,
+		HandleWhowas(
+			timestampFormat,
+			updateTrigger.SetPendingUpdate,
+			connection.AddMessage,
+		),
+	)
+	connection.AddCallback(
+		"AWAY",
+		HandleAway(
+			updateTrigger.SetPendingUpdate,
+			connection.GetChannels,
+		),
+	)
 	connection.AddCallback(
 		"QUIT",
 		HandleQuit(
 			timestampFormat,
 			updateTrigger.SetPendingUpdate,
 			connection.GetChannels,
+			connection.netsplits.quit,
 		),
 	)
 	connection.AddCallback(
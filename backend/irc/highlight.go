@@ -0,0 +1,206 @@
+package irc
+
+import (
+	"github.com/greboid/tithon/config"
+	"log/slog"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// Highlighter decides if a message from nickname should be highlighted
+type Highlighter interface {
+	IsHighlight(nickname string, message string) bool
+	// Highlights returns the start and end of each part of the message that causes a highlight
+	Highlights(nickname string, message string) [][]int
+}
+
+// HighlightWords highlights messages containing any of the words, ignoring case
+type HighlightWords []string
+
+func (w HighlightWords) IsHighlight(nickname string, message string) bool {
+	return len(w.Highlights(nickname, message)) > 0
+}
+
+func (w HighlightWords) Highlights(_ string, message string) [][]int {
+	var ranges [][]int
+	for i := range w {
+		if w[i] != "" {
+			ranges = append(ranges, matchRanges(compileHighlightWord(w[i]), true, message)...)
+		}
+	}
+	return ranges
+}
+
+// maxHighlightWords limits how many compiled highlight words are cached, the cache is emptied when it's full so
+// patterns for old nicknames don't build up
+const maxHighlightWords = 1000
+
+// highlightWords caches the pattern for each highlight word as they're checked against every message
+var highlightWords = struct {
+	sync.Mutex
+	patterns map[string]*regexp.Regexp
+}{patterns: map[string]*regexp.Regexp{}}
+
+// compileHighlightWord matches text as a whole word, ignoring case
+func compileHighlightWord(word string) *regexp.Regexp {
+	highlightWords.Lock()
+	defer highlightWords.Unlock()
+	if pattern, ok := highlightWords.patterns[word]; ok {
+		return pattern
+	}
+	if len(highlightWords.patterns) >= maxHighlightWords {
+		clear(highlightWords.patterns)
+	}
+	pattern := regexp.MustCompile(`(?i)` + wholeWord(regexp.QuoteMeta(word)))
+	highlightWords.patterns[word] = pattern
+	return pattern
+}
+
+// wholeWord wraps a pattern so it only matches when it isn't part of a larger word, the word is captured by the
+// first group.  \b isn't used as nicknames often start or end with characters such as _ or [ that aren't word
+// characters.
+func wholeWord(pattern string) string {
+	return `(?:^|[^\p{L}\p{N}_])(` + pattern + `)(?:$|[^\p{L}\p{N}_])`
+}
+
+// matchRanges returns the start and end of every match, for whole word patterns this excludes the characters either
+// side of the word
+func matchRanges(pattern *regexp.Regexp, wholeWord bool, message string) [][]int {
+	var ranges [][]int
+	for _, match := range pattern.FindAllStringSubmatchIndex(message, -1) {
+		if wholeWord {
+			ranges = append(ranges, match[2:4])
+		} else {
+			ranges = append(ranges, match[0:2])
+		}
+	}
+	return ranges
+}
+
+type highlightRule struct {
+	rule      config.HighlightRule
+	pattern   *regexp.Regexp
+	wholeWord bool
+}
+
+func newHighlightRule(rule config.HighlightRule) (highlightRule, error) {
+	if err := config.ValidateHighlightRule(rule); err != nil {
+		return highlightRule{}, err
+	}
+	var pattern string
+	switch {
+	case rule.Regex:
+		pattern = rule.Pattern
+	case rule.ExcludeNick:
+		// Nicknames are matched as a whole using * and ? wildcards
+		pattern = "^" + wildcardPattern(rule.Pattern) + "$"
+	default:
+		pattern = regexp.QuoteMeta(rule.Pattern)
+	}
+	isWholeWord := rule.WholeWord && !rule.ExcludeNick
+	if isWholeWord {
+		pattern = wholeWord(pattern)
+	}
+	if !rule.CaseSensitive {
+		pattern = "(?i)" + pattern
+	}
+	compiled, err := regexp.Compile(pattern)
+	if err != nil {
+		return highlightRule{}, err
+	}
+	return highlightRule{rule: rule, pattern: compiled, wholeWord: isWholeWord}, nil
+}
+
+func (r highlightRule) appliesTo(serverID string, channel string) bool {
+	if r.rule.Network != "" && r.rule.Network != serverID {
+		return false
+	}
+	if r.rule.Channel != "" && !strings.EqualFold(r.rule.Channel, channel) {
+		return false
+	}
+	return true
+}
+
+// HighlightRules holds the configured highlight rules for every server
+type HighlightRules struct {
+	mutex sync.RWMutex
+	rules []highlightRule
+}
+
+func NewHighlightRules(rules []config.HighlightRule) *HighlightRules {
+	hr := &HighlightRules{}
+	hr.SetRules(rules)
+	return hr
+}
+
+// SetRules replaces the current rules, invalid rules are logged and skipped
+func (hr *HighlightRules) SetRules(rules []config.HighlightRule) {
+	var compiled []highlightRule
+	for i := range rules {
+		rule, err := newHighlightRule(rules[i])
+		if err != nil {
+			slog.Error("Invalid highlight rule", "error", err)
+			continue
+		}
+		compiled = append(compiled, rule)
+	}
+	hr.mutex.Lock()
+	defer hr.mutex.Unlock()
+	hr.rules = compiled
+}
+
+// For returns a Highlighter for messages in a window, the current nickname and any extra words always highlight as a
+// whole word
+func (hr *HighlightRules) For(serverID string, channel string, currentNick string, words ...string) Highlighter {
+	return &windowHighlighter{
+		rules:       hr,
+		serverID:    serverID,
+		channel:     channel,
+		currentNick: currentNick,
+		words:       words,
+	}
+}
+
+type windowHighlighter struct {
+	rules       *HighlightRules
+	serverID    string
+	channel     string
+	currentNick string
+	words       []string
+}
+
+func (w *windowHighlighter) IsHighlight(nickname string, message string) bool {
+	return len(w.Highlights(nickname, message)) > 0
+}
+
+func (w *windowHighlighter) Highlights(nickname string, message string) [][]int {
+	var rules []highlightRule
+	if w.rules != nil {
+		w.rules.mutex.RLock()
+		rules = w.rules.rules
+		w.rules.mutex.RUnlock()
+	}
+	for i := range rules {
+		if rules[i].rule.ExcludeNick && rules[i].appliesTo(w.serverID, w.channel) && rules[i].pattern.MatchString(nickname) {
+			return nil
+		}
+	}
+	ranges := HighlightWords(append([]string{w.currentNick}, w.words...)).Highlights(nickname, message)
+	for i := range rules {
+		if !rules[i].rule.ExcludeNick && rules[i].appliesTo(w.serverID, w.channel) {
+			ranges = append(ranges, matchRanges(rules[i].pattern, rules[i].wholeWord, message)...)
+		}
+	}
+	return ranges
+}
+
+// GetHighlighter returns the Highlighter to use for messages in the given channel or query, including the window's
+// own highlight words
+func (c *Server) GetHighlighter(channel string) Highlighter {
+	return c.highlightRules.For(c.GetID(), channel, c.CurrentNick(), c.GetWindowSettings(channel).Highlights...)
+}
+
+func (c *Server) SetHighlightRules(rules *HighlightRules) {
+	c.highlightRules = rules
+}
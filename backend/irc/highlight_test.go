@@ -0,0 +1,133 @@
+package irc
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/greboid/tithon/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func noHighlights(string) Highlighter {
+	return nil
+}
+
+func TestHighlightWords_IsHighlight(t *testing.T) {
+	tests := []struct {
+		name    string
+		words   []string
+		message string
+		want    bool
+	}{
+		{name: "Whole word", words: []string{"al"}, message: "hi al", want: true},
+		{name: "Inside another word", words: []string{"al"}, message: "I also think so", want: false},
+		{name: "Followed by punctuation", words: []string{"al"}, message: "al: hello", want: true},
+		{name: "Nickname with special characters", words: []string{"[al]"}, message: "hello [al]!", want: true},
+		{name: "Nickname with underscore", words: []string{"al_"}, message: "hello al_", want: true},
+		{name: "Underscore is part of the word", words: []string{"al"}, message: "hello al_", want: false},
+		{name: "Case insensitive", words: []string{"al"}, message: "AL?", want: true},
+		{name: "Empty word", words: []string{""}, message: "hello", want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, HighlightWords(tt.words).IsHighlight("someone", tt.message))
+		})
+	}
+}
+
+func TestCompileHighlightWord_Cached(t *testing.T) {
+	first := compileHighlightWord("tithon")
+	assert.Same(t, first, compileHighlightWord("tithon"), "Words should only be compiled once")
+	assert.NotSame(t, first, compileHighlightWord("other"))
+
+	for i := range maxHighlightWords {
+		compileHighlightWord(fmt.Sprintf("word%d", i))
+	}
+	highlightWords.Lock()
+	defer highlightWords.Unlock()
+	assert.LessOrEqual(t, len(highlightWords.patterns), maxHighlightWords)
+}
+
+func TestHighlightRules_For(t *testing.T) {
+	rules := NewHighlightRules([]config.HighlightRule{
+		{Pattern: "tithon"},
+		{Pattern: "Release", WholeWord: true, CaseSensitive: true},
+		{Pattern: `ticket #\d+`, Regex: true},
+		{Pattern: "deploy", Network: "work"},
+		{Pattern: "urgent", Channel: "#ops"},
+		{Pattern: "*bot", ExcludeNick: true},
+		{Pattern: "noisy", ExcludeNick: true, Channel: "#busy"},
+		{Pattern: "[invalid", Regex: true},
+	})
+	tests := []struct {
+		name     string
+		serverID string
+		channel  string
+		nickname string
+		message  string
+		want     bool
+	}{
+		{name: "Current nickname", serverID: "home", channel: "#chat", nickname: "bob", message: "hi al", want: true},
+		{name: "Current nickname inside a word", serverID: "home", channel: "#chat", nickname: "bob", message: "also", want: false},
+		{name: "Substring rule", serverID: "home", channel: "#chat", nickname: "bob", message: "I love Tithons", want: true},
+		{name: "Whole word case sensitive rule", serverID: "home", channel: "#chat", nickname: "bob", message: "New Release out", want: true},
+		{name: "Whole word rule inside a word", serverID: "home", channel: "#chat", nickname: "bob", message: "Released", want: false},
+		{name: "Case sensitive rule wrong case", serverID: "home", channel: "#chat", nickname: "bob", message: "new release", want: false},
+		{name: "Regex rule", serverID: "home", channel: "#chat", nickname: "bob", message: "see TICKET #42", want: true},
+		{name: "Network rule on network", serverID: "work", channel: "#chat", nickname: "bob", message: "deploy now", want: true},
+		{name: "Network rule on other network", serverID: "home", channel: "#chat", nickname: "bob", message: "deploy now", want: false},
+		{name: "Channel rule in channel", serverID: "home", channel: "#OPS", nickname: "bob", message: "urgent", want: true},
+		{name: "Channel rule in other channel", serverID: "home", channel: "#chat", nickname: "bob", message: "urgent", want: false},
+		{name: "Excluded nickname", serverID: "home", channel: "#chat", nickname: "ChanBot", message: "hi al", want: false},
+		{name: "Excluded nickname in channel", serverID: "home", channel: "#busy", nickname: "noisy", message: "hi al", want: false},
+		{name: "Excluded nickname in other channel", serverID: "home", channel: "#chat", nickname: "noisy", message: "hi al", want: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, rules.For(tt.serverID, tt.channel, "al").IsHighlight(tt.nickname, tt.message))
+		})
+	}
+}
+
+func TestHighlightRules_Highlights(t *testing.T) {
+	rules := NewHighlightRules([]config.HighlightRule{
+		{Pattern: "tithon"},
+		{Pattern: "Release", WholeWord: true, CaseSensitive: true},
+		{Pattern: `ticket #\d+`, Regex: true},
+		{Pattern: "*bot", ExcludeNick: true},
+	})
+	tests := []struct {
+		name     string
+		nickname string
+		message  string
+		want     [][]int
+	}{
+		{name: "No highlight", nickname: "bob", message: "hello", want: nil},
+		{name: "Current nickname excludes the surrounding characters", nickname: "bob", message: "hi al!", want: [][]int{{3, 5}}},
+		{name: "Current nickname at the start", nickname: "bob", message: "al: hi", want: [][]int{{0, 2}}},
+		{name: "Substring rule", nickname: "bob", message: "I love Tithons", want: [][]int{{7, 13}}},
+		{name: "Whole word rule", nickname: "bob", message: "New Release out", want: [][]int{{4, 11}}},
+		{name: "Regex rule", nickname: "bob", message: "see TICKET #42", want: [][]int{{4, 14}}},
+		{name: "Several matches", nickname: "bob", message: "al: tithon", want: [][]int{{0, 2}, {4, 10}}},
+		{name: "Excluded nickname", nickname: "ChanBot", message: "hi al", want: nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, rules.For("home", "#chat", "al").Highlights(tt.nickname, tt.message))
+		})
+	}
+}
+
+func TestHighlightRules_Nil(t *testing.T) {
+	var rules *HighlightRules
+	assert.True(t, rules.For("home", "#chat", "al").IsHighlight("bob", "hi al"))
+	assert.False(t, rules.For("home", "#chat", "al").IsHighlight("bob", "hello"))
+}
+
+func TestHighlightRules_SetRules(t *testing.T) {
+	rules := NewHighlightRules(nil)
+	highlighter := rules.For("home", "#chat", "al")
+	assert.False(t, highlighter.IsHighlight("bob", "tithon"))
+	rules.SetRules([]config.HighlightRule{{Pattern: "tithon"}})
+	assert.True(t, highlighter.IsHighlight("bob", "tithon"))
+}
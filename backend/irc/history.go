@@ -0,0 +1,194 @@
+package irc
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"log/slog"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// historyLimit is the maximum number of imported messages shown when a window is opened
+const historyLimit = 1000
+
+// History stores messages imported from the logs of other clients, laid out as server/channel.jsonl using the same
+// lines as JSONL logs, so they can be shown when the matching channel or query is opened.  Servers are identified by
+// their configured ID as the network name isn't known until connected and can change.
+type History struct {
+	mutex     sync.Mutex
+	directory string
+}
+
+func NewHistory(directory string) *History {
+	return &History{directory: directory}
+}
+
+// Load returns the most recent messages stored for a channel or query on a server
+func (h *History) Load(serverID, name string) ([]jsonLogLine, error) {
+	if h == nil {
+		return nil, nil
+	}
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	lines, err := h.read(h.path(serverID, name))
+	if len(lines) > historyLimit {
+		lines = lines[len(lines)-historyLimit:]
+	}
+	return lines, err
+}
+
+// Add merges messages into the history for a channel or query, messages already stored are skipped, and returns the
+// number of messages added
+func (h *History) Add(serverID, name string, lines []jsonLogLine) (int, error) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	path := h.path(serverID, name)
+	existing, err := h.read(path)
+	if err != nil {
+		return 0, err
+	}
+	seen := map[historyKey]bool{}
+	for i := range existing {
+		seen[newHistoryKey(existing[i])] = true
+	}
+	added := 0
+	for i := range lines {
+		key := newHistoryKey(lines[i])
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		existing = append(existing, lines[i])
+		added++
+	}
+	if added == 0 {
+		return 0, nil
+	}
+	slices.SortStableFunc(existing, func(a, b jsonLogLine) int {
+		return a.Time.Compare(b.Time)
+	})
+	return added, h.write(path, existing)
+}
+
+func (h *History) path(serverID, name string) string {
+	return filepath.Join(h.directory, sanitiseLogName(strings.ToLower(serverID)), sanitiseLogName(strings.ToLower(name))+".jsonl")
+}
+
+func (h *History) read(path string) ([]jsonLogLine, error) {
+	file, err := os.Open(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	defer func() { _ = file.Close() }()
+	var lines []jsonLogLine
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(nil, 1024*1024)
+	for scanner.Scan() {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var line jsonLogLine
+		if err = json.Unmarshal(scanner.Bytes(), &line); err != nil {
+			return nil, err
+		}
+		lines = append(lines, line)
+	}
+	return lines, scanner.Err()
+}
+
+// write replaces the history file, writing to a temporary file first so a failure doesn't lose existing history
+func (h *History) write(path string, lines []jsonLogLine) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+	file, err := os.CreateTemp(filepath.Dir(path), ".history-*")
+	if err != nil {
+		return err
+	}
+	defer func() { _ = os.Remove(file.Name()) }()
+	writer := bufio.NewWriter(file)
+	encoder := json.NewEncoder(writer)
+	encoder.SetEscapeHTML(false)
+	for i := range lines {
+		if err = encoder.Encode(lines[i]); err != nil {
+			_ = file.Close()
+			return err
+		}
+	}
+	if err = writer.Flush(); err != nil {
+		_ = file.Close()
+		return err
+	}
+	if err = file.Close(); err != nil {
+		return err
+	}
+	return os.Rename(file.Name(), path)
+}
+
+type historyKey struct {
+	time    int64
+	kind    string
+	nick    string
+	message string
+}
+
+func newHistoryKey(line jsonLogLine) historyKey {
+	return historyKey{time: line.Time.Unix(), kind: line.Type, nick: line.Nick, message: line.Message}
+}
+
+// historyMessage turns a stored line back into a message, it's tagged as chat history so that it isn't logged again,
+// counted as unread or added to the mentions window
+func historyMessage(line jsonLogLine, timestampFormat string, highlighter Highlighter) *Message {
+	tags := maps.Clone(line.Tags)
+	if tags == nil {
+		tags = map[string]string{}
+	}
+	tags["time"] = line.Time.UTC().Format(v3TimestampFormat)
+	tags["chathistory"] = "true"
+	switch line.Type {
+	case "action":
+		return newMessage(timestampFormat, line.Me, line.Nick, "\001ACTION "+line.Message+"\001", Normal, tags, highlighter)
+	case "notice":
+		return newMessage(timestampFormat, line.Me, line.Nick, line.Message, Notice, tags, highlighter)
+	case "event":
+		message := newMessage(timestampFormat, line.Me, "", line.Message, Event, tags, nil)
+		message.eventType, message.subject, _ = parseMembershipEvent(line.Message)
+		return message
+	case "error":
+		return newMessage(timestampFormat, line.Me, "", line.Message, Error, tags, nil)
+	default:
+		return newMessage(timestampFormat, line.Me, line.Nick, line.Message, Normal, tags, highlighter)
+	}
+}
+
+// loadHistory adds any imported history for a channel or query to its window, it reads from disk so it shouldn't be
+// called with the server's mutex held
+func (c *Server) loadHistory(window *Window) {
+	if c.history == nil {
+		return
+	}
+	lines, err := c.history.Load(c.GetID(), window.GetName())
+	if err != nil {
+		slog.Error("Unable to load history", "window", window.GetName(), "error", err)
+		return
+	}
+	if len(lines) == 0 {
+		return
+	}
+	highlighter := c.GetHighlighter(window.GetName())
+	for i := range lines {
+		window.AddMessage(historyMessage(lines[i], c.timestampFormat, highlighter))
+	}
+}
+
+func (c *Server) SetHistory(history *History) {
+	c.history = history
+}
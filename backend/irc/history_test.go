@@ -0,0 +1,140 @@
+package irc
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ergochat/irc-go/ircevent"
+	"github.com/greboid/tithon/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHistory_AddAndLoad(t *testing.T) {
+	history := NewHistory(t.TempDir())
+	later := jsonLogLine{Time: time.Date(2025, 3, 2, 10, 0, 0, 0, time.UTC), Type: "message", Nick: "bob", Message: "later"}
+	earlier := jsonLogLine{Time: time.Date(2025, 3, 1, 10, 0, 0, 0, time.UTC), Type: "message", Nick: "alice", Message: "earlier <b>"}
+
+	added, err := history.Add("Libera", "#Test", []jsonLogLine{later})
+	require.NoError(t, err)
+	assert.Equal(t, 1, added)
+	added, err = history.Add("libera", "#test", []jsonLogLine{earlier, later})
+	require.NoError(t, err)
+	assert.Equal(t, 1, added)
+
+	lines, err := history.Load("LIBERA", "#TEST")
+	require.NoError(t, err)
+	require.Len(t, lines, 2)
+	assert.Equal(t, "earlier <b>", lines[0].Message)
+	assert.Equal(t, "later", lines[1].Message)
+
+	lines, err = history.Load("libera", "#other")
+	require.NoError(t, err)
+	assert.Empty(t, lines)
+}
+
+func TestHistory_LoadLimit(t *testing.T) {
+	history := NewHistory(t.TempDir())
+	var lines []jsonLogLine
+	start := time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC)
+	for i := range historyLimit + 10 {
+		lines = append(lines, jsonLogLine{Time: start.Add(time.Duration(i) * time.Second), Type: "message", Nick: "alice", Message: fmt.Sprintf("%d", i)})
+	}
+	_, err := history.Add("libera", "#test", lines)
+	require.NoError(t, err)
+
+	loaded, err := history.Load("libera", "#test")
+	require.NoError(t, err)
+	require.Len(t, loaded, historyLimit)
+	assert.Equal(t, "10", loaded[0].Message)
+}
+
+func TestHistory_LoadInvalid(t *testing.T) {
+	directory := t.TempDir()
+	history := NewHistory(directory)
+	require.NoError(t, os.MkdirAll(filepath.Join(directory, "libera"), 0700))
+	require.NoError(t, os.WriteFile(filepath.Join(directory, "libera", "#test.jsonl"), []byte("{"), 0600))
+	_, err := history.Load("libera", "#test")
+	assert.Error(t, err)
+}
+
+func TestHistory_Nil(t *testing.T) {
+	var history *History
+	lines, err := history.Load("libera", "#test")
+	assert.NoError(t, err)
+	assert.Nil(t, lines)
+}
+
+func TestHistoryMessage(t *testing.T) {
+	when := time.Date(2025, 3, 1, 10, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name     string
+		line     jsonLogLine
+		wantType MessageType
+		wantNick string
+		wantText string
+	}{
+		{name: "Message", line: jsonLogLine{Type: "message", Nick: "alice", Message: "hi"}, wantType: Normal, wantNick: "alice", wantText: "hi"},
+		{name: "Action", line: jsonLogLine{Type: "action", Nick: "alice", Message: "waves"}, wantType: Action, wantNick: "alice", wantText: "waves"},
+		{name: "Notice", line: jsonLogLine{Type: "notice", Nick: "ChanServ", Message: "welcome"}, wantType: Notice, wantNick: "ChanServ", wantText: "welcome"},
+		{name: "Event", line: jsonLogLine{Type: "event", Message: "alice has joined"}, wantType: Event, wantText: "alice has joined"},
+		{name: "Error", line: jsonLogLine{Type: "error", Message: "oops"}, wantType: Error, wantText: "oops"},
+		{name: "Highlight", line: jsonLogLine{Type: "message", Nick: "alice", Message: "hi bob"}, wantType: Highlight, wantNick: "alice", wantText: "hi bob"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.line.Time = when
+			tt.line.Tags = map[string]string{"msgid": "1"}
+			message := historyMessage(tt.line, "15:04", HighlightWords{"bob"})
+			assert.Equal(t, tt.wantType, message.GetType())
+			assert.Equal(t, tt.wantNick, message.GetNickname())
+			assert.Equal(t, tt.wantText, message.GetMessage())
+			assert.True(t, when.Equal(message.GetTime()))
+			assert.Equal(t, "true", message.GetTags()["chathistory"])
+			assert.Equal(t, "1", message.GetTags()["msgid"])
+		})
+	}
+}
+
+func TestHistoryMessage_NotUnread(t *testing.T) {
+	window := &Window{}
+	window.AddMessage(historyMessage(jsonLogLine{Type: "message", Nick: "alice", Message: "hi"}, "15:04", nil))
+	assert.Equal(t, 0, window.GetUnreadCount())
+}
+
+func TestHistoryMessage_Membership(t *testing.T) {
+	join := historyMessage(jsonLogLine{Type: "event", Message: "alice [al@example.com] has joined #tithon"}, "15:04", nil)
+	assert.True(t, join.isMembership(), "Imported joins should be filtered like live ones")
+	assert.Equal(t, "alice", join.subject)
+	topic := historyMessage(jsonLogLine{Type: "event", Message: "alice changed the topic"}, "15:04", nil)
+	assert.False(t, topic.isMembership())
+}
+
+func TestServer_AddChannelLoadsImportedHistory(t *testing.T) {
+	directory := t.TempDir()
+	path := filepath.Join(directory, "logs", "libera", "#Tithon.log")
+	require.NoError(t, os.MkdirAll(filepath.Dir(path), 0700))
+	require.NoError(t, os.WriteFile(path, []byte("--- Log opened Sat Mar 01 10:00:00 2025\n10:02 <alice> hello there\n"), 0600))
+	history := NewHistory(filepath.Join(directory, "history"))
+	servers := []config.Server{{ID: "s1", Hostname: "irc.libera.chat"}}
+	_, _, added, err := ImportLog(history, servers, ImportIrssi, path, "", "")
+	require.NoError(t, err)
+	require.Equal(t, 1, added)
+
+	server := &Server{
+		Window:     &Window{id: "s1", name: "Libera.Chat", isServer: true},
+		connection: &ircevent.Connection{},
+		channels:   map[string]*Channel{},
+		pms:        map[string]*Query{},
+		ut:         &testUpdateTrigger{updates: make(chan struct{}, 2)},
+		history:    history,
+	}
+	channel := server.AddChannel("#tithon")
+	messages := channel.GetMessages()
+	require.Len(t, messages, 1, "History should be loaded using the server's ID, not the network name")
+	assert.Equal(t, "hello there", messages[0].GetMessage())
+	assert.Empty(t, server.AddQuery("bob").GetMessages())
+}
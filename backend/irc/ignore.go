@@ -0,0 +1,212 @@
+package irc
+
+import (
+	"errors"
+	"github.com/ergochat/irc-go/ircmsg"
+	"github.com/greboid/tithon/config"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+)
+
+type IgnoreType string
+
+const (
+	IgnoreMessages IgnoreType = config.IgnoreMessages
+	IgnoreNotices  IgnoreType = config.IgnoreNotices
+	IgnoreCTCP     IgnoreType = config.IgnoreCTCP
+	IgnoreInvites  IgnoreType = config.IgnoreInvites
+	IgnoreJoins    IgnoreType = config.IgnoreJoins
+)
+
+// accountMaskPrefix marks a mask as matching an account name rather than a hostmask
+const accountMaskPrefix = "$a:"
+
+type ignoreRule struct {
+	entry   config.Ignore
+	account string
+	mask    *regexp.Regexp
+}
+
+// IgnoreList holds the ignore rules for every server, changes are passed to the save callback so they can be
+// persisted
+type IgnoreList struct {
+	mutex sync.RWMutex
+	rules []ignoreRule
+	save  func([]config.Ignore)
+}
+
+func NewIgnoreList(entries []config.Ignore, save func([]config.Ignore)) *IgnoreList {
+	il := &IgnoreList{save: save}
+	for i := range entries {
+		il.rules = append(il.rules, newIgnoreRule(entries[i]))
+	}
+	return il
+}
+
+func newIgnoreRule(entry config.Ignore) ignoreRule {
+	if account, ok := strings.CutPrefix(entry.Mask, accountMaskPrefix); ok {
+		return ignoreRule{entry: entry, account: strings.ToLower(account)}
+	}
+	entry.Mask = NormaliseMask(entry.Mask)
+	return ignoreRule{entry: entry, mask: compileMask(entry.Mask)}
+}
+
+// NormaliseMask expands a partial mask, so that a nickname on its own becomes nick!*@*
+func NormaliseMask(mask string) string {
+	if strings.HasPrefix(mask, accountMaskPrefix) {
+		return mask
+	}
+	if !strings.Contains(mask, "@") {
+		if !strings.Contains(mask, "!") {
+			return mask + "!*@*"
+		}
+		return mask + "@*"
+	}
+	if !strings.Contains(mask, "!") {
+		return "*!" + mask
+	}
+	return mask
+}
+
+// compileMask converts a wildcard mask into a case-insensitive regex
+func compileMask(mask string) *regexp.Regexp {
+	return regexp.MustCompile("(?i)^" + wildcardPattern(mask) + "$")
+}
+
+// wildcardPattern converts a mask using * and ? wildcards into a regex pattern
+func wildcardPattern(mask string) string {
+	var pattern strings.Builder
+	for _, char := range mask {
+		switch char {
+		case '*':
+			pattern.WriteString(".*")
+		case '?':
+			pattern.WriteString(".")
+		default:
+			pattern.WriteString(regexp.QuoteMeta(string(char)))
+		}
+	}
+	return pattern.String()
+}
+
+func (r ignoreRule) matches(serverID string, channel string, source string, account string, ignoreType IgnoreType) bool {
+	if r.entry.Network != "" && r.entry.Network != serverID {
+		return false
+	}
+	if r.entry.Channel != "" && !strings.EqualFold(r.entry.Channel, channel) {
+		return false
+	}
+	if len(r.entry.Types) > 0 && !slices.Contains(r.entry.Types, string(ignoreType)) {
+		return false
+	}
+	if r.mask == nil {
+		return account != "" && account != "*" && strings.ToLower(account) == r.account
+	}
+	return r.mask.MatchString(source)
+}
+
+// IsIgnored checks if an event from the given source, and account if known, should be hidden
+func (il *IgnoreList) IsIgnored(serverID string, channel string, source string, account string, ignoreType IgnoreType) bool {
+	if il == nil {
+		return false
+	}
+	il.mutex.RLock()
+	defer il.mutex.RUnlock()
+	for i := range il.rules {
+		if il.rules[i].matches(serverID, channel, source, account, ignoreType) {
+			return true
+		}
+	}
+	return false
+}
+
+// Entries returns the current ignore rules
+func (il *IgnoreList) Entries() []config.Ignore {
+	if il == nil {
+		return nil
+	}
+	il.mutex.RLock()
+	defer il.mutex.RUnlock()
+	entries := make([]config.Ignore, 0, len(il.rules))
+	for i := range il.rules {
+		entries = append(entries, il.rules[i].entry)
+	}
+	return entries
+}
+
+// Add adds a new ignore rule, replacing any existing rule with the same mask and scope
+func (il *IgnoreList) Add(entry config.Ignore) error {
+	if il == nil {
+		return errors.New("ignore list unavailable")
+	}
+	if entry.Mask == "" {
+		return errors.New("no mask specified")
+	}
+	for _, ignoreType := range entry.Types {
+		if !slices.Contains(config.IgnoreTypes, ignoreType) {
+			return errors.New("unknown ignore type: " + ignoreType)
+		}
+	}
+	rule := newIgnoreRule(entry)
+	il.mutex.Lock()
+	il.rules = slices.DeleteFunc(il.rules, func(existing ignoreRule) bool {
+		return sameIgnoreScope(existing.entry, rule.entry)
+	})
+	il.rules = append(il.rules, rule)
+	il.mutex.Unlock()
+	il.persist()
+	return nil
+}
+
+// Remove removes every rule with the given mask, returning how many were removed
+func (il *IgnoreList) Remove(mask string) int {
+	if il == nil {
+		return 0
+	}
+	mask = NormaliseMask(mask)
+	il.mutex.Lock()
+	before := len(il.rules)
+	il.rules = slices.DeleteFunc(il.rules, func(existing ignoreRule) bool {
+		return strings.EqualFold(existing.entry.Mask, mask)
+	})
+	removed := before - len(il.rules)
+	il.mutex.Unlock()
+	if removed > 0 {
+		il.persist()
+	}
+	return removed
+}
+
+func sameIgnoreScope(a config.Ignore, b config.Ignore) bool {
+	return strings.EqualFold(a.Mask, b.Mask) && a.Network == b.Network && strings.EqualFold(a.Channel, b.Channel)
+}
+
+func (il *IgnoreList) persist() {
+	if il.save != nil {
+		il.save(il.Entries())
+	}
+}
+
+// IsIgnored checks if a message should be hidden because the sender is on the ignore list
+func (c *Server) IsIgnored(message ircmsg.Message, channel string, ignoreType IgnoreType) bool {
+	_, account := message.GetTag("account")
+	return c.ignoreList.IsIgnored(c.GetID(), channel, message.Source, account, ignoreType)
+}
+
+func (c *Server) SetIgnoreList(ignoreList *IgnoreList) {
+	c.ignoreList = ignoreList
+}
+
+func (c *Server) GetIgnoreList() *IgnoreList {
+	return c.ignoreList
+}
+
+// channelTarget returns the target if it is a channel, or an empty string for private messages
+func channelTarget(isValidChannel func(string) bool, target string) string {
+	if isValidChannel(target) {
+		return target
+	}
+	return ""
+}
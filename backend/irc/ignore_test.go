@@ -0,0 +1,310 @@
+package irc
+
+import (
+	"testing"
+
+	"github.com/ergochat/irc-go/ircmsg"
+	"github.com/greboid/tithon/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func notIgnored(ircmsg.Message, string, IgnoreType) bool {
+	return false
+}
+
+func TestNormaliseMask(t *testing.T) {
+	tests := []struct {
+		mask string
+		want string
+	}{
+		{mask: "nick", want: "nick!*@*"},
+		{mask: "nick!user", want: "nick!user@*"},
+		{mask: "user@host", want: "*!user@host"},
+		{mask: "*!*@host", want: "*!*@host"},
+		{mask: "$a:account", want: "$a:account"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.mask, func(t *testing.T) {
+			assert.Equal(t, tt.want, NormaliseMask(tt.mask))
+		})
+	}
+}
+
+func TestIgnoreList_IsIgnored(t *testing.T) {
+	tests := []struct {
+		name       string
+		entry      config.Ignore
+		serverID   string
+		channel    string
+		source     string
+		account    string
+		ignoreType IgnoreType
+		want       bool
+	}{
+		{
+			name:       "Nick matches",
+			entry:      config.Ignore{Mask: "Spammer"},
+			source:     "spammer!user@example.com",
+			ignoreType: IgnoreMessages,
+			want:       true,
+		},
+		{
+			name:       "Nick doesn't match",
+			entry:      config.Ignore{Mask: "spammer"},
+			source:     "spammer2!user@example.com",
+			ignoreType: IgnoreMessages,
+			want:       false,
+		},
+		{
+			name:       "Host wildcard",
+			entry:      config.Ignore{Mask: "*!*@*.example.com"},
+			source:     "anyone!user@host.example.com",
+			ignoreType: IgnoreNotices,
+			want:       true,
+		},
+		{
+			name:       "Single character wildcard",
+			entry:      config.Ignore{Mask: "bot?!*@*"},
+			source:     "bot1!user@example.com",
+			ignoreType: IgnoreMessages,
+			want:       true,
+		},
+		{
+			name:       "Regex characters are literal",
+			entry:      config.Ignore{Mask: "*!*@host.com"},
+			source:     "nick!user@hostxcom",
+			ignoreType: IgnoreMessages,
+			want:       false,
+		},
+		{
+			name:       "Account matches",
+			entry:      config.Ignore{Mask: "$a:Troll"},
+			source:     "newnick!user@example.com",
+			account:    "troll",
+			ignoreType: IgnoreMessages,
+			want:       true,
+		},
+		{
+			name:       "Account mask doesn't match logged out users",
+			entry:      config.Ignore{Mask: "$a:troll"},
+			source:     "troll!user@example.com",
+			account:    "*",
+			ignoreType: IgnoreMessages,
+			want:       false,
+		},
+		{
+			name:       "Type included",
+			entry:      config.Ignore{Mask: "nick", Types: []string{config.IgnoreJoins, config.IgnoreCTCP}},
+			source:     "nick!user@host",
+			ignoreType: IgnoreCTCP,
+			want:       true,
+		},
+		{
+			name:       "Type excluded",
+			entry:      config.Ignore{Mask: "nick", Types: []string{config.IgnoreJoins}},
+			source:     "nick!user@host",
+			ignoreType: IgnoreMessages,
+			want:       false,
+		},
+		{
+			name:       "Network matches",
+			entry:      config.Ignore{Mask: "nick", Network: "server1"},
+			serverID:   "server1",
+			source:     "nick!user@host",
+			ignoreType: IgnoreMessages,
+			want:       true,
+		},
+		{
+			name:       "Other network",
+			entry:      config.Ignore{Mask: "nick", Network: "server1"},
+			serverID:   "server2",
+			source:     "nick!user@host",
+			ignoreType: IgnoreMessages,
+			want:       false,
+		},
+		{
+			name:       "Channel matches",
+			entry:      config.Ignore{Mask: "nick", Channel: "#Test"},
+			channel:    "#test",
+			source:     "nick!user@host",
+			ignoreType: IgnoreMessages,
+			want:       true,
+		},
+		{
+			name:       "Private message with channel scope",
+			entry:      config.Ignore{Mask: "nick", Channel: "#test"},
+			source:     "nick!user@host",
+			ignoreType: IgnoreMessages,
+			want:       false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			il := NewIgnoreList([]config.Ignore{tt.entry}, nil)
+			assert.Equal(t, tt.want, il.IsIgnored(tt.serverID, tt.channel, tt.source, tt.account, tt.ignoreType))
+		})
+	}
+}
+
+func TestIgnoreList_NilList(t *testing.T) {
+	var il *IgnoreList
+	assert.False(t, il.IsIgnored("", "", "nick!user@host", "", IgnoreMessages))
+	assert.Empty(t, il.Entries())
+	assert.Error(t, il.Add(config.Ignore{Mask: "nick"}))
+	assert.Equal(t, 0, il.Remove("nick"))
+}
+
+func TestIgnoreList_AddRemove(t *testing.T) {
+	var saved []config.Ignore
+	saves := 0
+	il := NewIgnoreList(nil, func(ignores []config.Ignore) {
+		saved = ignores
+		saves++
+	})
+
+	require.NoError(t, il.Add(config.Ignore{Mask: "nick"}))
+	assert.Equal(t, []config.Ignore{{Mask: "nick!*@*"}}, saved)
+
+	require.NoError(t, il.Add(config.Ignore{Mask: "nick!*@*", Types: []string{config.IgnoreJoins}}))
+	assert.Equal(t, []config.Ignore{{Mask: "nick!*@*", Types: []string{config.IgnoreJoins}}}, saved, "Same scope should be replaced")
+
+	require.NoError(t, il.Add(config.Ignore{Mask: "nick", Channel: "#test"}))
+	assert.Len(t, saved, 2)
+
+	assert.Error(t, il.Add(config.Ignore{Mask: "nick", Types: []string{"quits"}}))
+	assert.Error(t, il.Add(config.Ignore{}))
+	assert.Equal(t, 3, saves)
+
+	assert.Equal(t, 0, il.Remove("other"))
+	assert.Equal(t, 3, saves)
+	assert.Equal(t, 2, il.Remove("NICK"))
+	assert.Empty(t, saved)
+	assert.Equal(t, 4, saves)
+}
+
+func TestServer_IsIgnored(t *testing.T) {
+	server := NewServer("15:04:05", "server1", []config.ServerAddress{{Hostname: "irc.example.com", Port: 6697, TLS: true}}, false, "", "", "", "", config.Connection{}, NewProfile("nick"), nil, nil)
+	message := ircmsg.Message{Source: "other!user@host", Command: "PRIVMSG", Params: []string{"#test", "hello"}}
+	message.SetTag("account", "troll")
+	assert.False(t, server.IsIgnored(message, "#test", IgnoreMessages))
+
+	server.SetIgnoreList(NewIgnoreList([]config.Ignore{{Mask: "$a:troll", Network: "server1"}}, nil))
+	assert.True(t, server.IsIgnored(message, "#test", IgnoreMessages))
+}
+
+func TestHandlers_Ignored(t *testing.T) {
+	newChannel := func() *Channel {
+		return &Channel{Window: &Window{name: "#test", hasUsers: true}}
+	}
+	isValidChannel := func(name string) bool { return name == "#test" }
+	currentNick := func() string { return "testnick" }
+	source := "spammer!user@example.com"
+
+	tests := []struct {
+		name     string
+		wantType IgnoreType
+		run      func(channel *Channel, isIgnored func(ircmsg.Message, string, IgnoreType) bool) []*Message
+	}{
+		{
+			name:     "Channel message",
+			wantType: IgnoreMessages,
+			run: func(channel *Channel, isIgnored func(ircmsg.Message, string, IgnoreType) bool) []*Message {
+				notified := false
+				HandlePrivMsg("15:04:05", func() {}, isValidChannel,
+					func(string) (*Channel, error) { return channel, nil },
+					currentNick, func() string { return "server" }, func() string { return "id" },
+					func(string, string, string, string, string, bool) bool { notified = true; return true },
+					func(string) (*Query, error) { return nil, assert.AnError },
+					func(string) *Query { return nil },
+					isIgnored,
+					noHighlights,
+				)(ircmsg.Message{Source: source, Command: "PRIVMSG", Params: []string{"#test", "spam"}})
+				assert.False(t, notified)
+				return channel.GetMessages()
+			},
+		},
+		{
+			name:     "Private message",
+			wantType: IgnoreMessages,
+			run: func(channel *Channel, isIgnored func(ircmsg.Message, string, IgnoreType) bool) []*Message {
+				HandlePrivMsg("15:04:05", func() {}, isValidChannel,
+					func(string) (*Channel, error) { return channel, nil },
+					currentNick, func() string { return "server" }, func() string { return "id" },
+					func(string, string, string, string, string, bool) bool { return true },
+					func(string) (*Query, error) { return nil, assert.AnError },
+					func(string) *Query {
+						t.Error("Query should not be created")
+						return nil
+					},
+					isIgnored,
+					noHighlights,
+				)(ircmsg.Message{Source: source, Command: "PRIVMSG", Params: []string{"testnick", "spam"}})
+				return channel.GetMessages()
+			},
+		},
+		{
+			name:     "Notice",
+			wantType: IgnoreNotices,
+			run: func(channel *Channel, isIgnored func(ircmsg.Message, string, IgnoreType) bool) []*Message {
+				HandleNotice("15:04:05", func() {}, currentNick, channel.AddMessage, isValidChannel,
+					func(string) (*Channel, error) { return channel, nil },
+					func(string) (*Query, error) { return nil, assert.AnError },
+					func(string) *Query { return nil },
+					isIgnored,
+					noHighlights,
+				)(ircmsg.Message{Source: source, Command: "NOTICE", Params: []string{"#test", "spam"}})
+				return channel.GetMessages()
+			},
+		},
+		{
+			name:     "CTCP",
+			wantType: IgnoreCTCP,
+			run: func(channel *Channel, isIgnored func(ircmsg.Message, string, IgnoreType) bool) []*Message {
+				HandleCTCPQuery("15:04:05", func() {},
+					func(string) { t.Error("CTCP reply should not be sent") },
+					channel.AddMessage, isValidChannel, isIgnored,
+				)(ircmsg.Message{Source: source, Command: "PRIVMSG", Params: []string{"testnick", "\x01VERSION\x01"}})
+				return channel.GetMessages()
+			},
+		},
+		{
+			name:     "Join",
+			wantType: IgnoreJoins,
+			run: func(channel *Channel, isIgnored func(ircmsg.Message, string, IgnoreType) bool) []*Message {
+				HandleOtherJoin("15:04:05", func() {}, currentNick,
+					func(string) (*Channel, error) { return channel, nil },
+					isIgnored,
+					(&netsplits{}).join,
+				)(ircmsg.Message{Source: source, Command: "JOIN", Params: []string{"#test"}})
+				assert.Len(t, channel.GetUsers(), 1, "Ignored users should still be added to the nicklist")
+				return channel.GetMessages()
+			},
+		},
+		{
+			name:     "Part",
+			wantType: IgnoreJoins,
+			run: func(channel *Channel, isIgnored func(ircmsg.Message, string, IgnoreType) bool) []*Message {
+				channel.AddUser(NewUser("spammer", ""))
+				HandlePart("15:04:05", func() {}, currentNick,
+					func(string) (*Channel, error) { return channel, nil },
+					func(string) {},
+					isIgnored,
+				)(ircmsg.Message{Source: source, Command: "PART", Params: []string{"#test"}})
+				assert.Empty(t, channel.GetUsers(), "Ignored users should still be removed from the nicklist")
+				return channel.GetMessages()
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var gotType IgnoreType
+			messages := tt.run(newChannel(), func(message ircmsg.Message, _ string, ignoreType IgnoreType) bool {
+				gotType = ignoreType
+				return message.Source == source
+			})
+			assert.Equal(t, tt.wantType, gotType)
+			assert.Empty(t, messages)
+		})
+	}
+}
@@ -0,0 +1,292 @@
+package irc
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/greboid/tithon/config"
+)
+
+const (
+	ImportIrssi   = "irssi"
+	ImportWeechat = "weechat"
+	ImportZNC     = "znc"
+	ImportJSONL   = "jsonl"
+)
+
+// modePrefixes are stripped from the start of nicknames in logs
+const modePrefixes = " ~&@%+"
+
+// ImportLog parses a log file written by irssi, weechat, ZNC or Tithon and adds the messages to the history of one of
+// the configured servers, the network and channel are guessed from the path of the log when they're empty.  It returns
+// the server the messages were added to.
+func ImportLog(history *History, servers []config.Server, format, path, network, channel string) (config.Server, string, int, error) {
+	guessedNetwork, guessedChannel, date := guessImportTarget(format, path)
+	if network == "" {
+		network = guessedNetwork
+	}
+	if channel == "" {
+		channel = guessedChannel
+	}
+	if network == "" || channel == "" {
+		return config.Server{}, channel, 0, fmt.Errorf("unable to work out the network and channel for %s", path)
+	}
+	server, err := findImportServer(servers, network)
+	if err != nil {
+		return config.Server{}, channel, 0, err
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		return server, channel, 0, err
+	}
+	defer func() { _ = file.Close() }()
+	if date.IsZero() {
+		stat, err := file.Stat()
+		if err != nil {
+			return server, channel, 0, err
+		}
+		date = stat.ModTime()
+	}
+	lines, err := parseLog(format, file, date)
+	if err != nil {
+		return server, channel, 0, err
+	}
+	added, err := history.Add(server.ID, channel, lines)
+	return server, channel, added, err
+}
+
+// findImportServer finds the configured server a network's logs belong to, matching the server's ID or hostname, or a
+// part of the hostname so that libera matches irc.libera.chat
+func findImportServer(servers []config.Server, network string) (config.Server, error) {
+	var matches []config.Server
+	for i := range servers {
+		if strings.EqualFold(servers[i].ID, network) || strings.EqualFold(servers[i].Hostname, network) {
+			return servers[i], nil
+		}
+		if slices.ContainsFunc(strings.Split(servers[i].Hostname, "."), func(label string) bool {
+			return strings.EqualFold(label, network)
+		}) {
+			matches = append(matches, servers[i])
+		}
+	}
+	switch len(matches) {
+	case 0:
+		return config.Server{}, fmt.Errorf("no server is configured for %s, use -network with the ID or hostname of a server", network)
+	case 1:
+		return matches[0], nil
+	default:
+		return config.Server{}, fmt.Errorf("more than one server matches %s, use -network with the ID or hostname of a server", network)
+	}
+}
+
+// guessImportTarget works out the network, channel and date of a log from the default paths each client uses
+func guessImportTarget(format, path string) (string, string, time.Time) {
+	base := filepath.Base(path)
+	parent := filepath.Base(filepath.Dir(path))
+	grandparent := filepath.Base(filepath.Dir(filepath.Dir(path)))
+	if format == ImportWeechat {
+		// irc.network.#channel.weechatlog
+		name := strings.TrimSuffix(base, filepath.Ext(base))
+		name = strings.TrimPrefix(name, "irc.")
+		network, channel, _ := strings.Cut(name, ".")
+		return network, channel, time.Time{}
+	}
+	// ZNC and Tithon use network/channel/YYYY-MM-DD.log
+	name := strings.TrimSuffix(base, filepath.Ext(base))
+	for _, layout := range []string{time.DateOnly, "20060102"} {
+		if date, err := time.ParseInLocation(layout, name, time.Local); err == nil {
+			return grandparent, parent, date
+		}
+	}
+	if format == ImportIrssi {
+		// network/#channel.log
+		return parent, name, time.Time{}
+	}
+	return "", "", time.Time{}
+}
+
+// parseLog parses the lines of a log, date is used for the lines before the log gives a date
+func parseLog(format string, reader io.Reader, date time.Time) ([]jsonLogLine, error) {
+	var parse func(string, *time.Time) (jsonLogLine, bool, error)
+	switch format {
+	case ImportIrssi:
+		parse = parseIrssiLine
+	case ImportWeechat:
+		parse = parseWeechatLine
+	case ImportZNC:
+		parse = parseZNCLine
+	case ImportJSONL:
+		parse = parseJSONLine
+	default:
+		return nil, fmt.Errorf("unknown log format: %s", format)
+	}
+	var lines []jsonLogLine
+	scanner := bufio.NewScanner(reader)
+	scanner.Buffer(nil, 1024*1024)
+	number := 0
+	for scanner.Scan() {
+		number++
+		line, ok, err := parse(strings.TrimRight(scanner.Text(), "\r"), &date)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", number, err)
+		}
+		if ok {
+			lines = append(lines, line)
+		}
+	}
+	return lines, scanner.Err()
+}
+
+// parseIrssiLine parses irssi's default log format, the date comes from the log opened and day changed lines
+func parseIrssiLine(text string, date *time.Time) (jsonLogLine, bool, error) {
+	if rest, found := strings.CutPrefix(text, "--- Log opened "); found {
+		if opened, err := time.ParseInLocation("Mon Jan 02 15:04:05 2006", rest, time.Local); err == nil {
+			*date = opened
+		}
+		return jsonLogLine{}, false, nil
+	}
+	if rest, found := strings.CutPrefix(text, "--- Day changed "); found {
+		if changed, err := time.ParseInLocation("Mon Jan 02 2006", rest, time.Local); err == nil {
+			*date = changed
+		}
+		return jsonLogLine{}, false, nil
+	}
+	clock, rest, found := strings.Cut(text, " ")
+	if !found {
+		return jsonLogLine{}, false, nil
+	}
+	when, ok := parseClock(*date, clock)
+	if !ok {
+		return jsonLogLine{}, false, nil
+	}
+	line := jsonLogLine{Time: when}
+	switch {
+	case strings.HasPrefix(rest, "<"):
+		nick, message, found := strings.Cut(rest[1:], "> ")
+		if !found {
+			return jsonLogLine{}, false, nil
+		}
+		line.Type, line.Nick, line.Message = "message", strings.TrimLeft(nick, modePrefixes), message
+	case strings.HasPrefix(rest, " * "):
+		line.Type = "action"
+		line.Nick, line.Message, _ = strings.Cut(rest[3:], " ")
+	case strings.HasPrefix(rest, "-!- "):
+		line.Type, line.Message = "event", rest[4:]
+	case strings.HasPrefix(rest, "-"):
+		// -nick(user@host)- or -nick:#channel-
+		nick, message, found := strings.Cut(rest[1:], "- ")
+		if !found {
+			return jsonLogLine{}, false, nil
+		}
+		nick, _, _ = strings.Cut(nick, "(")
+		nick, _, _ = strings.Cut(nick, ":")
+		line.Type, line.Nick, line.Message = "notice", nick, message
+	default:
+		return jsonLogLine{}, false, nil
+	}
+	return line, true, nil
+}
+
+// parseWeechatLine parses weechat's tab separated log format, every line has the full date and time
+func parseWeechatLine(text string, _ *time.Time) (jsonLogLine, bool, error) {
+	fields := strings.SplitN(text, "\t", 3)
+	if len(fields) != 3 {
+		return jsonLogLine{}, false, nil
+	}
+	when, err := time.ParseInLocation(time.DateTime, fields[0], time.Local)
+	if err != nil {
+		return jsonLogLine{}, false, nil
+	}
+	line := jsonLogLine{Time: when}
+	prefix, message := fields[1], fields[2]
+	switch prefix {
+	case "-->", "<--", "--":
+		if rest, found := strings.CutPrefix(message, "Notice("); found {
+			nick, notice, found := strings.Cut(rest, ")")
+			if found {
+				_, notice, _ = strings.Cut(notice, ": ")
+				line.Type, line.Nick, line.Message = "notice", nick, notice
+				break
+			}
+		}
+		line.Type, line.Message = "event", message
+	case "=!=":
+		line.Type, line.Message = "error", message
+	case " *", "*":
+		line.Type = "action"
+		line.Nick, line.Message, _ = strings.Cut(message, " ")
+	case "", " ":
+		return jsonLogLine{}, false, nil
+	default:
+		line.Type, line.Nick, line.Message = "message", strings.TrimLeft(prefix, modePrefixes), message
+	}
+	return line, true, nil
+}
+
+// parseZNCLine parses the format used by ZNC's log module, the date comes from the name of the file
+func parseZNCLine(text string, date *time.Time) (jsonLogLine, bool, error) {
+	clock, found := strings.CutPrefix(text, "[")
+	if !found {
+		return jsonLogLine{}, false, nil
+	}
+	clock, rest, found := strings.Cut(clock, "] ")
+	if !found {
+		return jsonLogLine{}, false, nil
+	}
+	when, ok := parseClock(*date, clock)
+	if !ok {
+		return jsonLogLine{}, false, nil
+	}
+	line := jsonLogLine{Time: when}
+	switch {
+	case strings.HasPrefix(rest, "<"):
+		nick, message, found := strings.Cut(rest[1:], "> ")
+		if !found {
+			return jsonLogLine{}, false, nil
+		}
+		line.Type, line.Nick, line.Message = "message", nick, message
+	case strings.HasPrefix(rest, "*** "):
+		line.Type, line.Message = "event", rest[4:]
+	case strings.HasPrefix(rest, "* "):
+		line.Type = "action"
+		line.Nick, line.Message, _ = strings.Cut(rest[2:], " ")
+	case strings.HasPrefix(rest, "-"):
+		nick, message, found := strings.Cut(rest[1:], "- ")
+		if !found {
+			return jsonLogLine{}, false, nil
+		}
+		line.Type, line.Nick, line.Message = "notice", nick, message
+	default:
+		return jsonLogLine{}, false, nil
+	}
+	return line, true, nil
+}
+
+// parseJSONLine parses Tithon's own JSONL logs
+func parseJSONLine(text string, _ *time.Time) (jsonLogLine, bool, error) {
+	if strings.TrimSpace(text) == "" {
+		return jsonLogLine{}, false, nil
+	}
+	var line jsonLogLine
+	if err := json.Unmarshal([]byte(text), &line); err != nil {
+		return jsonLogLine{}, false, err
+	}
+	return line, true, nil
+}
+
+// parseClock combines a time of day from a log line with the current date of the log
+func parseClock(date time.Time, clock string) (time.Time, bool) {
+	for _, layout := range []string{time.TimeOnly, "15:04"} {
+		if parsed, err := time.Parse(layout, clock); err == nil {
+			return time.Date(date.Year(), date.Month(), date.Day(), parsed.Hour(), parsed.Minute(), parsed.Second(), 0, time.Local), true
+		}
+	}
+	return time.Time{}, false
+}
@@ -0,0 +1,215 @@
+package irc
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/greboid/tithon/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func localTime(year int, month time.Month, day, hour, minute, second int) time.Time {
+	return time.Date(year, month, day, hour, minute, second, 0, time.Local)
+}
+
+func TestParseLog(t *testing.T) {
+	date := localTime(2025, 3, 1, 0, 0, 0)
+	tests := []struct {
+		name   string
+		format string
+		log    string
+		want   []jsonLogLine
+	}{
+		{
+			name:   "irssi",
+			format: ImportIrssi,
+			log: "--- Log opened Sat Mar 01 10:00:00 2025\n" +
+				"10:01 -!- alice [alice@example.com] has joined #test\n" +
+				"10:02 <@alice> hello there\n" +
+				"10:03 < bob> hi\n" +
+				"10:04  * alice waves\n" +
+				"10:05 -ChanServ(ChanServ@services.)- welcome\n" +
+				"--- Day changed Sun Mar 02 2025\n" +
+				"00:01:30 <bob> still here\n" +
+				"--- Log closed Sun Mar 02 00:02:00 2025\n",
+			want: []jsonLogLine{
+				{Time: localTime(2025, 3, 1, 10, 1, 0), Type: "event", Message: "alice [alice@example.com] has joined #test"},
+				{Time: localTime(2025, 3, 1, 10, 2, 0), Type: "message", Nick: "alice", Message: "hello there"},
+				{Time: localTime(2025, 3, 1, 10, 3, 0), Type: "message", Nick: "bob", Message: "hi"},
+				{Time: localTime(2025, 3, 1, 10, 4, 0), Type: "action", Nick: "alice", Message: "waves"},
+				{Time: localTime(2025, 3, 1, 10, 5, 0), Type: "notice", Nick: "ChanServ", Message: "welcome"},
+				{Time: localTime(2025, 3, 2, 0, 1, 30), Type: "message", Nick: "bob", Message: "still here"},
+			},
+		},
+		{
+			name:   "weechat",
+			format: ImportWeechat,
+			log: "2025-03-01 10:01:00\t-->\talice (alice@example.com) has joined #test\n" +
+				"2025-03-01 10:02:00\t@alice\thello there\n" +
+				"2025-03-01 10:03:00\t *\talice waves\n" +
+				"2025-03-01 10:04:00\t--\tNotice(ChanServ): welcome\n" +
+				"2025-03-01 10:05:00\t=!=\tCannot send to channel\n" +
+				"2025-03-01 10:06:00\t<--\tbob has quit\n" +
+				"not a log line\n",
+			want: []jsonLogLine{
+				{Time: localTime(2025, 3, 1, 10, 1, 0), Type: "event", Message: "alice (alice@example.com) has joined #test"},
+				{Time: localTime(2025, 3, 1, 10, 2, 0), Type: "message", Nick: "alice", Message: "hello there"},
+				{Time: localTime(2025, 3, 1, 10, 3, 0), Type: "action", Nick: "alice", Message: "waves"},
+				{Time: localTime(2025, 3, 1, 10, 4, 0), Type: "notice", Nick: "ChanServ", Message: "welcome"},
+				{Time: localTime(2025, 3, 1, 10, 5, 0), Type: "error", Message: "Cannot send to channel"},
+				{Time: localTime(2025, 3, 1, 10, 6, 0), Type: "event", Message: "bob has quit"},
+			},
+		},
+		{
+			name:   "ZNC",
+			format: ImportZNC,
+			log: "[10:01:00] *** Joins: alice (alice@example.com)\n" +
+				"[10:02:00] <alice> hello there\n" +
+				"[10:03:00] * alice waves\n" +
+				"[10:04:00] -ChanServ- welcome\n",
+			want: []jsonLogLine{
+				{Time: localTime(2025, 3, 1, 10, 1, 0), Type: "event", Message: "Joins: alice (alice@example.com)"},
+				{Time: localTime(2025, 3, 1, 10, 2, 0), Type: "message", Nick: "alice", Message: "hello there"},
+				{Time: localTime(2025, 3, 1, 10, 3, 0), Type: "action", Nick: "alice", Message: "waves"},
+				{Time: localTime(2025, 3, 1, 10, 4, 0), Type: "notice", Nick: "ChanServ", Message: "welcome"},
+			},
+		},
+		{
+			name:   "Tithon irssi logs without a date header",
+			format: ImportIrssi,
+			log:    "10:02 <alice> hello there\n",
+			want: []jsonLogLine{
+				{Time: localTime(2025, 3, 1, 10, 2, 0), Type: "message", Nick: "alice", Message: "hello there"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseLog(tt.format, strings.NewReader(tt.log), date)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestParseLog_JSONL(t *testing.T) {
+	log := `{"time":"2025-03-01T10:00:00Z","type":"message","nick":"alice","message":"hi","tags":{"msgid":"1"}}` + "\n\n"
+	got, err := parseLog(ImportJSONL, strings.NewReader(log), time.Time{})
+	require.NoError(t, err)
+	require.Len(t, got, 1)
+	assert.True(t, time.Date(2025, 3, 1, 10, 0, 0, 0, time.UTC).Equal(got[0].Time))
+	assert.Equal(t, "alice", got[0].Nick)
+	assert.Equal(t, map[string]string{"msgid": "1"}, got[0].Tags)
+
+	_, err = parseLog(ImportJSONL, strings.NewReader("{"), time.Time{})
+	assert.ErrorContains(t, err, "line 1")
+}
+
+func TestParseLog_UnknownFormat(t *testing.T) {
+	_, err := parseLog("mirc", strings.NewReader(""), time.Time{})
+	assert.Error(t, err)
+}
+
+func TestGuessImportTarget(t *testing.T) {
+	tests := []struct {
+		name        string
+		format      string
+		path        string
+		wantNetwork string
+		wantChannel string
+		wantDate    time.Time
+	}{
+		{name: "irssi", format: ImportIrssi, path: "/home/user/irclogs/libera/#test.log", wantNetwork: "libera", wantChannel: "#test"},
+		{name: "weechat", format: ImportWeechat, path: "/logs/irc.libera.#test.weechatlog", wantNetwork: "libera", wantChannel: "#test"},
+		{name: "weechat server buffer", format: ImportWeechat, path: "/logs/irc.server.weechatlog", wantNetwork: "server"},
+		{
+			name: "ZNC", format: ImportZNC, path: "/znc/moddata/log/user/libera/#test/2025-03-01.log",
+			wantNetwork: "libera", wantChannel: "#test", wantDate: localTime(2025, 3, 1, 0, 0, 0),
+		},
+		{
+			name: "Old ZNC", format: ImportZNC, path: "/znc/moddata/log/user/libera/#test/20250301.log",
+			wantNetwork: "libera", wantChannel: "#test", wantDate: localTime(2025, 3, 1, 0, 0, 0),
+		},
+		{
+			name: "Tithon", format: ImportIrssi, path: "/logs/libera/#test/2025-03-01.log",
+			wantNetwork: "libera", wantChannel: "#test", wantDate: localTime(2025, 3, 1, 0, 0, 0),
+		},
+		{name: "Unknown ZNC layout", format: ImportZNC, path: "/logs/test.log"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			network, channel, date := guessImportTarget(tt.format, filepath.FromSlash(tt.path))
+			assert.Equal(t, tt.wantNetwork, network)
+			assert.Equal(t, tt.wantChannel, channel)
+			assert.True(t, tt.wantDate.Equal(date), "want %s got %s", tt.wantDate, date)
+		})
+	}
+}
+
+func TestImportLog(t *testing.T) {
+	directory := t.TempDir()
+	path := filepath.Join(directory, "logs", "Libera", "#Test", "2025-03-01.log")
+	require.NoError(t, os.MkdirAll(filepath.Dir(path), 0700))
+	require.NoError(t, os.WriteFile(path, []byte("[10:02:00] <alice> hello there\n[10:03:00] * alice waves\n"), 0600))
+	history := NewHistory(filepath.Join(directory, "history"))
+	servers := []config.Server{{ID: "s1", Hostname: "irc.libera.chat"}, {ID: "s2", Hostname: "irc.oftc.net"}}
+
+	server, channel, added, err := ImportLog(history, servers, ImportZNC, path, "", "")
+	require.NoError(t, err)
+	assert.Equal(t, "s1", server.ID)
+	assert.Equal(t, "#Test", channel)
+	assert.Equal(t, 2, added)
+
+	_, _, added, err = ImportLog(history, servers, ImportZNC, path, "", "")
+	require.NoError(t, err)
+	assert.Equal(t, 0, added, "importing the same log twice shouldn't duplicate messages")
+
+	server, channel, added, err = ImportLog(history, servers, ImportZNC, path, "s2", "#other")
+	require.NoError(t, err)
+	assert.Equal(t, "s2", server.ID)
+	assert.Equal(t, "#other", channel)
+	assert.Equal(t, 2, added)
+
+	_, _, _, err = ImportLog(history, servers, ImportZNC, path, "efnet", "")
+	assert.Error(t, err, "Logs for networks without a server shouldn't be imported")
+
+	_, _, _, err = ImportLog(history, servers, ImportZNC, filepath.Join(directory, "test.log"), "", "")
+	assert.Error(t, err)
+}
+
+func TestFindImportServer(t *testing.T) {
+	servers := []config.Server{
+		{ID: "s1", Hostname: "irc.libera.chat"},
+		{ID: "s2", Hostname: "irc.oftc.net"},
+		{ID: "s3", Hostname: "eu.irc.example.com"},
+		{ID: "s4", Hostname: "us.irc.example.com"},
+	}
+	tests := []struct {
+		name    string
+		network string
+		want    string
+		wantErr bool
+	}{
+		{name: "ID", network: "S2", want: "s2"},
+		{name: "Hostname", network: "irc.libera.chat", want: "s1"},
+		{name: "Part of hostname", network: "Libera", want: "s1"},
+		{name: "Hostname with shared parts", network: "us.irc.example.com", want: "s4"},
+		{name: "Ambiguous", network: "example", wantErr: true},
+		{name: "Unknown", network: "efnet", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := findImportServer(servers, tt.network)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got.ID)
+		})
+	}
+}
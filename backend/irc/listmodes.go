@@ -0,0 +1,145 @@
+package irc
+
+import (
+	"slices"
+	"strings"
+	"time"
+)
+
+const (
+	// rplQuietList and rplEndOfQuietList are sent by networks that have a quiet list, they include the mode before
+	// the mask
+	rplQuietList      = "728"
+	rplEndOfQuietList = "729"
+)
+
+// ListMode is a channel mode that holds a list of masks, such as bans
+type ListMode struct {
+	Mode string
+	Name string
+}
+
+// listModes are the list modes that can be viewed, quiets are only supported by some networks
+var listModes = []ListMode{
+	{Mode: "b", Name: "Bans"},
+	{Mode: "e", Name: "Exceptions"},
+	{Mode: "I", Name: "Invites"},
+	{Mode: "q", Name: "Quiets"},
+}
+
+// ListEntry is a single mask on one of a channel's lists, the setter and time are only known if the server sends them
+type ListEntry struct {
+	Mask  string
+	SetBy string
+	SetAt time.Time
+}
+
+// GetSetAt returns when the entry was set, formatted for display, or an empty string if it isn't known
+func (e *ListEntry) GetSetAt() string {
+	if e.SetAt.IsZero() {
+		return ""
+	}
+	return e.SetAt.Local().Format(time.DateTime)
+}
+
+// GetListModes returns the list modes the network supports
+func (c *Server) GetListModes() []ListMode {
+	var supported []ListMode
+	for i := range listModes {
+		if c.GetChannelModeType(listModes[i].Mode) == 'A' {
+			supported = append(supported, listModes[i])
+		}
+	}
+	return supported
+}
+
+// RequestList asks the server for the entries on one of a channel's lists, they replace the stored list once the
+// server has sent them all
+func (c *Server) RequestList(channel string, mode string) error {
+	window, err := c.GetChannelByName(channel)
+	if err != nil {
+		return err
+	}
+	window.startList(mode)
+	return c.send(PriorityInteractive, "MODE", channel, "+"+mode)
+}
+
+// GetList returns the entries on one of the channel's lists, and whether the list has been received from the server
+func (c *Channel) GetList(mode string) ([]*ListEntry, bool) {
+	c.listLock.Lock()
+	defer c.listLock.Unlock()
+	entries, loaded := c.lists[mode]
+	return slices.Clone(entries), loaded
+}
+
+// IsListLoading checks if the channel is waiting for the server to send a list
+func (c *Channel) IsListLoading(mode string) bool {
+	c.listLock.Lock()
+	defer c.listLock.Unlock()
+	_, loading := c.pendingLists[mode]
+	return loading
+}
+
+// GetListEntriesBefore returns the masks on a list that were set before the given time, entries without a time
+// aren't included
+func (c *Channel) GetListEntriesBefore(mode string, before time.Time) []string {
+	c.listLock.Lock()
+	defer c.listLock.Unlock()
+	var masks []string
+	for _, entry := range c.lists[mode] {
+		if !entry.SetAt.IsZero() && entry.SetAt.Before(before) {
+			masks = append(masks, entry.Mask)
+		}
+	}
+	return masks
+}
+
+func (c *Channel) startList(mode string) {
+	c.listLock.Lock()
+	defer c.listLock.Unlock()
+	if c.pendingLists == nil {
+		c.pendingLists = map[string][]*ListEntry{}
+	}
+	c.pendingLists[mode] = []*ListEntry{}
+}
+
+// addPendingListEntry adds an entry received from the server to the list being built
+func (c *Channel) addPendingListEntry(mode string, entry *ListEntry) {
+	c.listLock.Lock()
+	defer c.listLock.Unlock()
+	if c.pendingLists == nil {
+		c.pendingLists = map[string][]*ListEntry{}
+	}
+	c.pendingLists[mode] = append(c.pendingLists[mode], entry)
+}
+
+// endList replaces the stored list with the entries received from the server
+func (c *Channel) endList(mode string) {
+	c.listLock.Lock()
+	defer c.listLock.Unlock()
+	if c.lists == nil {
+		c.lists = map[string][]*ListEntry{}
+	}
+	c.lists[mode] = c.pendingLists[mode]
+	if c.lists[mode] == nil {
+		c.lists[mode] = []*ListEntry{}
+	}
+	delete(c.pendingLists, mode)
+}
+
+// setListEntry updates a list that has already been received when a mode change adds or removes a mask
+func (c *Channel) setListEntry(mode string, entry *ListEntry, set bool) {
+	c.listLock.Lock()
+	defer c.listLock.Unlock()
+	entries, loaded := c.lists[mode]
+	if !loaded {
+		return
+	}
+	entries = slices.DeleteFunc(entries, func(existing *ListEntry) bool {
+		return strings.EqualFold(existing.Mask, entry.Mask)
+	})
+	if set {
+		entries = append(entries, entry)
+	}
+	c.lists[mode] = entries
+}
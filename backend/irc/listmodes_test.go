@@ -0,0 +1,41 @@
+package irc
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestChannel_setListEntry(t *testing.T) {
+	channel := &Channel{Window: &Window{name: "#test"}}
+	channel.setListEntry("b", &ListEntry{Mask: "*!*@a.example.com"}, true)
+	_, loaded := channel.GetList("b")
+	assert.False(t, loaded, "mode changes shouldn't create a list that hasn't been fetched")
+
+	channel.endList("b")
+	channel.setListEntry("b", &ListEntry{Mask: "*!*@a.example.com"}, true)
+	channel.setListEntry("b", &ListEntry{Mask: "*!*@b.example.com"}, true)
+	channel.setListEntry("b", &ListEntry{Mask: "*!*@A.example.com", SetBy: "op"}, true)
+	channel.setListEntry("b", &ListEntry{Mask: "*!*@b.example.com"}, false)
+	entries, loaded := channel.GetList("b")
+	assert.True(t, loaded)
+	assert.Equal(t, []*ListEntry{{Mask: "*!*@A.example.com", SetBy: "op"}}, entries)
+}
+
+func TestChannel_GetListEntriesBefore(t *testing.T) {
+	now := time.Date(2025, 3, 10, 0, 0, 0, 0, time.UTC)
+	channel := &Channel{Window: &Window{name: "#test"}}
+	channel.addPendingListEntry("b", &ListEntry{Mask: "old", SetAt: now.AddDate(0, 0, -30)})
+	channel.addPendingListEntry("b", &ListEntry{Mask: "new", SetAt: now.AddDate(0, 0, -1)})
+	channel.addPendingListEntry("b", &ListEntry{Mask: "unknown"})
+	channel.endList("b")
+	assert.Equal(t, []string{"old"}, channel.GetListEntriesBefore("b", now.AddDate(0, 0, -7)))
+	assert.Empty(t, channel.GetListEntriesBefore("e", now))
+}
+
+func TestListEntry_GetSetAt(t *testing.T) {
+	assert.Empty(t, (&ListEntry{}).GetSetAt())
+	when := time.Date(2025, 3, 1, 10, 0, 0, 0, time.Local)
+	assert.Equal(t, "2025-03-01 10:00:00", (&ListEntry{SetAt: when}).GetSetAt())
+}
@@ -0,0 +1,228 @@
+package irc
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/greboid/tithon/config"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// serverLogName is used in place of a channel name for the server window, it can't clash with a channel or a nickname
+const serverLogName = "(server)"
+
+type logFile struct {
+	path string
+	file *os.File
+}
+
+// MessageLogger writes the messages added to windows to text files laid out as network/channel/YYYY-MM-DD.log, a
+// new file is started each day
+type MessageLogger struct {
+	mutex    sync.Mutex
+	settings config.Logging
+	files    map[*Window]*logFile
+}
+
+func NewMessageLogger(settings config.Logging) *MessageLogger {
+	return &MessageLogger{
+		settings: settings,
+		files:    map[*Window]*logFile{},
+	}
+}
+
+// Log writes a message to the log for the window, history replayed by the server is skipped as it will already
+// have been logged, as are windows with logging turned off in their settings
+func (ml *MessageLogger) Log(window *Window, message *Message) {
+	if ml == nil || message.GetTags()["chathistory"] == "true" || window.GetSettings().NoLog {
+		return
+	}
+	ml.mutex.Lock()
+	defer ml.mutex.Unlock()
+	if !ml.settings.Enabled {
+		return
+	}
+	line, err := formatLogLine(ml.settings.Format, message)
+	if err != nil {
+		slog.Error("Unable to format log line", "error", err)
+		return
+	}
+	file, err := ml.getFile(window, message.GetTime())
+	if err != nil {
+		slog.Error("Unable to open log file", "error", err)
+		return
+	}
+	if _, err = file.WriteString(line + "\n"); err != nil {
+		slog.Error("Unable to write to log file", "error", err)
+	}
+}
+
+// CloseWindow closes the log file for a window that has been removed
+func (ml *MessageLogger) CloseWindow(window *Window) {
+	if ml == nil {
+		return
+	}
+	ml.mutex.Lock()
+	defer ml.mutex.Unlock()
+	if log, ok := ml.files[window]; ok {
+		_ = log.file.Close()
+		delete(ml.files, window)
+	}
+}
+
+// Close closes all open log files
+func (ml *MessageLogger) Close() {
+	if ml == nil {
+		return
+	}
+	ml.mutex.Lock()
+	defer ml.mutex.Unlock()
+	ml.closeAll()
+}
+
+func (ml *MessageLogger) closeAll() {
+	for window, log := range ml.files {
+		_ = log.file.Close()
+		delete(ml.files, window)
+	}
+}
+
+// getFile returns the log file for the window on the day of the message, rotating the file when the day changes
+func (ml *MessageLogger) getFile(window *Window, when time.Time) (*os.File, error) {
+	path := ml.logPath(window, when)
+	if log, ok := ml.files[window]; ok {
+		if log.path == path {
+			return log.file, nil
+		}
+		_ = log.file.Close()
+		delete(ml.files, window)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return nil, err
+	}
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		return nil, err
+	}
+	ml.files[window] = &logFile{path: path, file: file}
+	return file, nil
+}
+
+func (ml *MessageLogger) logPath(window *Window, when time.Time) string {
+	network := window.GetName()
+	name := serverLogName
+	if server := window.GetServer(); server != nil && !window.IsServer() {
+		network = server.GetName()
+		name = strings.ToLower(window.GetName())
+	}
+	return filepath.Join(
+		ml.settings.GetDirectory(),
+		sanitiseLogName(network),
+		sanitiseLogName(name),
+		when.In(time.Local).Format(time.DateOnly)+".log",
+	)
+}
+
+// sanitiseLogName replaces characters that aren't allowed in file names on common platforms
+func sanitiseLogName(name string) string {
+	name = strings.Map(func(r rune) rune {
+		if r < 32 || strings.ContainsRune(`<>:"/\|?*`, r) {
+			return '_'
+		}
+		return r
+	}, name)
+	if name == "" || strings.Trim(name, ".") == "" {
+		return "_" + name
+	}
+	return name
+}
+
+type jsonLogLine struct {
+	Time      time.Time         `json:"time"`
+	Type      string            `json:"type"`
+	Nick      string            `json:"nick,omitempty"`
+	Message   string            `json:"message"`
+	Me        bool              `json:"me,omitempty"`
+	Highlight bool              `json:"highlight,omitempty"`
+	Tags      map[string]string `json:"tags,omitempty"`
+}
+
+func formatLogLine(format string, message *Message) (string, error) {
+	text := message.getPlainMessage()
+	nick := message.GetNickname()
+	when := message.GetTime().In(time.Local)
+	switch format {
+	case config.LogFormatJSONL:
+		line, err := json.Marshal(jsonLogLine{
+			Time:      message.GetTime(),
+			Type:      logMessageType(message),
+			Nick:      nick,
+			Message:   text,
+			Me:        message.IsMe(),
+			Highlight: isHighlightType(message.GetType()),
+			Tags:      message.GetTags(),
+		})
+		return string(line), err
+	case config.LogFormatWeechat:
+		var prefix string
+		switch logMessageType(message) {
+		case "action":
+			prefix, text = " *", nick+" "+text
+		case "notice":
+			prefix, text = "--", "Notice("+nick+"): "+text
+		case "event":
+			prefix = "--"
+		case "error":
+			prefix = "=!="
+		default:
+			prefix = nick
+		}
+		return fmt.Sprintf("%s\t%s\t%s", when.Format(time.DateTime), prefix, text), nil
+	case config.LogFormatIrssi, "":
+		timestamp := when.Format("15:04")
+		switch logMessageType(message) {
+		case "action":
+			return fmt.Sprintf("%s  * %s %s", timestamp, nick, text), nil
+		case "notice":
+			return fmt.Sprintf("%s -%s- %s", timestamp, nick, text), nil
+		case "event", "error":
+			return fmt.Sprintf("%s -!- %s", timestamp, text), nil
+		default:
+			return fmt.Sprintf("%s <%s> %s", timestamp, nick, text), nil
+		}
+	default:
+		return "", fmt.Errorf("unknown log format: %s", format)
+	}
+}
+
+func logMessageType(message *Message) string {
+	switch message.GetType() {
+	case Action, HighlightAction:
+		return "action"
+	case Notice, HighlightNotice:
+		return "notice"
+	case Event:
+		return "event"
+	case Error:
+		return "error"
+	default:
+		return "message"
+	}
+}
+
+func isHighlightType(messageType MessageType) bool {
+	switch messageType {
+	case Highlight, HighlightAction, HighlightNotice:
+		return true
+	default:
+		return false
+	}
+}
+
+func (c *Server) SetMessageLogger(logger *MessageLogger) {
+	c.messageLogger = logger
+}
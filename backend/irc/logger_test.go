@@ -0,0 +1,138 @@
+package irc
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/greboid/tithon/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func logTestMessage(messageType MessageType, at time.Time) *Message {
+	tags := map[string]string{"time": at.UTC().Format(v3TimestampFormat)}
+	switch messageType {
+	case Action:
+		return NewMessage("15:04:05", false, "alice", "\001ACTION waves\001", tags, nil)
+	case Notice:
+		return NewNotice("15:04:05", false, "alice", "hello", tags, nil)
+	case Event:
+		message := NewEvent(EventJoin, "15:04:05", false, "alice has joined #test")
+		message.timestamp = at
+		return message
+	case Highlight:
+		return NewMessage("15:04:05", false, "alice", "hi bob", tags, HighlightWords{"bob"})
+	default:
+		return NewMessage("15:04:05", false, "alice", "hello <world>", tags, nil)
+	}
+}
+
+func TestFormatLogLine(t *testing.T) {
+	at := time.Date(2025, 3, 4, 5, 6, 7, 0, time.Local)
+	tests := []struct {
+		name        string
+		format      string
+		messageType MessageType
+		want        string
+	}{
+		{name: "irssi message", format: config.LogFormatIrssi, messageType: Normal, want: "05:06 <alice> hello <world>"},
+		{name: "irssi action", format: config.LogFormatIrssi, messageType: Action, want: "05:06  * alice waves"},
+		{name: "irssi notice", format: config.LogFormatIrssi, messageType: Notice, want: "05:06 -alice- hello"},
+		{name: "irssi event", format: config.LogFormatIrssi, messageType: Event, want: "05:06 -!- alice has joined #test"},
+		{name: "weechat message", format: config.LogFormatWeechat, messageType: Normal, want: "2025-03-04 05:06:07\talice\thello <world>"},
+		{name: "weechat action", format: config.LogFormatWeechat, messageType: Action, want: "2025-03-04 05:06:07\t *\talice waves"},
+		{name: "weechat notice", format: config.LogFormatWeechat, messageType: Notice, want: "2025-03-04 05:06:07\t--\tNotice(alice): hello"},
+		{name: "weechat event", format: config.LogFormatWeechat, messageType: Event, want: "2025-03-04 05:06:07\t--\talice has joined #test"},
+		{
+			name:        "jsonl highlight",
+			format:      config.LogFormatJSONL,
+			messageType: Highlight,
+			want:        `{"time":"` + at.Format(time.RFC3339) + `","type":"message","nick":"alice","message":"hi bob","highlight":true,"tags":{"time":"2025-03-04T` + at.UTC().Format("15:04:05") + `.000Z"}}`,
+		},
+		{
+			name:        "jsonl event",
+			format:      config.LogFormatJSONL,
+			messageType: Event,
+			want:        `{"time":"` + at.Format(time.RFC3339) + `","type":"event","message":"alice has joined #test"}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := formatLogLine(tt.format, logTestMessage(tt.messageType, at))
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestFormatLogLine_UnknownFormat(t *testing.T) {
+	_, err := formatLogLine("mirc", logTestMessage(Normal, time.Now()))
+	assert.Error(t, err)
+}
+
+func TestMessageLogger_Log(t *testing.T) {
+	dir := t.TempDir()
+	logger := NewMessageLogger(config.Logging{Enabled: true, Directory: dir, Format: config.LogFormatIrssi})
+	server := &Server{Window: &Window{id: "libera", name: "Libera.Chat", isServer: true}, messageLogger: logger}
+	server.Window.connection = server
+	channel := &Window{id: "c1", name: "#Tithon", connection: server, isChannel: true}
+	query := &Window{id: "q1", name: "some/nick", connection: server, isQuery: true}
+
+	day1 := time.Date(2025, 3, 4, 12, 0, 0, 0, time.Local)
+	day2 := day1.AddDate(0, 0, 1)
+	channel.AddMessage(logTestMessage(Normal, day1))
+	channel.AddMessage(logTestMessage(Event, day1))
+	channel.AddMessage(logTestMessage(Normal, day2))
+	query.AddMessage(logTestMessage(Notice, day1))
+	server.Window.AddMessage(logTestMessage(Notice, day1))
+	history := logTestMessage(Normal, day1)
+	history.tags["chathistory"] = "true"
+	channel.AddMessage(history)
+	logger.Close()
+
+	read := func(path ...string) string {
+		data, err := os.ReadFile(filepath.Join(append([]string{dir}, path...)...))
+		require.NoError(t, err)
+		return string(data)
+	}
+	assert.Equal(t, "12:00 <alice> hello <world>\n12:00 -!- alice has joined #test\n", read("Libera.Chat", "#tithon", "2025-03-04.log"))
+	assert.Equal(t, "12:00 <alice> hello <world>\n", read("Libera.Chat", "#tithon", "2025-03-05.log"))
+	assert.Equal(t, "12:00 -alice- hello\n", read("Libera.Chat", "some_nick", "2025-03-04.log"))
+	assert.Equal(t, "12:00 -alice- hello\n", read("Libera.Chat", serverLogName, "2025-03-04.log"))
+}
+
+func TestMessageLogger_Disabled(t *testing.T) {
+	dir := t.TempDir()
+	logger := NewMessageLogger(config.Logging{Directory: dir})
+	server := &Server{Window: &Window{id: "libera", name: "Libera.Chat"}, messageLogger: logger}
+	channel := &Window{id: "c1", name: "#tithon", connection: server, isChannel: true}
+	channel.AddMessage(logTestMessage(Normal, time.Now()))
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	assert.Empty(t, entries)
+}
+
+func TestMessageLogger_WindowDisabled(t *testing.T) {
+	dir := t.TempDir()
+	logger := NewMessageLogger(config.Logging{Enabled: true, Directory: dir, Format: config.LogFormatIrssi})
+	server := &Server{Window: &Window{id: "libera", name: "Libera.Chat", isServer: true}, messageLogger: logger}
+	server.Window.connection = server
+	server.SetWindowSettings(NewWindowSettingsList([]config.WindowSettings{{Network: "libera", Window: "#secret", NoLog: true}}, nil))
+	secret := &Window{id: "c1", name: "#Secret", connection: server, isChannel: true}
+	channel := &Window{id: "c2", name: "#tithon", connection: server, isChannel: true}
+	secret.AddMessage(logTestMessage(Normal, time.Now()))
+	channel.AddMessage(logTestMessage(Normal, time.Now()))
+	logger.Close()
+
+	assert.NoDirExists(t, filepath.Join(dir, "Libera.Chat", "#secret"))
+	assert.DirExists(t, filepath.Join(dir, "Libera.Chat", "#tithon"))
+}
+
+func TestSanitiseLogName(t *testing.T) {
+	assert.Equal(t, "#tithon", sanitiseLogName("#tithon"))
+	assert.Equal(t, "a_b_c", sanitiseLogName("a/b\\c"))
+	assert.Equal(t, "_..", sanitiseLogName(".."))
+	assert.Equal(t, "_", sanitiseLogName(""))
+}
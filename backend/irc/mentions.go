@@ -0,0 +1,20 @@
+package irc
+
+// MentionsID is the ID of the mentions window, it is used in place of a server ID when linking to the window
+const MentionsID = "mentions"
+
+// NewMentionsWindow creates a window that isn't attached to a server, highlights and private messages from every
+// server are added to it as well as the window they were sent to
+func NewMentionsWindow() *Window {
+	return &Window{
+		id:         MentionsID,
+		name:       "Mentions",
+		title:      "Highlights and private messages from all servers",
+		messages:   make([]*Message, 0),
+		isMentions: true,
+	}
+}
+
+func (c *Server) SetMentions(mentions *Window) {
+	c.mentions = mentions
+}
@@ -0,0 +1,99 @@
+package irc
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWindow_AddMessage_Mentions(t *testing.T) {
+	highlighter := HighlightWords{"testnick"}
+	tests := []struct {
+		name    string
+		query   bool
+		message *Message
+		want    bool
+	}{
+		{
+			name:    "Highlight in channel",
+			message: NewMessage("15:04:05", false, "someone", "hi testnick", nil, highlighter),
+			want:    true,
+		},
+		{
+			name:    "Normal message in channel",
+			message: NewMessage("15:04:05", false, "someone", "hi everyone", nil, highlighter),
+			want:    false,
+		},
+		{
+			name:    "Private message",
+			query:   true,
+			message: NewMessage("15:04:05", false, "someone", "hi", nil, highlighter),
+			want:    true,
+		},
+		{
+			name:    "Private notice",
+			query:   true,
+			message: NewNotice("15:04:05", false, "someone", "hi", nil, highlighter),
+			want:    true,
+		},
+		{
+			name:    "Own private message",
+			query:   true,
+			message: NewMessage("15:04:05", true, "testnick", "hi", nil, nil),
+			want:    false,
+		},
+		{
+			name:    "Event in query",
+			query:   true,
+			message: NewEvent(EventNick, "15:04:05", false, "someone is now known as other"),
+			want:    false,
+		},
+		{
+			name:    "Highlight from history",
+			message: NewMessage("15:04:05", false, "someone", "hi testnick", map[string]string{"chathistory": "true"}, highlighter),
+			want:    false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mentions := NewMentionsWindow()
+			server := &Server{mentions: mentions}
+			window := &Window{id: "window", isQuery: tt.query, isChannel: !tt.query, connection: server}
+
+			window.AddMessage(tt.message)
+
+			assert.Equal(t, []*Message{tt.message}, window.GetMessages())
+			assert.Equal(t, window, tt.message.GetWindow())
+			if tt.want {
+				assert.Equal(t, []*Message{tt.message}, mentions.GetMessages())
+				assert.Equal(t, 1, mentions.GetUnreadCount())
+			} else {
+				assert.Empty(t, mentions.GetMessages())
+				assert.Equal(t, 0, mentions.GetUnreadCount())
+			}
+		})
+	}
+}
+
+func TestWindow_GetUnreadCount(t *testing.T) {
+	window := NewMentionsWindow()
+	window.AddMessage(NewMessage("15:04:05", false, "someone", "one", nil, nil))
+	window.AddMessage(NewMessage("15:04:05", false, "someone", "two", nil, nil))
+	assert.Equal(t, 2, window.GetUnreadCount())
+
+	window.SetActive(true)
+	assert.Equal(t, 0, window.GetUnreadCount())
+	window.AddMessage(NewMessage("15:04:05", false, "someone", "three", nil, nil))
+	assert.Equal(t, 0, window.GetUnreadCount())
+
+	window.SetActive(false)
+	window.AddMessage(NewMessage("15:04:05", false, "someone", "four", nil, nil))
+	assert.Equal(t, 1, window.GetUnreadCount())
+}
+
+func TestMessage_GetID(t *testing.T) {
+	first := NewMessage("15:04:05", false, "someone", "one", nil, nil)
+	second := NewMessage("15:04:05", false, "someone", "one", nil, nil)
+	assert.NotEmpty(t, first.GetID())
+	assert.NotEqual(t, first.GetID(), second.GetID())
+}
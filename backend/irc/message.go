@@ -1,30 +1,72 @@
 package irc
 
 import (
-	"bytes"
 	"fmt"
 	"github.com/ergochat/irc-go/ircfmt"
-	"golang.org/x/net/html"
 	"log/slog"
 	"regexp"
+	"slices"
 	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 )
 
 const (
 	v3TimestampFormat = "2006-01-02T15:04:05.000Z"
-	HyperlinkCode     = "\x05"
 )
 
+// messageCount is used to give every message a unique ID
+var messageCount atomic.Uint64
+
 type MessageType int
 type EventType int
 
-type Link struct {
-	Start int
-	End   int
-	Text  string
-	Link  string
+// Span is a run of text in a message with the same formatting
+type Span struct {
+	ircfmt.FormattedSubstring
+	// Link is the URL the text links to, if any
+	Link string
+	// Mention is set when the text caused the message to be highlighted
+	Mention bool
+	// Nick is the nickname of a known user the text refers to, if any
+	Nick string
+	// Channel is the name of the channel the text refers to, if any
+	Channel string
+}
+
+// GetClasses returns the CSS classes used to display the span
+func (s Span) GetClasses() string {
+	var classes []string
+	if s.ForegroundColor.IsSet {
+		classes = append(classes, fmt.Sprintf("fg-%d", s.ForegroundColor.Value))
+	}
+	if s.BackgroundColor.IsSet {
+		classes = append(classes, fmt.Sprintf("bg-%d", s.BackgroundColor.Value))
+	}
+	if s.Bold {
+		classes = append(classes, "bold")
+	}
+	if s.Monospace {
+		classes = append(classes, "monospace")
+	}
+	if s.Strikethrough {
+		classes = append(classes, "strikethrough")
+	}
+	if s.Underline {
+		classes = append(classes, "underline")
+	}
+	if s.Italic {
+		classes = append(classes, "italic")
+	}
+	if s.ReverseColor {
+		classes = append(classes, "reverseColour")
+	}
+	if s.Mention {
+		classes = append(classes, "mention")
+	}
+	return strings.Join(classes, " ")
 }
 
 const (
@@ -51,45 +93,74 @@ const (
 	EventDisconnected
 	EventWhois
 	EventHelp
+	EventWho
 )
 
 type Message struct {
+	id              string
+	window          *Window
 	timestamp       time.Time
 	nickname        string
 	message         string
+	plain           string
+	ranges          spanRanges
+	spans           []Span
+	previewLock     sync.Mutex
+	previews        []*LinkPreview
+	group           *EventGroup
 	messageType     MessageType
-	highlights      []string
+	eventType       EventType
+	subject         string
+	highlighter     Highlighter
 	me              bool
 	timestampFormat string
 	tags            map[string]string
 	nowFunc         func() time.Time
 }
 
-func NewNotice(timeFormat string, me bool, nickname string, message string, tags map[string]string, highlights ...string) *Message {
-	return newMessage(timeFormat, me, nickname, message, Notice, tags, highlights)
+func NewNotice(timeFormat string, me bool, nickname string, message string, tags map[string]string, highlighter Highlighter) *Message {
+	return newMessage(timeFormat, me, nickname, message, Notice, tags, highlighter)
 }
 
 func NewEvent(eventType EventType, timeFormat string, me bool, message string) *Message {
-	return newMessage(timeFormat, me, "", message, Event, nil, nil)
+	m := newMessage(timeFormat, me, "", message, Event, nil, nil)
+	m.eventType = eventType
+	return m
+}
+
+// NewMembershipEvent creates a join, part or quit event for another user, which can be hidden by the event filter
+func NewMembershipEvent(eventType EventType, timeFormat string, nickname string, message string) *Message {
+	m := NewEvent(eventType, timeFormat, false, message)
+	m.subject = nickname
+	return m
+}
+
+// NewGroupEvent creates an event that summarises several users, such as those who quit in a netsplit, more users can be
+// added to the group after the message has been shown
+func NewGroupEvent(eventType EventType, timeFormat string, message string, group *EventGroup) *Message {
+	m := NewEvent(eventType, timeFormat, false, message)
+	m.group = group
+	return m
 }
 
 func NewError(timeFormat string, me bool, message string) *Message {
 	return newMessage(timeFormat, me, "", message, Error, nil, nil)
 }
 
-func NewMessage(timeFormat string, me bool, nickname string, message string, tags map[string]string, highlights ...string) *Message {
-	return newMessage(timeFormat, me, nickname, message, Normal, tags, highlights)
+func NewMessage(timeFormat string, me bool, nickname string, message string, tags map[string]string, highlighter Highlighter) *Message {
+	return newMessage(timeFormat, me, nickname, message, Normal, tags, highlighter)
 }
 
-func newMessage(timeFormat string, me bool, nickname string, message string, messageType MessageType, tags map[string]string, highlights []string) *Message {
+func newMessage(timeFormat string, me bool, nickname string, message string, messageType MessageType, tags map[string]string, highlighter Highlighter) *Message {
 	if tags == nil {
 		tags = make(map[string]string)
 	}
 	m := &Message{
+		id:              strconv.FormatUint(messageCount.Add(1), 10),
 		nickname:        nickname,
 		message:         message,
 		messageType:     messageType,
-		highlights:      highlights,
+		highlighter:     highlighter,
 		me:              me,
 		timestampFormat: timeFormat,
 		tags:            tags,
@@ -116,8 +187,9 @@ func (m *Message) parseTime() {
 func (m *Message) parse() *Message {
 	m.parseTime()
 	m.parseAction()
+	m.plain = ircfmt.Strip(m.message)
 	m.parseHighlight()
-	m.parseFormatting()
+	m.parseSpans()
 	return m
 }
 
@@ -171,39 +243,34 @@ func (m *Message) GetTypeDisplay() string {
 	}
 }
 
+// GetMessage returns the text of the message as it was received, including any IRC formatting codes
 func (m *Message) GetMessage() string {
 	return m.message
 }
 
-func (m *Message) GetDisplayMessage() string {
+// GetSpans returns the text of the message split into runs with the same formatting, used to display the message
+func (m *Message) GetSpans() []Span {
+	return m.spans
+}
+
+// GetInlineNickname returns the nickname shown at the start of the message text rather than next to it
+func (m *Message) GetInlineNickname() string {
 	if m.messageType == Action {
-		return fmt.Sprintf(`<span class="%s">%s</span> %s`, m.GetNameColour(), m.nickname, m.message)
+		return m.nickname
 	}
-	return m.message
+	return ""
 }
 
 func (m *Message) GetPlainDisplayMessage() string {
-	// TODO: This is awful, I should store the message before I add HTML to it
-	node, err := html.Parse(strings.NewReader(m.GetDisplayMessage()))
-	if err != nil {
-		slog.Error("Error parsing message", "message", m, "error", err)
-		return m.GetDisplayMessage()
-	}
-	var stripper func(node *html.Node, buf *bytes.Buffer)
-	stripper = func(node *html.Node, buf *bytes.Buffer) {
-		if node.Type == html.TextNode {
-			buf.WriteString(node.Data)
-		}
-		for child := node.FirstChild; child != nil; child = child.NextSibling {
-			stripper(child, buf)
-		}
-	}
-	stripped := &bytes.Buffer{}
 	if m.messageType == Action {
-		stripped.WriteString("* ")
+		return "* " + m.nickname + " " + m.plain
 	}
-	stripper(node, stripped)
-	return stripped.String()
+	return m.plain
+}
+
+// getPlainMessage returns the message text without the nickname and with all formatting removed
+func (m *Message) getPlainMessage() string {
+	return m.plain
 }
 
 func (m *Message) GetNickname() string {
@@ -233,6 +300,77 @@ func (m *Message) GetTimestamp() string {
 	return m.timestamp.Format(m.timestampFormat)
 }
 
+func (m *Message) GetTime() time.Time {
+	return m.timestamp
+}
+
+// GetID returns an identifier for the message that is unique for the lifetime of the client
+func (m *Message) GetID() string {
+	return m.id
+}
+
+// GetLinks returns the URLs of each link in the message, in the order they appear
+func (m *Message) GetLinks() []string {
+	var links []string
+	for i := range m.spans {
+		if m.spans[i].Link != "" && !slices.Contains(links, m.spans[i].Link) {
+			links = append(links, m.spans[i].Link)
+		}
+	}
+	return links
+}
+
+// isMembership checks if the message is another user joining, parting or quitting, netsplits are grouped events rather
+// than membership events so they're always shown
+func (m *Message) isMembership() bool {
+	if m.messageType != Event || m.me || m.subject == "" {
+		return false
+	}
+	return m.eventType == EventJoin || m.eventType == EventPart || m.eventType == EventQuit
+}
+
+// GetGroup returns the users summarised by the event, or nil if it isn't a group event
+func (m *Message) GetGroup() *EventGroup {
+	return m.group
+}
+
+// GetPreviews returns the previews fetched for links in the message, in the same order as the links
+func (m *Message) GetPreviews() []*LinkPreview {
+	m.previewLock.Lock()
+	defer m.previewLock.Unlock()
+	var previews []*LinkPreview
+	for i := range m.previews {
+		if m.previews[i] != nil {
+			previews = append(previews, m.previews[i])
+		}
+	}
+	return previews
+}
+
+// setPreview stores the preview for the link at index, previews can arrive in any order
+func (m *Message) setPreview(index int, count int, preview *LinkPreview) {
+	m.previewLock.Lock()
+	defer m.previewLock.Unlock()
+	if m.previews == nil {
+		m.previews = make([]*LinkPreview, count)
+	}
+	m.previews[index] = preview
+}
+
+// GetServerID returns the ID of the server the message was received on, or an empty string if it wasn't added to a
+// server's window
+func (m *Message) GetServerID() string {
+	if m.window == nil || m.window.GetServer() == nil {
+		return ""
+	}
+	return m.window.GetServer().GetID()
+}
+
+// GetWindow returns the window the message was originally added to
+func (m *Message) GetWindow() *Window {
+	return m.window
+}
+
 func (m *Message) GetTags() map[string]string {
 	return m.tags
 }
@@ -242,96 +380,136 @@ func (m *Message) IsMe() bool {
 }
 
 func (m *Message) isHighlight() bool {
-	for i := range m.highlights {
-		if strings.Contains(strings.ToLower(m.message), strings.ToLower(m.highlights[i])) {
-			return true
-		}
-	}
-	return false
+	return m.highlighter != nil && m.highlighter.IsHighlight(m.nickname, m.plain)
+}
+
+// spanRanges are the start and end of the parts of the plain text that are links, mentions, nicknames or channels
+type spanRanges struct {
+	links    [][]int
+	mentions [][]int
+	nicks    [][]int
+	channels [][]int
 }
 
-func (m *Message) parseFormatting() {
+// parseSpans splits the message into runs of text with the same formatting, links and mentions.  Links aren't
+// added to events or errors.
+func (m *Message) parseSpans() {
 	if m.messageType != Event && m.messageType != Error {
-		m.message = m.GetLinks(m.message)
-		m.message = html.EscapeString(m.message)
-		m.message = m.ReplaceLinks(m.message)
-	} else {
-		m.message = html.EscapeString(m.message)
-	}
-	m.parseIRCFormatting()
-}
-
-func (m *Message) parseIRCFormatting() {
-	split := ircfmt.Split(m.message)
-	var out strings.Builder
-	for i := range split {
-		var classes []string
-		if split[i].ForegroundColor.IsSet {
-			classes = append(classes, fmt.Sprintf("fg-%d", split[i].ForegroundColor.Value))
-		}
-		if split[i].BackgroundColor.IsSet {
-			classes = append(classes, fmt.Sprintf("bg-%d", split[i].BackgroundColor.Value))
-		}
-		if split[i].Bold {
-			classes = append(classes, "bold")
-		}
-		if split[i].Monospace {
-			classes = append(classes, "monospace")
-		}
-		if split[i].Strikethrough {
-			classes = append(classes, "strikethrough")
-		}
-		if split[i].Underline {
-			classes = append(classes, "underline")
-		}
-		if split[i].Italic {
-			classes = append(classes, "italic")
-		}
-		if split[i].ReverseColor {
-			classes = append(classes, "reverseColour")
+		m.ranges.links = linkRegex.FindAllStringIndex(m.plain, -1)
+	}
+	if isHighlightType(m.messageType) {
+		m.ranges.mentions = m.highlighter.Highlights(m.nickname, m.plain)
+	}
+	m.buildSpans()
+}
+
+// nickRegex matches runs of characters that are allowed in nicknames
+var nickRegex = regexp.MustCompile(`[\p{L}\p{N}\[\]\\` + "`" + `_^{|}-]+`)
+
+// parseTargets marks the nicknames of known users and the names of channels in the message so they can be clicked,
+// anything that's part of a link is skipped
+func (m *Message) parseTargets(nicknames []string, chanTypes string) {
+	known := make(map[string]bool, len(nicknames))
+	for i := range nicknames {
+		known[strings.ToLower(nicknames[i])] = true
+	}
+	m.ranges.nicks = nil
+	m.ranges.channels = nil
+	for _, match := range nickRegex.FindAllStringIndex(m.plain, -1) {
+		if known[strings.ToLower(m.plain[match[0]:match[1]])] && !overlaps(m.ranges.links, match) {
+			m.ranges.nicks = append(m.ranges.nicks, match)
 		}
-		if len(classes) > 0 {
-			out.WriteString(`<span class="`)
-			out.WriteString(strings.Join(classes, " "))
-			out.WriteString(`">`)
+	}
+	if chanTypes != "" {
+		for _, match := range channelRegex(chanTypes).FindAllStringSubmatchIndex(m.plain, -1) {
+			name := match[2:4]
+			name[1] -= len(m.plain[name[0]:name[1]]) - len(strings.TrimRight(m.plain[name[0]:name[1]], channelTrailingPunctuation))
+			if name[1]-name[0] > 1 && !overlaps(m.ranges.links, name) && !overlaps(m.ranges.nicks, name) {
+				m.ranges.channels = append(m.ranges.channels, name)
+			}
 		}
-		out.WriteString(split[i].Content)
-		if len(classes) > 0 {
-			out.WriteString("</span>")
+	}
+	m.buildSpans()
+}
+
+// channelTrailingPunctuation is removed from the end of channel names, as it's more likely to end the sentence
+const channelTrailingPunctuation = `.,!?:;)'"`
+
+// channelRegex matches a channel name at the start of the message or after a space or bracket, the name is captured
+// by the first group
+func channelRegex(chanTypes string) *regexp.Regexp {
+	if cached, ok := channelRegexes.Load(chanTypes); ok {
+		return cached.(*regexp.Regexp)
+	}
+	compiled := regexp.MustCompile(`(?:^|[\s(])([` + regexp.QuoteMeta(chanTypes) + `][^\s,\x07]*)`)
+	channelRegexes.Store(chanTypes, compiled)
+	return compiled
+}
+
+// channelRegexes caches the compiled channelRegex for each CHANTYPES value
+var channelRegexes sync.Map
+
+// overlaps checks if any of the ranges overlap the match
+func overlaps(ranges [][]int, match []int) bool {
+	for i := range ranges {
+		if ranges[i][0] < match[1] && match[0] < ranges[i][1] {
+			return true
 		}
 	}
-	m.message = out.String()
+	return false
 }
 
-func (m *Message) GetLinks(input string) string {
-	var result []Link
-	urls := linkRegex.FindAllStringIndex(input, -1)
-	for _, u := range urls {
-		result = append(result, Link{
-			Start: u[0],
-			End:   u[1],
-			Text:  input[u[0]:u[1]],
-			Link:  input[u[0]:u[1]],
-		})
+// buildSpans splits the formatted text at the start and end of each range
+func (m *Message) buildSpans() {
+	var cuts []int
+	for _, match := range slices.Concat(m.ranges.links, m.ranges.mentions, m.ranges.nicks, m.ranges.channels) {
+		cuts = append(cuts, match[0], match[1])
 	}
-	output := input
+	m.spans = nil
 	offset := 0
-	for _, link := range result {
-		replacement := fmt.Sprintf(`%s%s%s`, HyperlinkCode, link.Link, HyperlinkCode)
-		output = fmt.Sprintf(`%s%s%s`, output[:link.Start+offset], replacement, output[link.End+offset:])
-		offset += len(replacement) - len(link.Text)
+	for _, formatted := range ircfmt.Split(m.message) {
+		start, end := offset, offset+len(formatted.Content)
+		offset = end
+		points := []int{start, end}
+		for _, cut := range cuts {
+			if cut > start && cut < end {
+				points = append(points, cut)
+			}
+		}
+		slices.Sort(points)
+		points = slices.Compact(points)
+		for i := 0; i < len(points)-1; i++ {
+			span := Span{FormattedSubstring: formatted}
+			span.Content = m.plain[points[i]:points[i+1]]
+			if link := findRange(m.ranges.links, points[i]); link != nil {
+				span.Link = linkURL(m.plain[link[0]:link[1]])
+			}
+			span.Mention = findRange(m.ranges.mentions, points[i]) != nil
+			if nick := findRange(m.ranges.nicks, points[i]); nick != nil {
+				span.Nick = m.plain[nick[0]:nick[1]]
+			}
+			if channel := findRange(m.ranges.channels, points[i]); channel != nil {
+				span.Channel = m.plain[channel[0]:channel[1]]
+			}
+			m.spans = append(m.spans, span)
+		}
 	}
-	return output
 }
 
-func (m *Message) ReplaceLinks(message string) string {
-	reg := regexp.MustCompile(`(?:\x05)(.*?)(?:\x05)`)
-	return reg.ReplaceAllStringFunc(message, func(i string) string {
-		i = strings.TrimPrefix(i, HyperlinkCode)
-		i = strings.TrimSuffix(i, HyperlinkCode)
-		if strings.HasPrefix(i, "http://") || strings.HasPrefix(i, "https://") {
-			return fmt.Sprintf(`<a target='_blank' href='%s'>%s</a>`, i, i)
+// findRange returns the range containing the offset
+func findRange(ranges [][]int, offset int) []int {
+	for i := range ranges {
+		if ranges[i][0] <= offset && offset < ranges[i][1] {
+			return ranges[i]
 		}
-		return fmt.Sprintf(`<a target='_blank' href='https://%s'>%s</a>`, i, i)
-	})
+	}
+	return nil
+}
+
+// linkURL adds a scheme to links that don't have one
+func linkURL(link string) string {
+	if strings.HasPrefix(link, "http://") || strings.HasPrefix(link, "https://") {
+		return link
+	}
+	return "https://" + link
 }
@@ -4,6 +4,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/ergochat/irc-go/ircfmt"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -89,7 +90,7 @@ func TestNewMessage(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			msg := NewMessage(tt.timeFormat, tt.me, tt.nickname, tt.message, tt.tags, tt.highlights...)
+			msg := NewMessage(tt.timeFormat, tt.me, tt.nickname, tt.message, tt.tags, HighlightWords(tt.highlights))
 
 			assert.NotNil(t, msg, "NewMessage() should not return nil")
 			assert.Equal(t, tt.wantType, msg.GetType(), "NewMessage() type mismatch")
@@ -136,7 +137,7 @@ func TestNewNotice(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			msg := NewNotice(tt.timeFormat, tt.me, tt.nickname, tt.message, tt.tags, tt.highlights...)
+			msg := NewNotice(tt.timeFormat, tt.me, tt.nickname, tt.message, tt.tags, HighlightWords(tt.highlights))
 
 			assert.NotNil(t, msg, "NewNotice() should not return nil")
 			assert.Equal(t, tt.wantType, msg.GetType(), "NewNotice() type mismatch")
@@ -343,9 +344,9 @@ func TestMessage_parseHighlight(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			m := &Message{
-				message:     tt.message,
+				plain:       tt.message,
 				messageType: tt.messageType,
-				highlights:  tt.highlights,
+				highlighter: HighlightWords(tt.highlights),
 			}
 			m.parseHighlight()
 			assert.Equal(t, tt.wantType, m.messageType, "parseHighlight() type mismatch")
@@ -411,38 +412,24 @@ func TestMessage_GetTypeDisplay(t *testing.T) {
 	}
 }
 
-func TestMessage_GetDisplayMessage(t *testing.T) {
+func TestMessage_GetInlineNickname(t *testing.T) {
 	tests := []struct {
 		name        string
 		messageType MessageType
-		nickname    string
-		message     string
 		want        string
 	}{
-		{
-			name:        "Normal message",
-			messageType: Normal,
-			nickname:    "testuser",
-			message:     "Hello, world!",
-			want:        "Hello, world!",
-		},
-		{
-			name:        "Action message",
-			messageType: Action,
-			nickname:    "testuser",
-			message:     "waves",
-			want:        `<span class="nickcolour32">testuser</span> waves`,
-		},
+		{name: "Normal message", messageType: Normal, want: ""},
+		{name: "Action message", messageType: Action, want: "testuser"},
+		{name: "Event", messageType: Event, want: ""},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			m := &Message{
 				messageType: tt.messageType,
-				nickname:    tt.nickname,
-				message:     tt.message,
+				nickname:    "testuser",
 			}
-			assert.Equal(t, tt.want, m.GetDisplayMessage(), "GetDisplayMessage() returned unexpected result")
+			assert.Equal(t, tt.want, m.GetInlineNickname(), "GetInlineNickname() returned unexpected result")
 		})
 	}
 }
@@ -548,6 +535,12 @@ func TestMessage_isHighlight(t *testing.T) {
 			highlights: []string{"targetuser"},
 			want:       true,
 		},
+		{
+			name:       "Highlight inside another word",
+			message:    "I also think so",
+			highlights: []string{"al"},
+			want:       false,
+		},
 		{
 			name:       "Multiple highlights - first match",
 			message:    "Hello, targetuser!",
@@ -583,8 +576,8 @@ func TestMessage_isHighlight(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			m := &Message{
-				message:    tt.message,
-				highlights: tt.highlights,
+				plain:       tt.message,
+				highlighter: HighlightWords(tt.highlights),
 			}
 			assert.Equal(t, tt.want, m.isHighlight(), "isHighlight() returned unexpected result")
 		})
@@ -608,207 +601,401 @@ func TestMessage_GetPlainDisplayMessage(t *testing.T) {
 		},
 		{
 			name:        "Action message",
-			messageType: Action,
+			messageType: Normal,
 			nickname:    "testuser",
-			message:     "waves",
+			message:     "\001ACTION waves\001",
 			want:        "* testuser waves",
 		},
 		{
-			name:        "Message with HTML",
+			name:        "Message with a link",
 			messageType: Normal,
 			nickname:    "testuser",
-			message:     "<a href='https://example.com'>Link</a>",
-			want:        "Link",
+			message:     "see https://example.com",
+			want:        "see https://example.com",
 		},
 		{
-			name:        "Message with HTML, full link",
+			name:        "Message with HTML",
 			messageType: Normal,
 			nickname:    "testuser",
-			message:     "<a href='https://example.com'>https://example.com</a>",
-			want:        "https://example.com",
+			message:     "<a href='https://example.com'>Link</a>",
+			want:        "<a href='https://example.com'>Link</a>",
 		},
 		{
-			name:        "Action message with HTML",
-			messageType: Action,
+			name:        "Action message with formatting",
+			messageType: Normal,
 			nickname:    "testuser",
-			message:     "<b>waves</b>",
+			message:     "\001ACTION \x02waves\x02\001",
 			want:        "* testuser waves",
 		},
 		{
-			name:        "Action message with a nick colour",
-			messageType: Action,
-			nickname:    "<span class=\"nickcolour12\">testuser</span>",
-			message:     "<b>waves</b>",
-			want:        "* testuser waves",
+			name:        "Event with formatting",
+			messageType: Event,
+			message:     "\x0304testuser\x03 has joined",
+			want:        "testuser has joined",
 		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			m := &Message{
-				messageType: tt.messageType,
-				nickname:    tt.nickname,
-				message:     tt.message,
-			}
+			m := newMessage("15:04", false, tt.nickname, tt.message, tt.messageType, nil, nil)
 			assert.Equal(t, tt.want, m.GetPlainDisplayMessage(), "GetPlainDisplayMessage() returned unexpected result")
 		})
 	}
 }
 
-func TestMessage_parseFormatting(t *testing.T) {
+func formatted(content string, modify ...func(*ircfmt.FormattedSubstring)) ircfmt.FormattedSubstring {
+	substring := ircfmt.FormattedSubstring{Content: content}
+	for i := range modify {
+		modify[i](&substring)
+	}
+	return substring
+}
+
+func bold(substring *ircfmt.FormattedSubstring) {
+	substring.Bold = true
+}
+
+func TestMessage_parseSpans(t *testing.T) {
 	tests := []struct {
-		name    string
-		message string
-		want    string
+		name        string
+		message     string
+		messageType MessageType
+		highlights  []string
+		want        []Span
 	}{
 		{
 			name:    "Plain text without formatting",
 			message: "Hello, world!",
-			want:    "Hello, world!",
+			want:    []Span{{FormattedSubstring: formatted("Hello, world!")}},
 		},
 		{
 			name:    "Text with HTML special characters",
 			message: "Hello <world> & \"friends\"",
-			want:    "Hello &lt;world&gt; &amp; &#34;friends&#34;",
+			want:    []Span{{FormattedSubstring: formatted("Hello <world> & \"friends\"")}},
 		},
 		{
 			name:    "Text with URL",
 			message: "Check out https://example.com",
-			want:    "Check out <a target='_blank' href='https://example.com'>https://example.com</a>",
+			want: []Span{
+				{FormattedSubstring: formatted("Check out ")},
+				{FormattedSubstring: formatted("https://example.com"), Link: "https://example.com"},
+			},
 		},
 		{
 			name:    "Text with URL with subdomain",
 			message: "Check out https://test.example.com",
-			want:    "Check out <a target='_blank' href='https://test.example.com'>https://test.example.com</a>",
-		},
-		{
-			name:    "Text with URL in a tag",
-			message: "Check out <a href=\"https://example.com\">https://example.com</a>",
-			want:    "Check out &lt;a href=&#34;<a target='_blank' href='https://example.com'>https://example.com</a>&#34;&gt;<a target='_blank' href='https://example.com'>https://example.com</a>&lt;/a&gt;",
+			want: []Span{
+				{FormattedSubstring: formatted("Check out ")},
+				{FormattedSubstring: formatted("https://test.example.com"), Link: "https://test.example.com"},
+			},
 		},
 		{
 			name:    "Text with multiple URLs",
 			message: "Visit https://example.com and http://test.org",
-			want:    "Visit <a target='_blank' href='https://example.com'>https://example.com</a> and <a target='_blank' href='http://test.org'>http://test.org</a>",
+			want: []Span{
+				{FormattedSubstring: formatted("Visit ")},
+				{FormattedSubstring: formatted("https://example.com"), Link: "https://example.com"},
+				{FormattedSubstring: formatted(" and ")},
+				{FormattedSubstring: formatted("http://test.org"), Link: "http://test.org"},
+			},
 		},
 		{
 			name:    "URL with path and query parameters",
 			message: "https://example.com/path?param=value&other=123",
-			want:    "<a target='_blank' href='https://example.com/path?param=value&amp;other=123'>https://example.com/path?param=value&amp;other=123</a>",
+			want: []Span{
+				{FormattedSubstring: formatted("https://example.com/path?param=value&other=123"), Link: "https://example.com/path?param=value&other=123"},
+			},
 		},
 		{
 			name:    "URL with special characters in path",
 			message: "https://example.com/path-with-[brackets]",
-			want:    "<a target='_blank' href='https://example.com/path-with-[brackets]'>https://example.com/path-with-[brackets]</a>",
+			want: []Span{
+				{FormattedSubstring: formatted("https://example.com/path-with-[brackets]"), Link: "https://example.com/path-with-[brackets]"},
+			},
 		},
 		{
 			name:    "Text with IRC formatting and URL",
 			message: "\x02Bold\x02 text with https://example.com link",
-			want:    "<span class=\"bold\">Bold</span> text with <a target='_blank' href='https://example.com'>https://example.com</a> link",
+			want: []Span{
+				{FormattedSubstring: formatted("Bold", bold)},
+				{FormattedSubstring: formatted(" text with ")},
+				{FormattedSubstring: formatted("https://example.com"), Link: "https://example.com"},
+				{FormattedSubstring: formatted(" link")},
+			},
+		},
+		{
+			name:    "URL with formatting part way through",
+			message: "https://exam\x02ple.com\x02 link",
+			want: []Span{
+				{FormattedSubstring: formatted("https://exam"), Link: "https://example.com"},
+				{FormattedSubstring: formatted("ple.com", bold), Link: "https://example.com"},
+				{FormattedSubstring: formatted(" link")},
+			},
 		},
 		{
 			name:    "No protocol in link",
 			message: "text with example.com link",
-			want:    "text with <a target='_blank' href='https://example.com'>example.com</a> link",
+			want: []Span{
+				{FormattedSubstring: formatted("text with ")},
+				{FormattedSubstring: formatted("example.com"), Link: "https://example.com"},
+				{FormattedSubstring: formatted(" link")},
+			},
 		},
 		{
 			name:    "URL with punctuation at start and end",
 			message: "text with (example.com) link",
-			want:    "text with (<a target='_blank' href='https://example.com'>example.com</a>) link",
+			want: []Span{
+				{FormattedSubstring: formatted("text with (")},
+				{FormattedSubstring: formatted("example.com"), Link: "https://example.com"},
+				{FormattedSubstring: formatted(") link")},
+			},
 		},
 		{
 			name:    "URL with punctuation at the end",
 			message: "text with example.com. link",
-			want:    "text with <a target='_blank' href='https://example.com'>example.com</a>. link",
+			want: []Span{
+				{FormattedSubstring: formatted("text with ")},
+				{FormattedSubstring: formatted("example.com"), Link: "https://example.com"},
+				{FormattedSubstring: formatted(". link")},
+			},
+		},
+		{
+			name:        "No links in events",
+			message:     "Topic is example.com",
+			messageType: Event,
+			want:        []Span{{FormattedSubstring: formatted("Topic is example.com")}},
+		},
+		{
+			name:       "Mention",
+			message:    "hi \x02targetuser\x02, how are you?",
+			highlights: []string{"targetuser"},
+			want: []Span{
+				{FormattedSubstring: formatted("hi ")},
+				{FormattedSubstring: formatted("targetuser", bold), Mention: true},
+				{FormattedSubstring: formatted(", how are you?")},
+			},
+		},
+		{
+			name:       "Multiple mentions",
+			message:    "targetuser: ping otheruser",
+			highlights: []string{"targetuser", "otheruser"},
+			want: []Span{
+				{FormattedSubstring: formatted("targetuser"), Mention: true},
+				{FormattedSubstring: formatted(": ping ")},
+				{FormattedSubstring: formatted("otheruser"), Mention: true},
+			},
+		},
+		{
+			name:    "Empty message",
+			message: "",
+			want:    nil,
 		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			m := &Message{
-				message: tt.message,
-			}
-			m.parseFormatting()
-			assert.Equal(t, tt.want, m.message, "parseFormatting() message mismatch")
+			m := newMessage("15:04", false, "testuser", tt.message, tt.messageType, nil, HighlightWords(tt.highlights))
+			assert.Equal(t, tt.want, m.GetSpans(), "parseSpans() spans mismatch")
+		})
+	}
+}
+
+func TestMessage_parseTargets(t *testing.T) {
+	tests := []struct {
+		name      string
+		message   string
+		nicknames []string
+		chanTypes string
+		want      []Span
+	}{
+		{
+			name:      "Known nickname",
+			message:   "Alice: hello",
+			nicknames: []string{"alice", "bob"},
+			chanTypes: "#",
+			want: []Span{
+				{FormattedSubstring: formatted("Alice"), Nick: "Alice"},
+				{FormattedSubstring: formatted(": hello")},
+			},
+		},
+		{
+			name:      "Unknown nickname",
+			message:   "carol: hello",
+			nicknames: []string{"alice"},
+			chanTypes: "#",
+			want:      []Span{{FormattedSubstring: formatted("carol: hello")}},
+		},
+		{
+			name:      "Nickname inside a word",
+			message:   "bobby and [bob]",
+			nicknames: []string{"bob"},
+			chanTypes: "#",
+			want:      []Span{{FormattedSubstring: formatted("bobby and [bob]")}},
+		},
+		{
+			name:      "Channel",
+			message:   "join #test, (#other) or &local.",
+			chanTypes: "#&",
+			want: []Span{
+				{FormattedSubstring: formatted("join ")},
+				{FormattedSubstring: formatted("#test"), Channel: "#test"},
+				{FormattedSubstring: formatted(", (")},
+				{FormattedSubstring: formatted("#other"), Channel: "#other"},
+				{FormattedSubstring: formatted(") or ")},
+				{FormattedSubstring: formatted("&local"), Channel: "&local"},
+				{FormattedSubstring: formatted(".")},
+			},
+		},
+		{
+			name:      "Channel types from the server",
+			message:   "#test &test",
+			chanTypes: "&",
+			want: []Span{
+				{FormattedSubstring: formatted("#test ")},
+				{FormattedSubstring: formatted("&test"), Channel: "&test"},
+			},
+		},
+		{
+			name:      "Channel prefix on its own",
+			message:   "# is not a channel",
+			chanTypes: "#",
+			want:      []Span{{FormattedSubstring: formatted("# is not a channel")}},
+		},
+		{
+			name:      "Inside a link",
+			message:   "https://example.com/#alice",
+			nicknames: []string{"alice"},
+			chanTypes: "#",
+			want: []Span{
+				{FormattedSubstring: formatted("https://example.com/#alice"), Link: "https://example.com/#alice"},
+			},
+		},
+		{
+			name:      "Formatted nickname",
+			message:   "\x02alice\x02 joined #test",
+			nicknames: []string{"alice"},
+			chanTypes: "#",
+			want: []Span{
+				{FormattedSubstring: formatted("alice", bold), Nick: "alice"},
+				{FormattedSubstring: formatted(" joined ")},
+				{FormattedSubstring: formatted("#test"), Channel: "#test"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := newMessage("15:04", false, "testuser", tt.message, Normal, nil, nil)
+			m.parseTargets(tt.nicknames, tt.chanTypes)
+			assert.Equal(t, tt.want, m.GetSpans(), "parseTargets() spans mismatch")
 		})
 	}
 }
 
-func TestMessage_parseIRCFormatting(t *testing.T) {
+func TestSpan_GetClasses(t *testing.T) {
 	tests := []struct {
 		name    string
 		message string
-		want    string
+		want    []string
 	}{
 		{
 			name:    "Plain text without formatting",
 			message: "Hello, world!",
-			want:    "Hello, world!",
+			want:    []string{""},
 		},
 		{
 			name:    "Text with foreground color",
 			message: "\x0304Red text\x03",
-			want:    "<span class=\"fg-4\">Red text</span>",
+			want:    []string{"fg-4"},
 		},
 		{
 			name:    "Text with background color",
 			message: "\x0304,02Red text on blue background\x03",
-			want:    "<span class=\"fg-4 bg-2\">Red text on blue background</span>",
+			want:    []string{"fg-4 bg-2"},
 		},
 		{
 			name:    "Text with bold formatting",
 			message: "\x02Bold text\x02",
-			want:    "<span class=\"bold\">Bold text</span>",
+			want:    []string{"bold"},
 		},
 		{
 			name:    "Text with monospace formatting",
 			message: "\x11Monospace text\x11",
-			want:    "<span class=\"monospace\">Monospace text</span>",
+			want:    []string{"monospace"},
 		},
 		{
 			name:    "Text with strikethrough formatting",
 			message: "\x1eStrikethrough text\x1e",
-			want:    "<span class=\"strikethrough\">Strikethrough text</span>",
+			want:    []string{"strikethrough"},
 		},
 		{
 			name:    "Text with underline formatting",
 			message: "\x1fUnderlined text\x1f",
-			want:    "<span class=\"underline\">Underlined text</span>",
+			want:    []string{"underline"},
 		},
 		{
 			name:    "Text with italic formatting",
 			message: "\x1dItalic text\x1d",
-			want:    "<span class=\"italic\">Italic text</span>",
+			want:    []string{"italic"},
 		},
 		{
 			name:    "Text with reverse color formatting",
 			message: "\x16Reverse color text\x16",
-			want:    "<span class=\"reverseColour\">Reverse color text</span>",
+			want:    []string{"reverseColour"},
 		},
 		{
 			name:    "Text with multiple formatting attributes",
 			message: "\x02\x034\x1fBold, red, underlined text\x1f\x03\x02",
-			want:    "<span class=\"fg-4 bold underline\">Bold, red, underlined text</span>",
+			want:    []string{"fg-4 bold underline"},
 		},
 		{
 			name:    "Multiple segments with different formatting",
 			message: "Normal \x02bold\x02 normal",
-			want:    "Normal <span class=\"bold\">bold</span> normal",
+			want:    []string{"", "bold", ""},
 		},
 		{
 			name:    "Nested formatting",
 			message: "\x02Bold \x034and red\x03 just bold\x02",
-			want:    "<span class=\"bold\">Bold </span><span class=\"fg-4 bold\">and red</span><span class=\"bold\"> just bold</span>",
+			want:    []string{"bold", "fg-4 bold", "bold"},
 		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			m := &Message{
-				message: tt.message,
+			m := newMessage("15:04", false, "testuser", tt.message, Normal, nil, nil)
+			var classes []string
+			for _, span := range m.GetSpans() {
+				classes = append(classes, span.GetClasses())
 			}
-			m.parseIRCFormatting()
-			assert.Equal(t, tt.want, m.message, "parseIRCFormatting() message mismatch")
+			assert.Equal(t, tt.want, classes, "GetClasses() mismatch")
 		})
 	}
+
+	assert.Equal(t, "mention", Span{Mention: true}.GetClasses())
+}
+
+func TestWindow_AddMessage_Targets(t *testing.T) {
+	server := &Server{Window: &Window{id: "libera", name: "Libera.Chat", isServer: true}}
+	server.Window.connection = server
+	channel := &Window{id: "c1", name: "#tithon", connection: server, isChannel: true, hasUsers: true}
+	channel.AddUser(NewUser("alice", ""))
+	query := &Window{id: "q1", name: "bob", connection: server, isQuery: true}
+
+	message := NewMessage("15:04", false, "alice", "alice and bob should join #test", nil, nil)
+	channel.AddMessage(message)
+	assert.Equal(t, "libera", message.GetServerID())
+	assert.Equal(t, []Span{
+		{FormattedSubstring: formatted("alice"), Nick: "alice"},
+		{FormattedSubstring: formatted(" and bob should join ")},
+		{FormattedSubstring: formatted("#test"), Channel: "#test"},
+	}, message.GetSpans())
+
+	message = NewMessage("15:04", false, "bob", "alice and bob", nil, nil)
+	query.AddMessage(message)
+	assert.Equal(t, []Span{
+		{FormattedSubstring: formatted("alice and ")},
+		{FormattedSubstring: formatted("bob"), Nick: "bob"},
+	}, message.GetSpans())
+
+	assert.Empty(t, newMessage("15:04", false, "alice", "hi", Normal, nil, nil).GetServerID())
 }
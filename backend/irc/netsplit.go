@@ -0,0 +1,158 @@
+package irc
+
+import (
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// netsplitGap is how long after the last quit a netsplit's quits stop being grouped together
+	netsplitGap = time.Minute
+	// netjoinTimeout is how long after a netsplit the users who quit are expected to rejoin
+	netjoinTimeout = 30 * time.Minute
+)
+
+// netsplitReason matches the quit reason servers give users lost in a netsplit, the names of the two servers that
+// split.  Some networks hide the server names, eg "*.net *.split".
+var netsplitReason = regexp.MustCompile(`^[\w*-]+(\.[\w*-]+)+ [\w*-]+(\.[\w*-]+)+$`)
+
+// isNetsplit checks if a quit reason is the result of a netsplit
+func isNetsplit(reason string) bool {
+	if !netsplitReason.MatchString(reason) {
+		return false
+	}
+	servers := strings.Fields(reason)
+	return servers[0] != servers[1]
+}
+
+// EventGroup collects the users affected by an event, such as a netsplit, so it can be shown as a single message
+type EventGroup struct {
+	lock      sync.Mutex
+	action    string
+	nicknames []string
+}
+
+func (g *EventGroup) add(nickname string) {
+	g.lock.Lock()
+	defer g.lock.Unlock()
+	g.nicknames = append(g.nicknames, nickname)
+}
+
+// GetNicknames returns the users affected by the event
+func (g *EventGroup) GetNicknames() []string {
+	g.lock.Lock()
+	defer g.lock.Unlock()
+	return slices.Clone(g.nicknames)
+}
+
+// GetSummary returns the number of users affected and what happened to them, eg "3 users quit"
+func (g *EventGroup) GetSummary() string {
+	g.lock.Lock()
+	defer g.lock.Unlock()
+	if len(g.nicknames) == 1 {
+		return "1 user " + g.action
+	}
+	return strconv.Itoa(len(g.nicknames)) + " users " + g.action
+}
+
+// netsplit is a split between two servers, with the users that quit and rejoined each channel
+type netsplit struct {
+	servers  string
+	lastQuit time.Time
+	quits    map[string]*EventGroup
+	joins    map[string]*EventGroup
+	lastJoin map[string]time.Time
+	// pending are the users that quit each channel and haven't rejoined
+	pending map[string][]string
+}
+
+// netsplits tracks recent netsplits so the quits and rejoins in each channel can be grouped together
+type netsplits struct {
+	lock   sync.Mutex
+	splits []*netsplit
+	now    func() time.Time
+}
+
+func (n *netsplits) getNow() time.Time {
+	if n.now == nil {
+		return time.Now()
+	}
+	return n.now()
+}
+
+// expire forgets netsplits the users haven't rejoined from in time
+func (n *netsplits) expire(now time.Time) {
+	n.splits = slices.DeleteFunc(n.splits, func(split *netsplit) bool {
+		return now.Sub(split.lastQuit) > netjoinTimeout
+	})
+}
+
+// quit records a user quitting a channel in a netsplit between servers.  It returns the group for the channel's
+// netsplit message, which is new if the message needs to be added to the channel.
+func (n *netsplits) quit(channel string, servers string, nickname string) (*EventGroup, bool) {
+	n.lock.Lock()
+	defer n.lock.Unlock()
+	now := n.getNow()
+	n.expire(now)
+	channel = strings.ToLower(channel)
+	index := slices.IndexFunc(n.splits, func(split *netsplit) bool {
+		return split.servers == servers && now.Sub(split.lastQuit) <= netsplitGap
+	})
+	if index == -1 {
+		n.splits = append(n.splits, &netsplit{
+			servers:  servers,
+			quits:    map[string]*EventGroup{},
+			joins:    map[string]*EventGroup{},
+			lastJoin: map[string]time.Time{},
+			pending:  map[string][]string{},
+		})
+		index = len(n.splits) - 1
+	}
+	split := n.splits[index]
+	split.lastQuit = now
+	split.pending[channel] = append(split.pending[channel], strings.ToLower(nickname))
+	group, exists := split.quits[channel]
+	if !exists {
+		group = &EventGroup{action: "quit"}
+		split.quits[channel] = group
+	}
+	group.add(nickname)
+	return group, !exists
+}
+
+// join records a user rejoining a channel after a netsplit.  It returns the servers that split and the group for the
+// channel's netjoin message, which is new if the message needs to be added to the channel, or nil if the user didn't
+// quit in a netsplit.  Users rejoining long after the last one start a new message.
+func (n *netsplits) join(channel string, nickname string) (string, *EventGroup, bool) {
+	n.lock.Lock()
+	defer n.lock.Unlock()
+	now := n.getNow()
+	n.expire(now)
+	channel = strings.ToLower(channel)
+	for _, split := range n.splits {
+		index := slices.Index(split.pending[channel], strings.ToLower(nickname))
+		if index == -1 {
+			continue
+		}
+		split.pending[channel] = slices.Delete(split.pending[channel], index, index+1)
+		group, exists := split.joins[channel]
+		if !exists || now.Sub(split.lastJoin[channel]) > netsplitGap {
+			group = &EventGroup{action: "rejoined"}
+			split.joins[channel] = group
+			exists = false
+		}
+		split.lastJoin[channel] = now
+		group.add(nickname)
+		return split.servers, group, !exists
+	}
+	return "", nil, false
+}
+
+// netsplitDescription describes the servers that split, eg "a.net ↔ b.net"
+func netsplitDescription(servers string) string {
+	return strings.Replace(servers, " ", " ↔ ", 1)
+}
@@ -0,0 +1,96 @@
+package irc
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIsNetsplit(t *testing.T) {
+	tests := []struct {
+		reason string
+		want   bool
+	}{
+		{reason: "hub.example.net leaf.example.net", want: true},
+		{reason: "*.net *.split", want: true},
+		{reason: "irc-1.example.com irc-2.example.com", want: true},
+		{reason: "hub.example.net hub.example.net", want: false},
+		{reason: "Leaving", want: false},
+		{reason: "Quit: see you.later bye.now", want: false},
+		{reason: "example.net", want: false},
+		{reason: "hub.example.net  leaf.example.net", want: false},
+		{reason: "", want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.reason, func(t *testing.T) {
+			assert.Equal(t, tt.want, isNetsplit(tt.reason))
+		})
+	}
+}
+
+func TestEventGroup_GetSummary(t *testing.T) {
+	group := &EventGroup{action: "quit"}
+	group.add("alice")
+	assert.Equal(t, "1 user quit", group.GetSummary())
+	group.add("bob")
+	assert.Equal(t, "2 users quit", group.GetSummary())
+	assert.Equal(t, []string{"alice", "bob"}, group.GetNicknames())
+}
+
+func TestNetsplits(t *testing.T) {
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	splits := &netsplits{now: func() time.Time { return now }}
+
+	first, isNew := splits.quit("#tithon", "a.net b.net", "alice")
+	assert.True(t, isNew)
+	second, isNew := splits.quit("#TITHON", "a.net b.net", "bob")
+	assert.False(t, isNew, "Quits in the same channel should be grouped")
+	assert.Same(t, first, second)
+	other, isNew := splits.quit("#other", "a.net b.net", "alice")
+	assert.True(t, isNew, "Each channel should have its own group")
+	assert.Equal(t, []string{"alice"}, other.GetNicknames())
+	assert.Equal(t, []string{"alice", "bob"}, first.GetNicknames())
+
+	servers, group, _ := splits.join("#tithon", "carol")
+	assert.Nil(t, group, "Users that didn't quit in the split shouldn't be grouped")
+	assert.Empty(t, servers)
+
+	now = now.Add(5 * time.Minute)
+	servers, joined, isNew := splits.join("#tithon", "Alice")
+	assert.Equal(t, "a.net b.net", servers)
+	assert.True(t, isNew)
+	_, again, isNew := splits.join("#tithon", "bob")
+	assert.False(t, isNew, "Rejoins in the same channel should be grouped")
+	assert.Same(t, joined, again)
+	_, group, _ = splits.join("#tithon", "bob")
+	assert.Nil(t, group, "Users should only rejoin once")
+
+	now = now.Add(10 * time.Minute)
+	_, late, isNew := splits.join("#other", "alice")
+	assert.True(t, isNew)
+	assert.NotNil(t, late)
+
+	now = now.Add(2 * time.Minute)
+	_, isNew = splits.quit("#tithon", "a.net b.net", "dave")
+	assert.True(t, isNew, "Quits long after the last should be a new netsplit")
+	now = now.Add(netjoinTimeout + time.Second)
+	_, group, _ = splits.join("#tithon", "dave")
+	assert.Nil(t, group, "Netsplits should expire if users don't rejoin")
+}
+
+func TestNetsplits_LateRejoin(t *testing.T) {
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	splits := &netsplits{now: func() time.Time { return now }}
+	splits.quit("#tithon", "a.net b.net", "alice")
+	splits.quit("#tithon", "a.net b.net", "bob")
+	_, first, _ := splits.join("#tithon", "alice")
+	now = now.Add(netsplitGap + time.Second)
+	_, second, isNew := splits.join("#tithon", "bob")
+	assert.True(t, isNew, "Rejoins long after the last should start a new group")
+	assert.NotSame(t, first, second)
+}
+
+func TestNetsplitDescription(t *testing.T) {
+	assert.Equal(t, "a.net ↔ b.net", netsplitDescription("a.net b.net"))
+}
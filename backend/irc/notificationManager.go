@@ -11,7 +11,7 @@ import (
 )
 
 type NotificationManager interface {
-	CheckAndNotify(network, serverID, source, nick, message string) bool
+	CheckAndNotify(network, serverID, source, nick, message string, highlight bool) bool
 	SendNotification(notification Notification)
 }
 
@@ -39,12 +39,14 @@ type DesktopNotificationManager struct {
 	pendingNotifications    chan Notification
 	lastNotificationTimes   map[string]time.Time
 	lastNotificationTimesMu sync.RWMutex
+	windowSettings          *WindowSettingsList
 }
 
-func NewNotificationManager(pendingNotifications chan Notification, triggers []config.NotificationTrigger) NotificationManager {
+func NewNotificationManager(pendingNotifications chan Notification, triggers []config.NotificationTrigger, windowSettings *WindowSettingsList) NotificationManager {
 	nm := &DesktopNotificationManager{
 		pendingNotifications:  pendingNotifications,
 		lastNotificationTimes: make(map[string]time.Time),
+		windowSettings:        windowSettings,
 	}
 	triggers = SortNotificationTriggers(triggers)
 
@@ -139,7 +141,12 @@ func GetTriggerSpecificity(trigger config.NotificationTrigger) int {
 	return length
 }
 
-func (cm *DesktopNotificationManager) CheckAndNotify(network, serverID, source, nick, message string) bool {
+// CheckAndNotify shows a notification for the first trigger the message matches, unless the notification level of the
+// window it's in doesn't allow it
+func (cm *DesktopNotificationManager) CheckAndNotify(network, serverID, source, nick, message string, highlight bool) bool {
+	if !cm.windowSettings.shouldNotify(serverID, source, highlight) {
+		return false
+	}
 	for i := range cm.notifications {
 		if cm.notifications[i].Network.MatchString(network) &&
 			cm.notifications[i].Source.MatchString(source) &&
@@ -0,0 +1,165 @@
+package irc
+
+import (
+	"errors"
+	"fmt"
+	"github.com/greboid/tithon/config"
+	"net"
+	"strconv"
+	"strings"
+)
+
+// defaultModesLimit is the number of modes with parameters sent in one MODE command when the server doesn't say
+const defaultModesLimit = 3
+
+// unlimitedModesLimit is used when the server doesn't limit the number of modes, to keep lines a sensible length
+const unlimitedModesLimit = 12
+
+var ErrNoNick = errors.New("no nickname specified")
+
+// getModesLimit returns the number of modes with parameters the server accepts in a single MODE command
+func (c *Server) getModesLimit() int {
+	value, ok := c.connection.ISupport()["MODES"]
+	if !ok {
+		return defaultModesLimit
+	}
+	if value == "" {
+		return unlimitedModesLimit
+	}
+	limit, err := strconv.Atoi(value)
+	if err != nil || limit < 1 {
+		return defaultModesLimit
+	}
+	return limit
+}
+
+// checkChannelMode returns an error if the network doesn't support the mode as the given type
+func (c *Server) checkChannelMode(mode string, modeType rune) error {
+	if c.GetChannelModeType(mode) != modeType {
+		return fmt.Errorf("mode %s isn't supported on this network", mode)
+	}
+	return nil
+}
+
+// SendChannelModes sets or unsets a mode on a channel for each parameter, as many as the server allows are sent in
+// each MODE command
+func (c *Server) SendChannelModes(channel string, add bool, mode string, params []string) error {
+	for i, batch := range batchModes(add, mode, params, c.getModesLimit()) {
+		if err := c.send(batchPriority(i), "MODE", append([]string{channel}, batch...)...); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SendKick kicks users from a channel, the reason is optional
+func (c *Server) SendKick(channel string, nicknames []string, reason string) error {
+	for i := range nicknames {
+		params := []string{channel, nicknames[i]}
+		if reason != "" {
+			params = append(params, reason)
+		}
+		if err := c.send(batchPriority(i), "KICK", params...); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// batchPriority returns the priority for a line of an operator command, the first is sent straight away and the rest
+// are queued behind anything the user types so mass kicks and modes don't hold up the channel
+func batchPriority(index int) SendPriority {
+	if index == 0 {
+		return PriorityInteractive
+	}
+	return PriorityBulk
+}
+
+func (c *Server) SendInvite(nickname string, channel string) error {
+	return c.send(PriorityInteractive, "INVITE", nickname, channel)
+}
+
+// batchModes splits the parameters into groups of at most limit, each group is preceded by the mode string, eg +ooo
+func batchModes(add bool, mode string, params []string, limit int) [][]string {
+	sign := "-"
+	if add {
+		sign = "+"
+	}
+	var batches [][]string
+	for start := 0; start < len(params); start += limit {
+		end := min(start+limit, len(params))
+		batch := []string{sign + strings.Repeat(mode, end-start)}
+		batches = append(batches, append(batch, params[start:end]...))
+	}
+	return batches
+}
+
+// getOpTarget returns the channel an operator command applies to and the rest of the input, the channel can be given
+// as the first argument and defaults to the current channel
+func getOpTarget(window *Window, input string) (string, string, error) {
+	if window == nil || window.connection == nil {
+		return "", "", ErrNoServer
+	}
+	first, rest, _ := strings.Cut(strings.TrimSpace(input), " ")
+	if first != "" && window.connection.IsTargetChannel(first) {
+		return first, strings.TrimSpace(rest), nil
+	}
+	if !window.IsChannel() {
+		return "", "", ErrNoChannel
+	}
+	return window.GetName(), strings.TrimSpace(input), nil
+}
+
+// findUser returns the user in the channel with the nickname, or nil if they aren't in the channel
+func (c *Server) findUser(channel string, nickname string) *User {
+	window, err := c.GetChannelByName(channel)
+	if err != nil {
+		return nil
+	}
+	for _, user := range window.GetUsers() {
+		if strings.EqualFold(user.GetNickname(), nickname) {
+			return user
+		}
+	}
+	return nil
+}
+
+// banMask returns the mask used to ban a nickname, anything that already looks like a mask or an extban is returned
+// as is.  The host is only known for users who've joined since we did, or when the server supports userhost-in-names,
+// otherwise the nickname is banned.
+func banMask(nickname string, user *User, style string) string {
+	if strings.ContainsAny(nickname, "!@$:*") {
+		return nickname
+	}
+	var ident, host string
+	if user != nil {
+		ident, host = user.getUserHost()
+	}
+	if host == "" || style == config.BanMaskNick {
+		return nickname + "!*@*"
+	}
+	switch style {
+	case config.BanMaskUserHost:
+		return "*!*" + strings.TrimPrefix(ident, "~") + "@" + host
+	case config.BanMaskDomain:
+		return "*!*@" + maskDomain(host)
+	default:
+		return "*!*@" + host
+	}
+}
+
+// maskDomain replaces the first part of a hostname, or the last part of an IPv4 address, with a wildcard.  IPv6
+// addresses and cloaks are left alone.
+func maskDomain(host string) string {
+	if ip := net.ParseIP(host); ip != nil {
+		if v4 := ip.To4(); v4 != nil {
+			return fmt.Sprintf("%d.%d.%d.*", v4[0], v4[1], v4[2])
+		}
+		return host
+	}
+	labels := strings.Split(host, ".")
+	if len(labels) < 3 {
+		return host
+	}
+	return "*." + strings.Join(labels[1:], ".")
+}
@@ -0,0 +1,120 @@
+package irc
+
+import (
+	"testing"
+
+	"github.com/greboid/tithon/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBatchModes(t *testing.T) {
+	tests := []struct {
+		name   string
+		add    bool
+		params []string
+		limit  int
+		want   [][]string
+	}{
+		{name: "Single", add: true, params: []string{"alice"}, limit: 3, want: [][]string{{"+o", "alice"}}},
+		{name: "Exactly the limit", add: true, params: []string{"a", "b", "c"}, limit: 3, want: [][]string{{"+ooo", "a", "b", "c"}}},
+		{
+			name: "Over the limit", params: []string{"a", "b", "c", "d", "e"}, limit: 2,
+			want: [][]string{{"-oo", "a", "b"}, {"-oo", "c", "d"}, {"-o", "e"}},
+		},
+		{name: "Nothing", params: nil, limit: 3, want: nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, batchModes(tt.add, "o", tt.params, tt.limit))
+		})
+	}
+}
+
+func TestBatchPriority(t *testing.T) {
+	assert.Equal(t, PriorityInteractive, batchPriority(0), "single commands shouldn't wait behind bulk traffic")
+	assert.Equal(t, PriorityBulk, batchPriority(1))
+	assert.Equal(t, PriorityBulk, batchPriority(5))
+}
+
+func TestBanMask(t *testing.T) {
+	user := &User{nickname: "alice", ident: "~al", host: "host-1.isp.example.com"}
+	tests := []struct {
+		name     string
+		nickname string
+		user     *User
+		style    string
+		want     string
+	}{
+		{name: "Default style", nickname: "alice", user: user, want: "*!*@host-1.isp.example.com"},
+		{name: "Host", nickname: "alice", user: user, style: config.BanMaskHost, want: "*!*@host-1.isp.example.com"},
+		{name: "Nick", nickname: "alice", user: user, style: config.BanMaskNick, want: "alice!*@*"},
+		{name: "User and host", nickname: "alice", user: user, style: config.BanMaskUserHost, want: "*!*al@host-1.isp.example.com"},
+		{name: "Domain", nickname: "alice", user: user, style: config.BanMaskDomain, want: "*!*@*.isp.example.com"},
+		{name: "Unknown host", nickname: "bob", user: &User{nickname: "bob"}, style: config.BanMaskHost, want: "bob!*@*"},
+		{name: "Unknown user", nickname: "bob", style: config.BanMaskDomain, want: "bob!*@*"},
+		{name: "Mask", nickname: "*!*@example.com", user: user, want: "*!*@example.com"},
+		{name: "Extban", nickname: "$a:alice", user: user, want: "$a:alice"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, banMask(tt.nickname, tt.user, tt.style))
+		})
+	}
+}
+
+func TestMaskDomain(t *testing.T) {
+	tests := []struct {
+		host string
+		want string
+	}{
+		{host: "host-1.isp.example.com", want: "*.isp.example.com"},
+		{host: "example.com", want: "example.com"},
+		{host: "192.0.2.55", want: "192.0.2.*"},
+		{host: "2001:db8::1", want: "2001:db8::1"},
+		{host: "user/alice", want: "user/alice"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.host, func(t *testing.T) {
+			assert.Equal(t, tt.want, maskDomain(tt.host))
+		})
+	}
+}
+
+func TestGetOpTarget(t *testing.T) {
+	server := &Server{Window: &Window{id: "libera", name: "Libera.Chat", isServer: true}}
+	server.Window.connection = server
+	channel := &Window{name: "#tithon", connection: server, isChannel: true}
+	query := &Window{name: "alice", connection: server, isQuery: true}
+	tests := []struct {
+		name        string
+		window      *Window
+		input       string
+		wantChannel string
+		wantRest    string
+		wantErr     error
+	}{
+		{name: "Current channel", window: channel, input: "alice bob", wantChannel: "#tithon", wantRest: "alice bob"},
+		{name: "Given channel", window: channel, input: "#other alice  ", wantChannel: "#other", wantRest: "alice"},
+		{name: "Given channel in a query", window: query, input: "#other alice", wantChannel: "#other", wantRest: "alice"},
+		{name: "No channel in a query", window: query, input: "alice", wantErr: ErrNoChannel},
+		{name: "Only a channel", window: channel, input: "#other", wantChannel: "#other"},
+		{name: "No window", input: "alice", wantErr: ErrNoServer},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			channel, rest, err := getOpTarget(tt.window, tt.input)
+			assert.Equal(t, tt.wantErr, err)
+			assert.Equal(t, tt.wantChannel, channel)
+			assert.Equal(t, tt.wantRest, rest)
+		})
+	}
+}
+
+func TestServer_findUser(t *testing.T) {
+	server := &Server{channels: map[string]*Channel{}}
+	channel := &Channel{Window: &Window{name: "#tithon", hasUsers: true, users: []*User{{nickname: "Alice", host: "example.com"}}}}
+	server.channels["c1"] = channel
+	assert.Equal(t, "example.com", server.findUser("#TITHON", "alice").host)
+	assert.Nil(t, server.findUser("#tithon", "bob"))
+	assert.Nil(t, server.findUser("#other", "alice"))
+}
@@ -0,0 +1,510 @@
+package irc
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/greboid/tithon/config"
+	"golang.org/x/net/html"
+	"image"
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+	"io"
+	"log/slog"
+	"mime"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+)
+
+// maxPreviews is the most links in a single message that will be previewed
+const maxPreviews = 3
+
+// maxPreviewRedirects is the most redirects followed when fetching a link
+const maxPreviewRedirects = 5
+
+// maxPreviewDescription is the longest description shown in a preview, in characters
+const maxPreviewDescription = 300
+
+// previewFetchers is the number of links fetched at the same time
+const previewFetchers = 4
+
+// previewQueueSize is the most links waiting to be fetched, links posted when the queue is full aren't previewed
+const previewQueueSize = 100
+
+// previewImageTypes are the types of image that are served for previews, others such as SVG can contain scripts
+var previewImageTypes = []string{"image/png", "image/jpeg", "image/gif", "image/webp"}
+
+// LinkPreview is the title, description and image of a page, or the size of an image, shown under messages that
+// link to it
+type LinkPreview struct {
+	URL         string `json:"url"`
+	Title       string `json:"title,omitempty"`
+	Description string `json:"description,omitempty"`
+	SiteName    string `json:"site_name,omitempty"`
+	Image       string `json:"image,omitempty"`
+	Width       int    `json:"width,omitempty"`
+	Height      int    `json:"height,omitempty"`
+	// IsImage is set when the link is to an image rather than a page
+	IsImage bool `json:"is_image,omitempty"`
+}
+
+// IsEmpty checks if there's nothing to show for the preview
+func (p *LinkPreview) IsEmpty() bool {
+	return p.Title == "" && p.Description == "" && p.Image == ""
+}
+
+// LinkPreviewer fetches the titles and images of links posted in messages, previews are cached on disk so each link is
+// only fetched once
+type LinkPreviewer struct {
+	settings  config.LinkPreviews
+	directory string
+	client    *http.Client
+	queue     chan previewRequest
+	start     sync.Once
+	// proxy picks the proxy for each request, previews use the proxy from the environment
+	proxy func(*http.Request) (*url.URL, error)
+	// proxies are the addresses of the proxies that have been used, they're allowed to be local
+	proxies sync.Map
+	// allowPrivate lets previews be fetched from local addresses, only used by tests
+	allowPrivate bool
+}
+
+// previewRequest is a link waiting to be previewed, index is its position among the total links in the message
+type previewRequest struct {
+	message *Message
+	link    string
+	index   int
+	total   int
+	trigger UpdateTrigger
+}
+
+func NewLinkPreviewer(settings config.LinkPreviews, directory string) *LinkPreviewer {
+	lp := &LinkPreviewer{
+		settings:  settings,
+		directory: directory,
+		queue:     make(chan previewRequest, previewQueueSize),
+		proxy:     http.ProxyFromEnvironment,
+	}
+	proxyDialer := &net.Dialer{Timeout: settings.Timeout}
+	dialer := &net.Dialer{
+		Timeout: settings.Timeout,
+		Control: func(_ string, address string, _ syscall.RawConn) error {
+			if lp.allowPrivate {
+				return nil
+			}
+			return checkPreviewAddress(address)
+		},
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = lp.getProxy
+	transport.DialContext = func(ctx context.Context, network, address string) (net.Conn, error) {
+		if _, ok := lp.proxies.Load(address); ok {
+			return proxyDialer.DialContext(ctx, network, address)
+		}
+		return dialer.DialContext(ctx, network, address)
+	}
+	lp.client = &http.Client{
+		Timeout:   settings.Timeout,
+		Transport: transport,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) >= maxPreviewRedirects {
+				return errors.New("too many redirects")
+			}
+			if !lp.isAllowed(req.URL) {
+				return fmt.Errorf("redirected to %s which isn't allowed", req.URL.Hostname())
+			}
+			return nil
+		},
+	}
+	return lp
+}
+
+// Preview fetches previews for the links in a message in the background, the trigger is called as each preview is
+// added to the message.  History replayed by the server is skipped unless enabled in the settings.
+func (lp *LinkPreviewer) Preview(message *Message, trigger UpdateTrigger) {
+	if lp == nil || !lp.settings.Enabled {
+		return
+	}
+	if message.GetTags()["chathistory"] == "true" && !lp.settings.ChatHistory {
+		return
+	}
+	links := message.GetLinks()
+	if len(links) > maxPreviews {
+		links = links[:maxPreviews]
+	}
+	lp.start.Do(func() {
+		for range previewFetchers {
+			go lp.fetcher()
+		}
+	})
+	for i := range links {
+		target, err := url.Parse(links[i])
+		if err != nil || !lp.isAllowed(target) {
+			continue
+		}
+		select {
+		case lp.queue <- previewRequest{message: message, link: links[i], index: i, total: len(links), trigger: trigger}:
+		default:
+			slog.Debug("Too many links waiting to be previewed", "url", links[i])
+		}
+	}
+}
+
+// fetcher previews queued links until the previewer is discarded
+func (lp *LinkPreviewer) fetcher() {
+	for request := range lp.queue {
+		preview, err := lp.get(request.link)
+		if err != nil {
+			slog.Debug("Unable to preview link", "url", request.link, "error", err)
+			continue
+		}
+		if preview.IsEmpty() {
+			continue
+		}
+		request.message.setPreview(request.index, request.total, preview)
+		if request.trigger != nil {
+			request.trigger.SetPendingUpdate()
+		}
+	}
+}
+
+// getProxy returns the proxy to use for a request.  The proxy connects to the link rather than the previewer, so the
+// link's addresses are checked here instead, and the proxy's address is remembered so it can be connected to even if
+// it's local.
+func (lp *LinkPreviewer) getProxy(req *http.Request) (*url.URL, error) {
+	proxyURL, err := lp.proxy(req)
+	if err != nil || proxyURL == nil {
+		return proxyURL, err
+	}
+	if !lp.allowPrivate {
+		if err = checkPreviewHost(req.Context(), req.URL.Hostname()); err != nil {
+			return nil, err
+		}
+	}
+	lp.proxies.Store(proxyAddress(proxyURL), true)
+	return proxyURL, nil
+}
+
+// proxyAddress returns the host and port that will be connected to for a proxy, using the default port for the
+// proxy's scheme if there isn't one
+func proxyAddress(proxyURL *url.URL) string {
+	port := proxyURL.Port()
+	if port == "" {
+		switch proxyURL.Scheme {
+		case "https":
+			port = "443"
+		case "socks5", "socks5h":
+			port = "1080"
+		default:
+			port = "80"
+		}
+	}
+	return net.JoinHostPort(proxyURL.Hostname(), port)
+}
+
+// checkPreviewHost resolves a host and checks each of its addresses, for when the connection is made by a proxy
+func checkPreviewHost(ctx context.Context, host string) error {
+	if ip, err := netip.ParseAddr(host); err == nil {
+		return checkPreviewIP(ip)
+	}
+	ips, err := net.DefaultResolver.LookupNetIP(ctx, "ip", host)
+	if err != nil {
+		return err
+	}
+	for i := range ips {
+		if err = checkPreviewIP(ips[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// checkPreviewAddress stops previews connecting to loopback, private, link-local, unspecified and multicast addresses
+// so links can't be used to reach the local network or Tithon itself.  It's checked when connecting, after the name
+// is resolved, so it applies to redirects too.
+func checkPreviewAddress(address string) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	ip, err := netip.ParseAddr(host)
+	if err != nil {
+		return err
+	}
+	return checkPreviewIP(ip)
+}
+
+// checkPreviewIP checks a single address for checkPreviewAddress
+func checkPreviewIP(ip netip.Addr) error {
+	ip = ip.Unmap()
+	if ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() || ip.IsUnspecified() || ip.IsMulticast() ||
+		ip.IsLinkLocalMulticast() || ip.IsInterfaceLocalMulticast() {
+		return fmt.Errorf("previews of %s aren't allowed", ip)
+	}
+	return nil
+}
+
+// isAllowed checks the link is http or https and the domain is allowed by the settings
+func (lp *LinkPreviewer) isAllowed(target *url.URL) bool {
+	if target.Scheme != "http" && target.Scheme != "https" {
+		return false
+	}
+	host := strings.ToLower(target.Hostname())
+	if host == "" || matchesDomain(host, lp.settings.Deny) {
+		return false
+	}
+	return len(lp.settings.Allow) == 0 || matchesDomain(host, lp.settings.Allow)
+}
+
+// matchesDomain checks if the host is one of the domains or a subdomain of one
+func matchesDomain(host string, domains []string) bool {
+	for i := range domains {
+		domain := strings.ToLower(strings.TrimPrefix(domains[i], "."))
+		if host == domain || strings.HasSuffix(host, "."+domain) {
+			return true
+		}
+	}
+	return false
+}
+
+// get returns the preview for a link from the cache, fetching it if it isn't cached.  Links that don't have a preview
+// are cached too so they aren't fetched again.
+func (lp *LinkPreviewer) get(link string) (*LinkPreview, error) {
+	if preview, err := lp.cached(link); err == nil {
+		return preview, nil
+	}
+	path := lp.cachePath(link)
+	preview, err := lp.fetch(link)
+	if err != nil {
+		return nil, err
+	}
+	data, err := json.Marshal(preview)
+	if err != nil {
+		return nil, err
+	}
+	if err = os.MkdirAll(lp.directory, 0700); err != nil {
+		slog.Error("Unable to create link preview cache", "error", err)
+	} else if err = os.WriteFile(path, data, 0600); err != nil {
+		slog.Error("Unable to cache link preview", "error", err)
+	}
+	return preview, nil
+}
+
+// cachePath returns the file the preview for a link is cached in
+func (lp *LinkPreviewer) cachePath(link string) string {
+	hash := sha256.Sum256([]byte(link))
+	return filepath.Join(lp.directory, hex.EncodeToString(hash[:])+".json")
+}
+
+// cached returns the preview for a link from the cache, without fetching it
+func (lp *LinkPreviewer) cached(link string) (*LinkPreview, error) {
+	data, err := os.ReadFile(lp.cachePath(link))
+	if err != nil {
+		return nil, err
+	}
+	preview := &LinkPreview{}
+	if err = json.Unmarshal(data, preview); err != nil {
+		return nil, err
+	}
+	return preview, nil
+}
+
+// Image fetches the image shown in the cached preview of a link, so the browser loads it through the previewer with
+// the same restrictions as the preview rather than connecting to the image's host itself.  Only previews that have
+// already been fetched are used, so this can't be used to fetch anything else.
+func (lp *LinkPreviewer) Image(link string) (string, []byte, error) {
+	if lp == nil || !lp.settings.Enabled {
+		return "", nil, errors.New("link previews are disabled")
+	}
+	preview, err := lp.cached(link)
+	if err != nil || preview.Image == "" {
+		return "", nil, errors.New("no preview image")
+	}
+	target, err := url.Parse(preview.Image)
+	if err != nil || !lp.isAllowed(target) {
+		return "", nil, errors.New("preview image isn't allowed")
+	}
+	req, err := http.NewRequest(http.MethodGet, target.String(), nil)
+	if err != nil {
+		return "", nil, err
+	}
+	req.Header.Set("User-Agent", "Tithon")
+	req.Header.Set("Accept", strings.Join(previewImageTypes, ","))
+	resp, err := lp.client.Do(req)
+	if err != nil {
+		return "", nil, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode != http.StatusOK {
+		return "", nil, fmt.Errorf("unexpected status: %s", resp.Status)
+	}
+	mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if !slices.Contains(previewImageTypes, mediaType) {
+		return "", nil, fmt.Errorf("unsupported image type: %s", mediaType)
+	}
+	if resp.ContentLength > lp.settings.MaxSize {
+		return "", nil, errors.New("preview image is too large")
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, lp.settings.MaxSize+1))
+	if err != nil {
+		return "", nil, err
+	}
+	if int64(len(data)) > lp.settings.MaxSize {
+		return "", nil, errors.New("preview image is too large")
+	}
+	return mediaType, data, nil
+}
+
+// fetch downloads a link, reading at most the maximum size, and builds a preview from the page's metadata or the
+// dimensions of the image
+func (lp *LinkPreviewer) fetch(link string) (*LinkPreview, error) {
+	req, err := http.NewRequest(http.MethodGet, link, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", "Tithon")
+	req.Header.Set("Accept", "text/html,application/xhtml+xml,image/*;q=0.9")
+	resp, err := lp.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status: %s", resp.Status)
+	}
+	preview := &LinkPreview{URL: link}
+	body := io.LimitReader(resp.Body, lp.settings.MaxSize)
+	mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	switch {
+	case slices.Contains(previewImageTypes, mediaType):
+		if resp.ContentLength > lp.settings.MaxSize {
+			return preview, nil
+		}
+		preview.IsImage = true
+		preview.Image = resp.Request.URL.String()
+		if size, _, err := image.DecodeConfig(body); err == nil {
+			preview.Width, preview.Height = size.Width, size.Height
+		}
+	case mediaType == "text/html" || mediaType == "application/xhtml+xml":
+		parsePagePreview(body, resp.Request.URL, preview)
+		if target, err := url.Parse(preview.Image); preview.Image != "" && (err != nil || !lp.isAllowed(target)) {
+			preview.Image = ""
+			preview.Width, preview.Height = 0, 0
+		}
+	}
+	return preview, nil
+}
+
+// parsePagePreview reads the title and OpenGraph metadata from the head of a page
+func parsePagePreview(body io.Reader, base *url.URL, preview *LinkPreview) {
+	var title, description string
+	tokenizer := html.NewTokenizer(body)
+	for {
+		switch tokenizer.Next() {
+		case html.ErrorToken:
+			preview.finish(title, description, base)
+			return
+		case html.StartTagToken, html.SelfClosingTagToken:
+			name, hasAttr := tokenizer.TagName()
+			switch string(name) {
+			case "title":
+				if tokenizer.Next() == html.TextToken {
+					title = string(tokenizer.Text())
+				}
+			case "meta":
+				if hasAttr {
+					preview.parseMeta(tokenizer, &description)
+				}
+			case "body":
+				preview.finish(title, description, base)
+				return
+			}
+		case html.EndTagToken:
+			if name, _ := tokenizer.TagName(); string(name) == "head" {
+				preview.finish(title, description, base)
+				return
+			}
+		}
+	}
+}
+
+// parseMeta reads a single meta tag, the plain description is only used if there's no OpenGraph description
+func (p *LinkPreview) parseMeta(tokenizer *html.Tokenizer, description *string) {
+	var property, content string
+	for {
+		key, value, more := tokenizer.TagAttr()
+		switch string(key) {
+		case "property", "name":
+			property = strings.ToLower(string(value))
+		case "content":
+			content = string(value)
+		}
+		if !more {
+			break
+		}
+	}
+	switch property {
+	case "og:title":
+		p.Title = content
+	case "og:description":
+		p.Description = content
+	case "description":
+		*description = content
+	case "og:site_name":
+		p.SiteName = content
+	case "og:image", "og:image:url", "og:image:secure_url":
+		if p.Image == "" {
+			p.Image = content
+		}
+	case "twitter:image":
+		if p.Image == "" {
+			p.Image = content
+		}
+	case "og:image:width":
+		p.Width, _ = strconv.Atoi(content)
+	case "og:image:height":
+		p.Height, _ = strconv.Atoi(content)
+	}
+}
+
+// finish fills in anything the OpenGraph metadata didn't have, tidies up the whitespace and resolves the image
+func (p *LinkPreview) finish(title, description string, base *url.URL) {
+	if p.Title == "" {
+		p.Title = title
+	}
+	if p.Description == "" {
+		p.Description = description
+	}
+	p.Title = strings.Join(strings.Fields(p.Title), " ")
+	p.SiteName = strings.Join(strings.Fields(p.SiteName), " ")
+	p.Description = strings.Join(strings.Fields(p.Description), " ")
+	if runes := []rune(p.Description); len(runes) > maxPreviewDescription {
+		p.Description = string(runes[:maxPreviewDescription]) + "…"
+	}
+	if p.Image != "" {
+		resolved, err := base.Parse(p.Image)
+		if err != nil || (resolved.Scheme != "http" && resolved.Scheme != "https") {
+			p.Image = ""
+			p.Width, p.Height = 0, 0
+		} else {
+			p.Image = resolved.String()
+		}
+	}
+}
+
+func (c *Server) SetLinkPreviewer(previewer *LinkPreviewer) {
+	c.linkPreviewer = previewer
+}
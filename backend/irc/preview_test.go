@@ -0,0 +1,316 @@
+package irc
+
+import (
+	"bytes"
+	"image"
+	"image/png"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/greboid/tithon/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testPreviewer(t *testing.T, settings config.LinkPreviews) *LinkPreviewer {
+	settings.Enabled = true
+	if settings.MaxSize == 0 {
+		settings.MaxSize = 1024 * 1024
+	}
+	if settings.Timeout == 0 {
+		settings.Timeout = 5 * time.Second
+	}
+	previewer := NewLinkPreviewer(settings, t.TempDir())
+	previewer.allowPrivate = true
+	return previewer
+}
+
+func TestCheckPreviewAddress(t *testing.T) {
+	tests := []struct {
+		address string
+		wantErr bool
+	}{
+		{address: "93.184.216.34:443"},
+		{address: "[2606:2800:220:1::]:443"},
+		{address: "127.0.0.1:80", wantErr: true},
+		{address: "[::1]:80", wantErr: true},
+		{address: "10.0.0.1:80", wantErr: true},
+		{address: "192.168.1.1:80", wantErr: true},
+		{address: "172.16.0.1:80", wantErr: true},
+		{address: "169.254.169.254:80", wantErr: true},
+		{address: "[fe80::1]:80", wantErr: true},
+		{address: "[fd00::1]:80", wantErr: true},
+		{address: "0.0.0.0:80", wantErr: true},
+		{address: "224.0.0.1:80", wantErr: true},
+		{address: "[::ffff:127.0.0.1]:80", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.address, func(t *testing.T) {
+			err := checkPreviewAddress(tt.address)
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestLinkPreviewer_LocalAddresses(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		_, _ = w.Write([]byte(`<title>Local</title>`))
+	}))
+	defer server.Close()
+	previewer := NewLinkPreviewer(config.LinkPreviews{Enabled: true, MaxSize: 1024, Timeout: 5 * time.Second}, t.TempDir())
+	_, err := previewer.get(server.URL + "/page")
+	assert.Error(t, err, "local addresses shouldn't be previewed")
+}
+
+func TestLinkPreviewer_Proxy(t *testing.T) {
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		_, _ = w.Write([]byte(`<title>Proxied ` + r.URL.Host + `</title>`))
+	}))
+	defer proxy.Close()
+	proxyURL, err := url.Parse(proxy.URL)
+	require.NoError(t, err)
+	previewer := NewLinkPreviewer(config.LinkPreviews{Enabled: true, MaxSize: 1024, Timeout: 5 * time.Second}, t.TempDir())
+	previewer.proxy = http.ProxyURL(proxyURL)
+
+	preview, err := previewer.get("http://93.184.216.34/page")
+	require.NoError(t, err, "a local proxy should be allowed")
+	assert.Equal(t, "Proxied 93.184.216.34", preview.Title)
+	_, err = previewer.get("http://127.0.0.1:8080/page")
+	assert.Error(t, err, "local addresses shouldn't be previewed through a proxy")
+}
+
+func TestParsePagePreview(t *testing.T) {
+	base, _ := url.Parse("https://example.com/posts/1")
+	tests := []struct {
+		name string
+		page string
+		want LinkPreview
+	}{
+		{
+			name: "OpenGraph",
+			page: `<html><head><title>Page title</title>
+				<meta property="og:title" content="OG title">
+				<meta property="og:description" content="A   description
+					over lines">
+				<meta property="og:site_name" content="Example">
+				<meta property="og:image" content="/image.png">
+				<meta property="og:image:width" content="640">
+				<meta property="og:image:height" content="480">
+				</head><body><meta property="og:title" content="ignored"></body></html>`,
+			want: LinkPreview{
+				Title: "OG title", Description: "A description over lines", SiteName: "Example",
+				Image: "https://example.com/image.png", Width: 640, Height: 480,
+			},
+		},
+		{
+			name: "Title and description",
+			page: `<!DOCTYPE html><title> Page &amp; title </title><meta name="description" content="Plain description">`,
+			want: LinkPreview{Title: "Page & title", Description: "Plain description"},
+		},
+		{
+			name: "Unsupported image scheme",
+			page: `<meta property="og:image" content="javascript:alert(1)"><meta property="og:image:width" content="10">`,
+			want: LinkPreview{},
+		},
+		{
+			name: "Long description",
+			page: `<meta name="description" content="` + strings.Repeat("a", maxPreviewDescription+10) + `">`,
+			want: LinkPreview{Description: strings.Repeat("a", maxPreviewDescription) + "…"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			preview := &LinkPreview{}
+			parsePagePreview(strings.NewReader(tt.page), base, preview)
+			assert.Equal(t, tt.want, *preview)
+		})
+	}
+}
+
+func TestLinkPreviewer_isAllowed(t *testing.T) {
+	tests := []struct {
+		name  string
+		link  string
+		allow []string
+		deny  []string
+		want  bool
+	}{
+		{name: "Allowed by default", link: "https://example.com", want: true},
+		{name: "Not http", link: "ftp://example.com", want: false},
+		{name: "Denied", link: "https://example.com", deny: []string{"example.com"}, want: false},
+		{name: "Denied subdomain", link: "https://www.EXAMPLE.com", deny: []string{"example.com"}, want: false},
+		{name: "Denied suffix isn't a subdomain", link: "https://badexample.com", deny: []string{"example.com"}, want: true},
+		{name: "Allowed", link: "https://www.example.com", allow: []string{".example.com"}, want: true},
+		{name: "Not allowed", link: "https://example.org", allow: []string{"example.com"}, want: false},
+		{name: "Deny beats allow", link: "https://a.example.com", allow: []string{"example.com"}, deny: []string{"a.example.com"}, want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			previewer := testPreviewer(t, config.LinkPreviews{Allow: tt.allow, Deny: tt.deny})
+			target, err := url.Parse(tt.link)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, previewer.isAllowed(target))
+		})
+	}
+}
+
+func TestLinkPreviewer_get(t *testing.T) {
+	var buffer bytes.Buffer
+	require.NoError(t, png.Encode(&buffer, image.NewRGBA(image.Rect(0, 0, 30, 20))))
+	var requests atomic.Int32
+	mux := http.NewServeMux()
+	mux.HandleFunc("/page", func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		_, _ = w.Write([]byte(`<title>Test page</title>`))
+	})
+	mux.HandleFunc("/image.png", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "image/png")
+		_, _ = w.Write(buffer.Bytes())
+	})
+	mux.HandleFunc("/redirect", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/image.png", http.StatusFound)
+	})
+	mux.HandleFunc("/missing", http.NotFound)
+	mux.HandleFunc("/external", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		_, _ = w.Write([]byte(`<title>External</title><meta property="og:image" content="https://denied.example/image.png">`))
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+	previewer := testPreviewer(t, config.LinkPreviews{Deny: []string{"denied.example"}})
+
+	preview, err := previewer.get(server.URL + "/page")
+	require.NoError(t, err)
+	assert.Equal(t, &LinkPreview{URL: server.URL + "/page", Title: "Test page"}, preview)
+	_, err = previewer.get(server.URL + "/page")
+	require.NoError(t, err)
+	assert.Equal(t, int32(1), requests.Load(), "previews should be cached")
+
+	preview, err = previewer.get(server.URL + "/redirect")
+	require.NoError(t, err)
+	assert.Equal(t, &LinkPreview{URL: server.URL + "/redirect", Image: server.URL + "/image.png", Width: 30, Height: 20, IsImage: true}, preview)
+
+	_, err = previewer.get(server.URL + "/missing")
+	assert.Error(t, err)
+
+	preview, err = previewer.get(server.URL + "/external")
+	require.NoError(t, err)
+	assert.Equal(t, &LinkPreview{URL: server.URL + "/external", Title: "External"}, preview, "denied images should be dropped")
+
+	small := testPreviewer(t, config.LinkPreviews{MaxSize: 10})
+	preview, err = small.get(server.URL + "/image.png")
+	require.NoError(t, err)
+	assert.True(t, preview.IsEmpty(), "images larger than the maximum size shouldn't be previewed")
+
+	denied := testPreviewer(t, config.LinkPreviews{Deny: []string{"127.0.0.1"}})
+	_, err = denied.get(server.URL + "/redirect")
+	assert.Error(t, err, "redirects to denied domains shouldn't be followed")
+}
+
+func TestLinkPreviewer_Image(t *testing.T) {
+	var buffer bytes.Buffer
+	require.NoError(t, png.Encode(&buffer, image.NewRGBA(image.Rect(0, 0, 30, 20))))
+	mux := http.NewServeMux()
+	mux.HandleFunc("/page", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		_, _ = w.Write([]byte(`<meta property="og:image" content="/image.png">`))
+	})
+	mux.HandleFunc("/svg", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		_, _ = w.Write([]byte(`<meta property="og:image" content="/image.svg">`))
+	})
+	mux.HandleFunc("/image.png", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "image/png")
+		_, _ = w.Write(buffer.Bytes())
+	})
+	mux.HandleFunc("/image.svg", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "image/svg+xml")
+		_, _ = w.Write([]byte(`<svg xmlns="http://www.w3.org/2000/svg"><script>alert(1)</script></svg>`))
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+	previewer := testPreviewer(t, config.LinkPreviews{})
+
+	_, _, err := previewer.Image(server.URL + "/page")
+	assert.Error(t, err, "images should only be served for cached previews")
+	_, err = previewer.get(server.URL + "/page")
+	require.NoError(t, err)
+	contentType, data, err := previewer.Image(server.URL + "/page")
+	require.NoError(t, err)
+	assert.Equal(t, "image/png", contentType)
+	assert.Equal(t, buffer.Bytes(), data)
+
+	_, err = previewer.get(server.URL + "/svg")
+	require.NoError(t, err)
+	_, _, err = previewer.Image(server.URL + "/svg")
+	assert.Error(t, err, "SVG images shouldn't be served")
+
+	small := testPreviewer(t, config.LinkPreviews{MaxSize: 10})
+	small.directory = previewer.directory
+	_, _, err = small.Image(server.URL + "/page")
+	assert.Error(t, err, "images larger than the maximum size shouldn't be served")
+
+	var disabled *LinkPreviewer
+	_, _, err = disabled.Image(server.URL + "/page")
+	assert.Error(t, err)
+}
+
+type testUpdateTrigger struct {
+	updates chan struct{}
+}
+
+func (t *testUpdateTrigger) SetPendingUpdate() {
+	t.updates <- struct{}{}
+}
+
+func TestLinkPreviewer_Preview(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		_, _ = w.Write([]byte(`<title>` + r.URL.Path + `</title>`))
+	}))
+	defer server.Close()
+	previewer := testPreviewer(t, config.LinkPreviews{})
+	trigger := &testUpdateTrigger{updates: make(chan struct{}, 10)}
+
+	message := NewMessage("15:04", false, "alice", "see "+server.URL+"/one and "+server.URL+"/two", nil, nil)
+	previewer.Preview(message, trigger)
+	for range 2 {
+		select {
+		case <-trigger.updates:
+		case <-time.After(5 * time.Second):
+			require.Fail(t, "timed out waiting for previews")
+		}
+	}
+	previews := message.GetPreviews()
+	require.Len(t, previews, 2)
+	assert.Equal(t, "/one", previews[0].Title)
+	assert.Equal(t, "/two", previews[1].Title)
+
+	history := NewMessage("15:04", false, "alice", server.URL+"/three", map[string]string{"chathistory": "true"}, nil)
+	previewer.Preview(history, trigger)
+	var disabled *LinkPreviewer
+	disabled.Preview(NewMessage("15:04", false, "alice", server.URL+"/four", nil, nil), trigger)
+	select {
+	case <-trigger.updates:
+		assert.Fail(t, "history and disabled previewers shouldn't fetch previews")
+	case <-time.After(100 * time.Millisecond):
+	}
+	assert.Empty(t, history.GetPreviews())
+}
+
+func TestMessage_GetLinks(t *testing.T) {
+	message := NewMessage("15:04", false, "alice", "https://exam\x02ple.com\x02 and example.org and https://example.com", nil, nil)
+	assert.Equal(t, []string{"https://example.com", "https://example.org"}, message.GetLinks())
+}
@@ -0,0 +1,158 @@
+package irc
+
+import (
+	"bufio"
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"github.com/greboid/tithon/config"
+	"golang.org/x/net/proxy"
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+var ErrProxyAuth = errors.New("proxy authentication required")
+
+type DialContextFunc func(ctx context.Context, network, addr string) (net.Conn, error)
+
+// NewProxyDialer returns a dial function that connects via the given proxy URL, nil is returned when
+// no proxy should be used.
+func NewProxyDialer(proxyURL string) (DialContextFunc, error) {
+	if proxyURL == "" || proxyURL == config.ProxyDirect {
+		return nil, nil
+	}
+	if err := config.ValidateProxy(proxyURL); err != nil {
+		return nil, err
+	}
+	u, err := url.Parse(proxyURL)
+	if err != nil {
+		return nil, err
+	}
+	forward := &net.Dialer{}
+	switch u.Scheme {
+	case "http":
+		if u.Port() == "" {
+			u.Host = net.JoinHostPort(u.Hostname(), "8080")
+		}
+		return (&httpProxyDialer{proxy: u, forward: forward}).DialContext, nil
+	default:
+		if u.Port() == "" {
+			u.Host = net.JoinHostPort(u.Hostname(), "1080")
+		}
+		dialer, err := proxy.FromURL(u, forward)
+		if err != nil {
+			return nil, err
+		}
+		contextDialer, ok := dialer.(proxy.ContextDialer)
+		if !ok {
+			return nil, fmt.Errorf("unsupported proxy scheme %q", u.Scheme)
+		}
+		return (&socksProxyDialer{
+			proxy:         u,
+			dialer:        contextDialer,
+			remoteResolve: u.Scheme == "socks5h",
+		}).DialContext, nil
+	}
+}
+
+// FailingDialer returns a dial function that always fails with the given error, used to surface proxy
+// configuration errors when connecting
+func FailingDialer(err error) DialContextFunc {
+	return func(_ context.Context, _, _ string) (net.Conn, error) {
+		return nil, err
+	}
+}
+
+type socksProxyDialer struct {
+	proxy         *url.URL
+	dialer        proxy.ContextDialer
+	remoteResolve bool
+}
+
+func (d *socksProxyDialer) DialContext(ctx context.Context, network, addr string) (net.Conn, error) {
+	if !d.remoteResolve {
+		// socks5:// resolves locally, socks5h:// leaves it to the proxy (needed for .onion addresses)
+		host, port, err := net.SplitHostPort(addr)
+		if err != nil {
+			return nil, err
+		}
+		addrs, err := net.DefaultResolver.LookupHost(ctx, host)
+		if err != nil {
+			return nil, fmt.Errorf("unable to resolve %s: %w", host, err)
+		}
+		addr = net.JoinHostPort(addrs[0], port)
+	}
+	conn, err := d.dialer.DialContext(ctx, network, addr)
+	if err != nil {
+		var opErr, dialErr *net.OpError
+		if errors.As(err, &opErr) && errors.As(opErr.Err, &dialErr) && dialErr.Op == "dial" {
+			return nil, fmt.Errorf("unable to reach proxy %s: %w", d.proxy.Host, dialErr)
+		}
+		return nil, fmt.Errorf("proxy %s refused connection to %s: %w", d.proxy.Host, addr, err)
+	}
+	return conn, nil
+}
+
+type httpProxyDialer struct {
+	proxy   *url.URL
+	forward *net.Dialer
+}
+
+func (d *httpProxyDialer) DialContext(ctx context.Context, _, addr string) (net.Conn, error) {
+	conn, err := d.forward.DialContext(ctx, "tcp", d.proxy.Host)
+	if err != nil {
+		return nil, fmt.Errorf("unable to reach proxy %s: %w", d.proxy.Host, err)
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
+		defer func() { _ = conn.SetDeadline(time.Time{}) }()
+	}
+	req := &http.Request{
+		Method: http.MethodConnect,
+		URL:    &url.URL{Opaque: addr},
+		Host:   addr,
+		Header: make(http.Header),
+	}
+	if d.proxy.User != nil {
+		password, _ := d.proxy.User.Password()
+		credentials := base64.StdEncoding.EncodeToString([]byte(d.proxy.User.Username() + ":" + password))
+		req.Header.Set("Proxy-Authorization", "Basic "+credentials)
+	}
+	if err = req.Write(conn); err != nil {
+		_ = conn.Close()
+		return nil, fmt.Errorf("unable to send request to proxy %s: %w", d.proxy.Host, err)
+	}
+	reader := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(reader, req)
+	if err != nil {
+		_ = conn.Close()
+		return nil, fmt.Errorf("invalid response from proxy %s: %w", d.proxy.Host, err)
+	}
+	_ = resp.Body.Close()
+	switch {
+	case resp.StatusCode == http.StatusProxyAuthRequired:
+		_ = conn.Close()
+		return nil, fmt.Errorf("proxy %s refused connection to %s: %w", d.proxy.Host, addr, ErrProxyAuth)
+	case resp.StatusCode != http.StatusOK:
+		_ = conn.Close()
+		return nil, fmt.Errorf("proxy %s refused connection to %s: %s", d.proxy.Host, addr, resp.Status)
+	}
+	if reader.Buffered() > 0 {
+		return &bufferedConn{Conn: conn, reader: reader}, nil
+	}
+	return conn, nil
+}
+
+// bufferedConn returns any data the server sent straight after the CONNECT response before
+// reading from the underlying connection
+type bufferedConn struct {
+	net.Conn
+	reader *bufio.Reader
+}
+
+func (c *bufferedConn) Read(b []byte) (int, error) {
+	return c.reader.Read(b)
+}
@@ -0,0 +1,173 @@
+package irc
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func startHTTPProxy(t *testing.T, status int, wantAuth string) (string, chan *http.Request) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = listener.Close() })
+	requests := make(chan *http.Request, 1)
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer func() { _ = conn.Close() }()
+		req, err := http.ReadRequest(bufio.NewReader(conn))
+		if err != nil {
+			return
+		}
+		requests <- req
+		if wantAuth != "" && req.Header.Get("Proxy-Authorization") != wantAuth {
+			_, _ = io.WriteString(conn, "HTTP/1.1 407 Proxy Authentication Required\r\n\r\n")
+			return
+		}
+		_, _ = fmt.Fprintf(conn, "HTTP/1.1 %d %s\r\n\r\n", status, http.StatusText(status))
+		if status == http.StatusOK {
+			_, _ = io.WriteString(conn, ":irc.example.com NOTICE * :hello\r\n")
+		}
+	}()
+	return listener.Addr().String(), requests
+}
+
+func startSOCKSProxy(t *testing.T, reply byte) string {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = listener.Close() })
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer func() { _ = conn.Close() }()
+		greeting := make([]byte, 3)
+		if _, err = io.ReadFull(conn, greeting); err != nil {
+			return
+		}
+		_, _ = conn.Write([]byte{0x05, 0x00})
+		request := make([]byte, 512)
+		if _, err = conn.Read(request); err != nil {
+			return
+		}
+		_, _ = conn.Write([]byte{0x05, reply, 0x00, 0x01, 0, 0, 0, 0, 0, 0})
+	}()
+	return listener.Addr().String()
+}
+
+func TestNewProxyDialer_NoProxy(t *testing.T) {
+	for _, proxyURL := range []string{"", "direct"} {
+		dialer, err := NewProxyDialer(proxyURL)
+		assert.NoError(t, err)
+		assert.Nil(t, dialer)
+	}
+}
+
+func TestNewProxyDialer_Invalid(t *testing.T) {
+	tests := []struct {
+		name     string
+		proxyURL string
+	}{
+		{name: "Unsupported scheme", proxyURL: "ftp://proxy.example.com"},
+		{name: "No scheme", proxyURL: "proxy.example.com:1080"},
+		{name: "No host", proxyURL: "socks5://"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dialer, err := NewProxyDialer(tt.proxyURL)
+			assert.Error(t, err)
+			assert.Nil(t, dialer)
+		})
+	}
+}
+
+func TestHTTPProxyDialer(t *testing.T) {
+	tests := []struct {
+		name      string
+		status    int
+		userinfo  string
+		wantAuth  string
+		wantError string
+	}{
+		{
+			name:   "Successful connect",
+			status: http.StatusOK,
+		},
+		{
+			name:     "Successful connect with auth",
+			status:   http.StatusOK,
+			userinfo: "user:pass@",
+			wantAuth: "Basic dXNlcjpwYXNz",
+		},
+		{
+			name:      "Missing auth",
+			status:    http.StatusOK,
+			wantAuth:  "Basic dXNlcjpwYXNz",
+			wantError: "proxy authentication required",
+		},
+		{
+			name:      "Proxy refuses",
+			status:    http.StatusForbidden,
+			wantError: "refused connection to irc.example.com:6697: 403",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			addr, requests := startHTTPProxy(t, tt.status, tt.wantAuth)
+			dialer, err := NewProxyDialer("http://" + tt.userinfo + addr)
+			require.NoError(t, err)
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			conn, err := dialer(ctx, "tcp", "irc.example.com:6697")
+			req := <-requests
+			assert.Equal(t, http.MethodConnect, req.Method)
+			assert.Equal(t, "irc.example.com:6697", req.Host)
+			if tt.wantError != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.wantError)
+				return
+			}
+			require.NoError(t, err)
+			defer func() { _ = conn.Close() }()
+			line, err := bufio.NewReader(conn).ReadString('\n')
+			require.NoError(t, err)
+			assert.Equal(t, ":irc.example.com NOTICE * :hello\r\n", line)
+		})
+	}
+}
+
+func TestSOCKSProxyDialer_Refused(t *testing.T) {
+	addr := startSOCKSProxy(t, 0x02)
+	dialer, err := NewProxyDialer("socks5h://" + addr)
+	require.NoError(t, err)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	_, err = dialer(ctx, "tcp", "example.onion:6697")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "proxy "+addr+" refused connection to example.onion:6697")
+}
+
+func TestSOCKSProxyDialer_Unreachable(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	addr := listener.Addr().String()
+	_ = listener.Close()
+	dialer, err := NewProxyDialer("socks5h://" + addr)
+	require.NoError(t, err)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	_, err = dialer(ctx, "tcp", "irc.example.com:6697")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "unable to reach proxy "+addr)
+}
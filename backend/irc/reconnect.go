@@ -0,0 +1,172 @@
+package irc
+
+import (
+	"fmt"
+	"log/slog"
+	"math/rand/v2"
+	"strings"
+	"time"
+)
+
+const pingTokenPrefix = "tithon-"
+
+// fatalErrorPatterns are phrases in an ERROR message that mean reconnecting will not help
+var fatalErrorPatterns = []string{
+	"k-lined",
+	"g-lined",
+	"z-lined",
+	"d-lined",
+	"k-line",
+	"g-line",
+	"z-line",
+	"d-line",
+	"kline",
+	"gline",
+	"zline",
+	"dline",
+	"akill",
+	"banned",
+	"bad password",
+	"password incorrect",
+	"password mismatch",
+}
+
+// isFatalError checks if an ERROR message indicates the client has been banned or rejected
+func isFatalError(message string) bool {
+	message = strings.ToLower(message)
+	for _, pattern := range fatalErrorPatterns {
+		if strings.Contains(message, pattern) {
+			return true
+		}
+	}
+	return false
+}
+
+// reconnectDelay calculates how long to wait before a reconnection attempt.  The delay doubles from minDelay up to
+// maxDelay for each full rotation through the servers, with up to half of it randomised so that clients don't all
+// reconnect at the same time after a netsplit or server restart.
+func reconnectDelay(attempt int, servers int, minDelay time.Duration, maxDelay time.Duration, random func(time.Duration) time.Duration) time.Duration {
+	if servers < 1 {
+		servers = 1
+	}
+	rounds := (attempt - 1) / servers
+	delay := minDelay
+	for i := 0; i < rounds && delay < maxDelay; i++ {
+		delay *= 2
+	}
+	if delay > maxDelay {
+		delay = maxDelay
+	}
+	if delay <= 0 {
+		return 0
+	}
+	half := delay / 2
+	return half + random(delay-half)
+}
+
+func randomDuration(n time.Duration) time.Duration {
+	if n <= 0 {
+		return 0
+	}
+	return rand.N(n)
+}
+
+// setFatalError stops any further reconnection attempts until the user manually reconnects
+func (c *Server) setFatalError(reason string) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.fatalError = reason
+}
+
+// startPingLoop begins checking the connection is alive and measuring lag, replacing any existing check
+func (c *Server) startPingLoop() {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if c.pingStop != nil {
+		close(c.pingStop)
+		c.pingStop = nil
+	}
+	c.pingToken = ""
+	if c.settings.PingInterval <= 0 {
+		return
+	}
+	c.pingStop = make(chan struct{})
+	go c.pingLoop(c.pingStop, c.settings.PingInterval)
+}
+
+func (c *Server) stopPingLoop() {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if c.pingStop != nil {
+		close(c.pingStop)
+		c.pingStop = nil
+	}
+	c.pingToken = ""
+}
+
+func (c *Server) pingLoop(stop chan struct{}, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	// Measure the lag straight away rather than waiting for the first tick
+	c.checkLiveness()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			c.checkLiveness()
+		}
+	}
+}
+
+// checkLiveness sends a PING to the server, or forces a reconnect if the previous one hasn't been answered in time
+func (c *Server) checkLiveness() {
+	c.mutex.Lock()
+	if c.pingToken != "" {
+		if time.Since(c.pingSent) < c.settings.PingTimeout {
+			c.mutex.Unlock()
+			return
+		}
+		c.pingToken = ""
+		c.mutex.Unlock()
+		defer c.ut.SetPendingUpdate()
+		c.AddMessage(NewError(c.timestampFormat, false, fmt.Sprintf("No response from server in %v, reconnecting", c.settings.PingTimeout)))
+		c.connection.Reconnect()
+		return
+	}
+	token := fmt.Sprintf("%s%d", pingTokenPrefix, time.Now().UnixNano())
+	c.pingToken = token
+	c.pingSent = time.Now()
+	c.mutex.Unlock()
+	err := c.sendQueue.Send(PriorityHigh, func() error {
+		c.setPingSent(token)
+		return c.connection.Send("PING", token)
+	})
+	if err != nil {
+		slog.Debug("Unable to send liveness check", "error", err)
+	}
+}
+
+// setPingSent records when the PING was written, it may have waited in the send queue so the lag only includes the
+// time the server took to reply
+func (c *Server) setPingSent(token string) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if c.pingToken == token {
+		c.pingSent = time.Now()
+	}
+}
+
+// handlePong clears the outstanding liveness check if the PONG is a reply to it
+func (c *Server) handlePong(token string) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if c.pingToken == "" || token != c.pingToken {
+		return
+	}
+	c.pingToken = ""
+	c.setLag(time.Since(c.pingSent))
+	if c.ut != nil {
+		c.ut.SetPendingUpdate()
+	}
+}
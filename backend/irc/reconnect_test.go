@@ -0,0 +1,91 @@
+package irc
+
+import (
+	"testing"
+	"time"
+
+	"github.com/greboid/tithon/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIsFatalError(t *testing.T) {
+	tests := []struct {
+		name    string
+		message string
+		want    bool
+	}{
+		{name: "K-lined", message: "Closing Link: host (K-Lined: spamming)", want: true},
+		{name: "G-lined", message: "Closing Link: host (G-lined)", want: true},
+		{name: "Banned", message: "You are banned from this server", want: true},
+		{name: "Bad password", message: "Closing Link: host (Bad Password)", want: true},
+		{name: "Ping timeout", message: "Closing Link: host (Ping timeout: 240 seconds)", want: false},
+		{name: "Quit", message: "Closing Link: host (Quit: bye)", want: false},
+		{name: "Empty", message: "", want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, isFatalError(tt.message))
+		})
+	}
+}
+
+func TestReconnectDelay(t *testing.T) {
+	noJitter := func(time.Duration) time.Duration { return 0 }
+	fullJitter := func(n time.Duration) time.Duration { return n }
+	tests := []struct {
+		name    string
+		attempt int
+		servers int
+		random  func(time.Duration) time.Duration
+		want    time.Duration
+	}{
+		{name: "First attempt", attempt: 1, servers: 1, random: fullJitter, want: 2 * time.Second},
+		{name: "First attempt without jitter", attempt: 1, servers: 1, random: noJitter, want: time.Second},
+		{name: "Doubles each attempt", attempt: 3, servers: 1, random: fullJitter, want: 8 * time.Second},
+		{name: "Capped at max", attempt: 10, servers: 1, random: fullJitter, want: time.Minute},
+		{name: "Same delay within a rotation", attempt: 3, servers: 3, random: fullJitter, want: 2 * time.Second},
+		{name: "Doubles after a rotation", attempt: 4, servers: 3, random: fullJitter, want: 4 * time.Second},
+		{name: "No servers", attempt: 2, servers: 0, random: fullJitter, want: 4 * time.Second},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, reconnectDelay(tt.attempt, tt.servers, 2*time.Second, time.Minute, tt.random))
+		})
+	}
+}
+
+func TestReconnectDelay_Jitter(t *testing.T) {
+	for range 100 {
+		delay := reconnectDelay(1, 1, 10*time.Second, time.Minute, randomDuration)
+		assert.GreaterOrEqual(t, delay, 5*time.Second)
+		assert.Less(t, delay, 10*time.Second)
+	}
+}
+
+func TestServer_HandlePong(t *testing.T) {
+	server := NewServer("15:04:05", "test", []config.ServerAddress{{Hostname: "irc.example.com", Port: 6697, TLS: true}}, false, "", "", "", "", config.Connection{}, NewProfile("nick"), nil, nil)
+	server.pingToken = "tithon-1"
+	server.pingSent = time.Now().Add(-100 * time.Millisecond)
+
+	server.handlePong("KeepAlive-1")
+	assert.Equal(t, "tithon-1", server.pingToken)
+
+	assert.Zero(t, server.GetConnectionStatus().Lag)
+
+	server.handlePong("tithon-1")
+	assert.Empty(t, server.pingToken)
+	assert.GreaterOrEqual(t, server.GetConnectionStatus().Lag, 100*time.Millisecond)
+}
+
+func TestServer_SetPingSent(t *testing.T) {
+	server := NewServer("15:04:05", "test", []config.ServerAddress{{Hostname: "irc.example.com", Port: 6697, TLS: true}}, false, "", "", "", "", config.Connection{}, NewProfile("nick"), nil, nil)
+	queued := time.Now().Add(-time.Minute)
+	server.pingToken = "tithon-1"
+	server.pingSent = queued
+
+	server.setPingSent("tithon-0")
+	assert.Equal(t, queued, server.pingSent, "an old PING shouldn't change when the current one was sent")
+
+	server.setPingSent("tithon-1")
+	assert.WithinDuration(t, time.Now(), server.pingSent, time.Second)
+}
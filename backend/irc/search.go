@@ -0,0 +1,172 @@
+package irc
+
+import (
+	"errors"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// SearchQuery filters the messages returned by a search, empty fields match everything
+type SearchQuery struct {
+	Text string
+	// Regex treats Text as a case-insensitive regular expression rather than plain text
+	Regex bool
+	Nick  string
+	// Network matches either the ID or the name of a server
+	Network string
+	// Window matches the name of a channel, query or server window
+	Window string
+	// In limits the search to a single window
+	In   *Window
+	From time.Time
+	To   time.Time
+	// Limit is the maximum number of results, the most recent matches are kept
+	Limit int
+}
+
+type SearchResult struct {
+	Message *Message
+	Window  *Window
+}
+
+type searchEntry struct {
+	message *Message
+	window  *Window
+	text    string
+	lower   string
+	nick    string
+}
+
+// SearchIndex stores the plain text of every message added to a server window so they can be searched without
+// having to strip the formatting from each message again
+type SearchIndex struct {
+	mutex   sync.RWMutex
+	entries []searchEntry
+}
+
+func NewSearchIndex() *SearchIndex {
+	return &SearchIndex{}
+}
+
+// Add indexes a message, events and errors are skipped so that search output doesn't end up in later searches
+func (si *SearchIndex) Add(window *Window, message *Message) {
+	if si == nil {
+		return
+	}
+	switch message.GetType() {
+	case Event, Error:
+		return
+	}
+	text := message.GetPlainDisplayMessage()
+	entry := searchEntry{
+		message: message,
+		window:  window,
+		text:    text,
+		lower:   strings.ToLower(text),
+		nick:    strings.ToLower(message.GetNickname()),
+	}
+	si.mutex.Lock()
+	defer si.mutex.Unlock()
+	si.entries = append(si.entries, entry)
+}
+
+// RemoveWindow removes all the messages from a window that has been closed
+func (si *SearchIndex) RemoveWindow(window *Window) {
+	if si == nil {
+		return
+	}
+	si.mutex.Lock()
+	defer si.mutex.Unlock()
+	si.entries = slices.DeleteFunc(si.entries, func(entry searchEntry) bool {
+		return entry.window == window
+	})
+}
+
+// RemoveServer removes all the messages from a server that has been removed
+func (si *SearchIndex) RemoveServer(server *Server) {
+	if si == nil {
+		return
+	}
+	si.mutex.Lock()
+	defer si.mutex.Unlock()
+	si.entries = slices.DeleteFunc(si.entries, func(entry searchEntry) bool {
+		return entry.window.GetServer() == server
+	})
+}
+
+// Search returns the messages matching the query in the order they were received
+func (si *SearchIndex) Search(query SearchQuery) ([]SearchResult, error) {
+	if si == nil {
+		return nil, nil
+	}
+	matchText, err := textMatcher(query.Text, query.Regex)
+	if err != nil {
+		return nil, err
+	}
+	nick := strings.ToLower(query.Nick)
+	si.mutex.RLock()
+	defer si.mutex.RUnlock()
+	var results []SearchResult
+	for i := range si.entries {
+		entry := si.entries[i]
+		if query.In != nil && entry.window != query.In {
+			continue
+		}
+		if nick != "" && entry.nick != nick {
+			continue
+		}
+		if query.Window != "" && !strings.EqualFold(entry.window.GetName(), query.Window) {
+			continue
+		}
+		if query.Network != "" && !matchesNetwork(entry.window.GetServer(), query.Network) {
+			continue
+		}
+		if !query.From.IsZero() && entry.message.GetTime().Before(query.From) {
+			continue
+		}
+		if !query.To.IsZero() && !entry.message.GetTime().Before(query.To) {
+			continue
+		}
+		if !matchText(entry) {
+			continue
+		}
+		results = append(results, SearchResult{Message: entry.message, Window: entry.window})
+	}
+	if query.Limit > 0 && len(results) > query.Limit {
+		results = results[len(results)-query.Limit:]
+	}
+	return results, nil
+}
+
+func textMatcher(text string, regex bool) (func(searchEntry) bool, error) {
+	if regex {
+		if text == "" {
+			return nil, errors.New("no regex specified")
+		}
+		pattern, err := regexp.Compile("(?i)" + text)
+		if err != nil {
+			return nil, err
+		}
+		return func(entry searchEntry) bool {
+			return pattern.MatchString(entry.text)
+		}, nil
+	}
+	text = strings.ToLower(text)
+	return func(entry searchEntry) bool {
+		return strings.Contains(entry.lower, text)
+	}, nil
+}
+
+func matchesNetwork(server *Server, network string) bool {
+	if server == nil {
+		return false
+	}
+	return server.GetID() == network || strings.EqualFold(server.GetName(), network)
+}
+
+func (c *Server) SetSearchIndex(index *SearchIndex) {
+	c.searchIndex = index
+}
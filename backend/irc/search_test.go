@@ -0,0 +1,84 @@
+package irc
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSearchIndex_Search(t *testing.T) {
+	index := NewSearchIndex()
+	libera := &Server{Window: &Window{id: "libera", name: "irc.libera.chat"}, searchIndex: index}
+	oftc := &Server{Window: &Window{id: "oftc", name: "irc.oftc.net"}, searchIndex: index}
+	general := &Window{id: "c1", name: "#general", connection: libera, isChannel: true}
+	random := &Window{id: "c2", name: "#random", connection: libera, isChannel: true}
+	other := &Window{id: "c3", name: "#general", connection: oftc, isChannel: true}
+
+	add := func(window *Window, nick string, text string, at time.Time) *Message {
+		message := NewMessage("15:04:05", false, nick, text, map[string]string{"time": at.UTC().Format(v3TimestampFormat)}, nil)
+		window.AddMessage(message)
+		return message
+	}
+	day1 := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	day2 := day1.AddDate(0, 0, 1)
+	first := add(general, "alice", "Hello world", day1)
+	second := add(random, "bob", "hello again", day1)
+	third := add(other, "alice", "goodbye world", day2)
+	general.AddMessage(NewEvent(EventJoin, "15:04:05", false, "hello joined"))
+
+	tests := []struct {
+		name  string
+		query SearchQuery
+		want  []*Message
+	}{
+		{name: "Text is case insensitive", query: SearchQuery{Text: "HELLO"}, want: []*Message{first, second}},
+		{name: "Regex", query: SearchQuery{Text: `^(hello|goodbye) world$`, Regex: true}, want: []*Message{first, third}},
+		{name: "Nick", query: SearchQuery{Nick: "Alice"}, want: []*Message{first, third}},
+		{name: "Window name", query: SearchQuery{Window: "#GENERAL"}, want: []*Message{first, third}},
+		{name: "Network", query: SearchQuery{Window: "#general", Network: "oftc"}, want: []*Message{third}},
+		{name: "Single window", query: SearchQuery{In: random}, want: []*Message{second}},
+		{name: "From date", query: SearchQuery{From: day2}, want: []*Message{third}},
+		{name: "To date", query: SearchQuery{To: day2}, want: []*Message{first, second}},
+		{name: "Limit keeps most recent", query: SearchQuery{Limit: 2}, want: []*Message{second, third}},
+		{name: "No matches", query: SearchQuery{Text: "missing"}, want: nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			results, err := index.Search(tt.query)
+			require.NoError(t, err)
+			var got []*Message
+			for i := range results {
+				got = append(got, results[i].Message)
+				assert.Equal(t, results[i].Message.GetWindow(), results[i].Window)
+			}
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestSearchIndex_InvalidRegex(t *testing.T) {
+	_, err := NewSearchIndex().Search(SearchQuery{Text: "[", Regex: true})
+	assert.Error(t, err)
+}
+
+func TestSearchIndex_Remove(t *testing.T) {
+	index := NewSearchIndex()
+	server := &Server{Window: &Window{id: "libera", name: "irc.libera.chat"}, searchIndex: index}
+	channel := &Window{id: "c1", name: "#general", connection: server, isChannel: true}
+	query := &Window{id: "q1", name: "bob", connection: server, isQuery: true}
+	channel.AddMessage(NewMessage("15:04:05", false, "alice", "hello", nil, nil))
+	query.AddMessage(NewMessage("15:04:05", false, "bob", "hello", nil, nil))
+
+	index.RemoveWindow(channel)
+	results, err := index.Search(SearchQuery{Text: "hello"})
+	require.NoError(t, err)
+	require.Len(t, results, 1)
+	assert.Equal(t, query, results[0].Window)
+
+	index.RemoveServer(server)
+	results, err = index.Search(SearchQuery{Text: "hello"})
+	require.NoError(t, err)
+	assert.Empty(t, results)
+}
@@ -0,0 +1,191 @@
+package irc
+
+import (
+	"log/slog"
+	"sync"
+	"time"
+)
+
+type SendPriority int
+
+const (
+	// PriorityInteractive is used for things the user has typed, they are sent before any bulk messages
+	PriorityInteractive SendPriority = iota
+	// PriorityBulk is used for pastes and commands that generate lots of lines
+	PriorityBulk
+	// PriorityHigh is used for lines that keep the connection alive, such as liveness PINGs, they are sent before
+	// anything the user has typed
+	PriorityHigh
+)
+
+// SendQueue throttles outgoing lines using a token bucket so that pasting lots of lines doesn't get the client
+// disconnected for flooding.  Lines are sent straight away while there are tokens available, otherwise they are
+// queued and sent in priority order as tokens are refilled.
+type SendQueue struct {
+	mutex       sync.Mutex
+	burst       int
+	interval    time.Duration
+	tokens      float64
+	lastRefill  time.Time
+	high        []func() error
+	interactive []func() error
+	bulk        []func() error
+	running     bool
+	wake        chan struct{}
+	onChange    func()
+	onError     func(error)
+	now         func() time.Time
+}
+
+// NewSendQueue creates a queue allowing burst lines to be sent at once, then one line every interval. A zero or
+// negative interval disables throttling.  onError is called when a line that had to be queued fails to send.
+func NewSendQueue(burst int, interval time.Duration, onChange func(), onError func(error)) *SendQueue {
+	burst = max(burst, 1)
+	return &SendQueue{
+		burst:      burst,
+		interval:   interval,
+		tokens:     float64(burst),
+		lastRefill: time.Now(),
+		wake:       make(chan struct{}, 1),
+		onChange:   onChange,
+		onError:    onError,
+		now:        time.Now,
+	}
+}
+
+// Send sends the line immediately if the rate limit allows it and nothing is waiting, returning any error, otherwise
+// the line is queued
+func (q *SendQueue) Send(priority SendPriority, send func() error) error {
+	q.mutex.Lock()
+	q.refill()
+	if q.len() == 0 && q.takeToken() {
+		q.mutex.Unlock()
+		return send()
+	}
+	switch priority {
+	case PriorityHigh:
+		q.high = append(q.high, send)
+	case PriorityInteractive:
+		q.interactive = append(q.interactive, send)
+	default:
+		q.bulk = append(q.bulk, send)
+	}
+	if !q.running {
+		q.running = true
+		go q.run()
+	}
+	q.mutex.Unlock()
+	q.changed()
+	return nil
+}
+
+// Len returns the number of lines waiting to be sent
+func (q *SendQueue) Len() int {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	return q.len()
+}
+
+// len returns the number of lines waiting to be sent, the mutex must be held
+func (q *SendQueue) len() int {
+	return len(q.high) + len(q.interactive) + len(q.bulk)
+}
+
+// Clear discards any lines waiting to be sent, returning how many were removed
+func (q *SendQueue) Clear() int {
+	q.mutex.Lock()
+	count := q.len()
+	q.high = nil
+	q.interactive = nil
+	q.bulk = nil
+	q.mutex.Unlock()
+	select {
+	case q.wake <- struct{}{}:
+	default:
+	}
+	if count > 0 {
+		q.changed()
+	}
+	return count
+}
+
+func (q *SendQueue) run() {
+	for {
+		q.mutex.Lock()
+		q.refill()
+		if q.len() == 0 {
+			q.running = false
+			q.mutex.Unlock()
+			return
+		}
+		if !q.takeToken() {
+			wait := q.untilNextToken()
+			q.mutex.Unlock()
+			timer := time.NewTimer(wait)
+			select {
+			case <-timer.C:
+			case <-q.wake:
+				timer.Stop()
+			}
+			continue
+		}
+		send := q.next()
+		q.mutex.Unlock()
+		if err := send(); err != nil {
+			slog.Debug("Error sending queued line", "error", err)
+			if q.onError != nil {
+				q.onError(err)
+			}
+		}
+		q.changed()
+	}
+}
+
+// next removes the highest priority line from the queue, the mutex must be held
+func (q *SendQueue) next() func() error {
+	if len(q.high) > 0 {
+		send := q.high[0]
+		q.high = q.high[1:]
+		return send
+	}
+	if len(q.interactive) > 0 {
+		send := q.interactive[0]
+		q.interactive = q.interactive[1:]
+		return send
+	}
+	send := q.bulk[0]
+	q.bulk = q.bulk[1:]
+	return send
+}
+
+// refill adds any tokens earned since the last refill, the mutex must be held
+func (q *SendQueue) refill() {
+	now := q.now()
+	if q.interval > 0 {
+		q.tokens = min(float64(q.burst), q.tokens+float64(now.Sub(q.lastRefill))/float64(q.interval))
+	}
+	q.lastRefill = now
+}
+
+// takeToken uses a token if one is available, the mutex must be held
+func (q *SendQueue) takeToken() bool {
+	if q.interval <= 0 {
+		return true
+	}
+	if q.tokens < 1 {
+		return false
+	}
+	q.tokens--
+	return true
+}
+
+// untilNextToken returns how long until a token will be available, the mutex must be held
+func (q *SendQueue) untilNextToken() time.Duration {
+	return max(time.Millisecond, time.Duration((1-q.tokens)*float64(q.interval)))
+}
+
+func (q *SendQueue) changed() {
+	if q.onChange != nil {
+		q.onChange()
+	}
+}
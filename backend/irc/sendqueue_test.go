@@ -0,0 +1,158 @@
+package irc
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type sentLines struct {
+	mutex sync.Mutex
+	lines []string
+}
+
+func (s *sentLines) sender(line string) func() error {
+	return func() error {
+		s.mutex.Lock()
+		defer s.mutex.Unlock()
+		s.lines = append(s.lines, line)
+		return nil
+	}
+}
+
+func (s *sentLines) get() []string {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return append([]string{}, s.lines...)
+}
+
+func TestSendQueue_Unthrottled(t *testing.T) {
+	sent := &sentLines{}
+	queue := NewSendQueue(1, 0, nil, nil)
+	for _, line := range []string{"one", "two", "three"} {
+		require.NoError(t, queue.Send(PriorityBulk, sent.sender(line)))
+	}
+	assert.Equal(t, []string{"one", "two", "three"}, sent.get())
+	assert.Equal(t, 0, queue.Len())
+}
+
+func TestSendQueue_ReturnsImmediateErrors(t *testing.T) {
+	queue := NewSendQueue(1, time.Hour, nil, nil)
+	err := queue.Send(PriorityInteractive, func() error {
+		return errors.New("not connected")
+	})
+	assert.EqualError(t, err, "not connected")
+}
+
+func TestSendQueue_Burst(t *testing.T) {
+	sent := &sentLines{}
+	queue := NewSendQueue(2, time.Hour, nil, nil)
+	for _, line := range []string{"one", "two", "three", "four"} {
+		require.NoError(t, queue.Send(PriorityBulk, sent.sender(line)))
+	}
+	assert.Equal(t, []string{"one", "two"}, sent.get())
+	assert.Equal(t, 2, queue.Len())
+}
+
+func TestSendQueue_InteractiveBeforeBulk(t *testing.T) {
+	sent := &sentLines{}
+	queue := NewSendQueue(1, 20*time.Millisecond, nil, nil)
+	require.NoError(t, queue.Send(PriorityBulk, sent.sender("bulk 1")))
+	require.NoError(t, queue.Send(PriorityBulk, sent.sender("bulk 2")))
+	require.NoError(t, queue.Send(PriorityBulk, sent.sender("bulk 3")))
+	require.NoError(t, queue.Send(PriorityInteractive, sent.sender("interactive")))
+	assert.Equal(t, []string{"bulk 1"}, sent.get())
+	assert.Eventually(t, func() bool {
+		return len(sent.get()) == 4
+	}, time.Second, 5*time.Millisecond)
+	assert.Equal(t, []string{"bulk 1", "interactive", "bulk 2", "bulk 3"}, sent.get())
+}
+
+func TestSendQueue_HighBeforeInteractive(t *testing.T) {
+	sent := &sentLines{}
+	queue := NewSendQueue(1, 20*time.Millisecond, nil, nil)
+	require.NoError(t, queue.Send(PriorityBulk, sent.sender("bulk")))
+	require.NoError(t, queue.Send(PriorityInteractive, sent.sender("interactive")))
+	require.NoError(t, queue.Send(PriorityHigh, sent.sender("ping")))
+	assert.Eventually(t, func() bool {
+		return len(sent.get()) == 3
+	}, time.Second, 5*time.Millisecond)
+	assert.Equal(t, []string{"bulk", "ping", "interactive"}, sent.get())
+}
+
+func TestSendQueue_QueuedErrors(t *testing.T) {
+	errs := make(chan error, 1)
+	queue := NewSendQueue(1, 10*time.Millisecond, nil, func(err error) {
+		errs <- err
+	})
+	require.NoError(t, queue.Send(PriorityInteractive, func() error { return nil }))
+	require.NoError(t, queue.Send(PriorityInteractive, func() error {
+		return errors.New("not connected")
+	}))
+	select {
+	case err := <-errs:
+		assert.EqualError(t, err, "not connected")
+	case <-time.After(time.Second):
+		assert.Fail(t, "Queued errors should be reported")
+	}
+}
+
+func TestSendQueue_Refill(t *testing.T) {
+	now := time.Now()
+	queue := NewSendQueue(2, 2*time.Second, nil, nil)
+	queue.now = func() time.Time { return now }
+	queue.lastRefill = now
+
+	queue.mutex.Lock()
+	defer queue.mutex.Unlock()
+	assert.True(t, queue.takeToken())
+	assert.True(t, queue.takeToken())
+	assert.False(t, queue.takeToken())
+	assert.Equal(t, 2*time.Second, queue.untilNextToken())
+
+	now = now.Add(time.Second)
+	queue.refill()
+	assert.False(t, queue.takeToken())
+	assert.Equal(t, time.Second, queue.untilNextToken())
+
+	now = now.Add(time.Minute)
+	queue.refill()
+	assert.True(t, queue.takeToken())
+	assert.True(t, queue.takeToken())
+	assert.False(t, queue.takeToken())
+}
+
+func TestSendQueue_DrainsQueue(t *testing.T) {
+	sent := &sentLines{}
+	changes := make(chan struct{}, 10)
+	queue := NewSendQueue(1, 10*time.Millisecond, func() {
+		select {
+		case changes <- struct{}{}:
+		default:
+		}
+	}, nil)
+	for _, line := range []string{"one", "two", "three"} {
+		require.NoError(t, queue.Send(PriorityBulk, sent.sender(line)))
+	}
+	assert.Eventually(t, func() bool {
+		return len(sent.get()) == 3
+	}, time.Second, 5*time.Millisecond)
+	assert.Equal(t, []string{"one", "two", "three"}, sent.get())
+	assert.Equal(t, 0, queue.Len())
+	assert.NotEmpty(t, changes)
+}
+
+func TestSendQueue_Clear(t *testing.T) {
+	sent := &sentLines{}
+	queue := NewSendQueue(1, time.Hour, nil, nil)
+	for _, line := range []string{"one", "two", "three"} {
+		require.NoError(t, queue.Send(PriorityBulk, sent.sender(line)))
+	}
+	assert.Equal(t, 2, queue.Clear())
+	assert.Equal(t, 0, queue.Len())
+	assert.Equal(t, []string{"one"}, sent.get())
+}
@@ -6,8 +6,12 @@ import (
 	uniqueid "github.com/albinj12/unique-id"
 	"github.com/ergochat/irc-go/ircevent"
 	"github.com/ergochat/irc-go/ircmsg"
+	"github.com/greboid/tithon/config"
 	"log/slog"
 	"maps"
+	"math/rand/v2"
+	"net"
+	"net/url"
 	"regexp"
 	"slices"
 	"strconv"
@@ -23,12 +27,12 @@ const (
 type Server struct {
 	*Window
 	connection            *ircevent.Connection
-	hostname              string
-	port                  int
-	tls                   bool
+	addresses             []config.ServerAddress
+	addressIndex          int
 	password              string
 	saslLogin             string
 	saslPassword          string
+	proxy                 string
 	preferredNickname     string
 	channels              map[string]*Channel
 	pms                   map[string]*Query
@@ -41,39 +45,72 @@ type Server struct {
 	reconnectAttempts     int
 	reconnectTimer        *time.Timer
 	manualDisconnect      bool
+	fatalError            string
+	immediateReconnect    bool
+	settings              config.Connection
+	pingToken             string
+	pingSent              time.Time
+	pingStop              chan struct{}
+	statusMutex           sync.Mutex
+	connectionState       ConnectionState
+	lag                   time.Duration
+	reconnectAt           time.Time
+	sendQueue             *SendQueue
+	ignoreList            *IgnoreList
+	eventFilter           *EventFilter
+	windowSettings        *WindowSettingsList
+	commands              *CommandManager
+	highlightRules        *HighlightRules
+	mentions              *Window
+	searchIndex           *SearchIndex
+	messageLogger         *MessageLogger
+	linkPreviewer         *LinkPreviewer
+	history               *History
 	linkRegex             *regexp.Regexp
 	windowRemovalCallback WindowRemovalCallback
+	channelList           ChannelList
+	whoQueue              whoRequests
+	whoStop               chan struct{}
+	netsplits             netsplits
 }
 
 func (c *Server) GetWindow() *Window {
 	return c.Window
 }
 
-func NewServer(timestampFormat string, id string, hostname string, port int, tls bool, password string, sasllogin string, saslpassword string, profile *Profile, ut UpdateTrigger, nm NotificationManager) *Server {
+func NewServer(timestampFormat string, id string, addresses []config.ServerAddress, shuffle bool, password string, sasllogin string, saslpassword string, proxy string, settings config.Connection, profile *Profile, ut UpdateTrigger, nm NotificationManager) *Server {
 	if id == "" {
 		id, _ = uniqueid.Generateid("a", 5, "s")
 	}
 	useSasl := len(sasllogin) > 0 && len(saslpassword) > 0
+	addresses = slices.Clone(addresses)
+	if shuffle {
+		rand.Shuffle(len(addresses), func(i, j int) {
+			addresses[i], addresses[j] = addresses[j], addresses[i]
+		})
+	}
+	hostname := ""
+	if len(addresses) > 0 {
+		hostname = addresses[0].Hostname
+	}
 
 	server := &Server{
-		hostname:          hostname,
-		port:              port,
-		tls:               tls,
+		addresses:         addresses,
 		password:          password,
 		saslLogin:         sasllogin,
 		saslPassword:      saslpassword,
+		proxy:             proxy,
+		settings:          settings,
 		preferredNickname: profile.nickname,
 		channels:          map[string]*Channel{},
 		pms:               map[string]*Query{},
 		connection: &ircevent.Connection{
 			Timeout:      10 * time.Second,
-			Server:       fmt.Sprintf("%s:%d", hostname, port),
 			Nick:         profile.nickname,
 			SASLLogin:    sasllogin,
 			SASLPassword: saslpassword,
 			QuitMessage:  " ",
 			Version:      " ",
-			UseTLS:       tls,
 			UseSASL:      useSasl,
 			EnableCTCP:   false,
 			RequestCaps: []string{
@@ -84,6 +121,9 @@ func NewServer(timestampFormat string, id string, hostname string, port int, tls
 				"draft/chathistory",
 				"draft/event-playback",
 				"batch",
+				"account-tag",
+				"userhost-in-names",
+				"away-notify",
 			},
 			Debug: true,
 			Log:   slog.NewLogLogger(slog.Default().Handler().WithAttrs([]slog.Attr{slog.Bool("rawirc", true), slog.String("Server", id)}), LevelTrace),
@@ -95,6 +135,7 @@ func NewServer(timestampFormat string, id string, hostname string, port int, tls
 		reconnectAttempts: 0,
 		reconnectTimer:    nil,
 		manualDisconnect:  false,
+		connectionState:   StateDisconnected,
 		linkRegex:         linkRegex,
 	}
 	server.Window = &Window{
@@ -107,6 +148,23 @@ func NewServer(timestampFormat string, id string, hostname string, port int, tls
 		tabCompleter: NewServerTabCompleter(server),
 	}
 
+	if len(addresses) > 0 {
+		server.setAddress(0)
+	}
+
+	dialer, err := NewProxyDialer(proxy)
+	if err != nil {
+		dialer = FailingDialer(fmt.Errorf("invalid proxy: %w", err))
+	}
+	server.connection.DialContext = server.statusDialer(dialer)
+	server.sendQueue = NewSendQueue(settings.FloodBurst, settings.FloodInterval, func() {
+		if server.ut != nil {
+			server.ut.SetPendingUpdate()
+		}
+	}, func(err error) {
+		server.AddMessage(NewError(server.timestampFormat, false, fmt.Sprintf("Unable to send queued message: %s", err)))
+	})
+
 	return server
 }
 
@@ -130,6 +188,10 @@ func (c *Server) Connect() {
 
 	c.AddDisconnectCallback(func(message ircmsg.Message) {
 		slog.Debug("Disconnected", "message", message)
+		c.stopPingLoop()
+		c.stopWhoLoop()
+		c.sendQueue.Clear()
+		c.setConnectionState(StateDisconnected)
 		c.mutex.Lock()
 		defer c.mutex.Unlock()
 		if c.reconnecting || c.manualDisconnect {
@@ -139,29 +201,107 @@ func (c *Server) Connect() {
 	})
 	c.AddConnectCallback(func(message ircmsg.Message) {
 		c.mutex.Lock()
-		defer c.mutex.Unlock()
 		c.reconnecting = false
 		c.reconnectAttempts = 0
+		c.fatalError = ""
 		if c.reconnectTimer != nil {
 			c.reconnectTimer.Stop()
 			c.reconnectTimer = nil
 		}
+		c.mutex.Unlock()
+		c.setConnectionState(StateConnected)
+		c.startPingLoop()
+		c.startWhoLoop()
+	})
+	c.AddCallback("PONG", func(message ircmsg.Message) {
+		if len(message.Params) > 0 {
+			c.handlePong(message.Params[len(message.Params)-1])
+		}
 	})
 	c.AddCallback("ERROR", func(message ircmsg.Message) {
+		if reason := strings.Join(message.Params, " "); isFatalError(reason) {
+			// The disconnect will report why we aren't reconnecting
+			c.setFatalError(reason)
+			return
+		}
 		go c.scheduleReconnect()
 	})
+	c.AddCallback(ircevent.ERR_YOUREBANNEDCREEP, func(message ircmsg.Message) {
+		c.setFatalError("Banned from server: " + strings.Join(message.Params[1:], " "))
+	})
+	c.AddCallback(ircevent.ERR_PASSWDMISMATCH, func(message ircmsg.Message) {
+		c.setFatalError("Server password incorrect")
+	})
+	c.AddCallback(ircevent.ERR_SASLFAIL, func(message ircmsg.Message) {
+		c.setFatalError("SASL authentication failed")
+	})
 
-	c.AddMessage(NewEvent(EventConnecting, c.timestampFormat, false, fmt.Sprintf("Connecting to %s", c.connection.Server)))
+	c.AddMessage(NewEvent(EventConnecting, c.timestampFormat, false, c.connectingMessage()))
 	if !c.connection.Connected() {
 		c.resetReconnectValues()
+		c.setConnectionState(StateConnecting)
 		err := c.connection.Connect()
 		if err != nil {
+			c.setConnectionState(StateDisconnected)
 			c.AddMessage(NewError(c.timestampFormat, false, "Server error: "+err.Error()))
 			go c.scheduleReconnect()
 		}
 	}
 }
 
+// connectingMessage describes the address being connected to, and which of the server's addresses it is if it has
+// more than one
+func (c *Server) connectingMessage() string {
+	message := "Connecting to " + c.connection.Server
+	if len(c.addresses) > 1 {
+		message += fmt.Sprintf(" (server %d of %d)", c.addressIndex+1, len(c.addresses))
+	}
+	if c.proxy == "" || c.proxy == config.ProxyDirect {
+		return message
+	}
+	proxyHost := c.proxy
+	if u, err := url.Parse(c.proxy); err == nil {
+		proxyHost = u.Host
+	}
+	return message + " via proxy " + proxyHost
+}
+
+// setAddress switches the connection to use the server address at the given index, it will be used the next time
+// the server connects
+func (c *Server) setAddress(index int) {
+	c.addressIndex = index
+	address := c.addresses[index]
+	c.connection.Server = net.JoinHostPort(address.Hostname, strconv.Itoa(address.Port))
+	c.connection.UseTLS = address.TLS
+	// The TLS server name is cached on the config, so it needs clearing when the host changes
+	c.connection.TLSConfig = nil
+}
+
+// rotateAddress moves on to the next server address, wrapping back to the start
+func (c *Server) rotateAddress() {
+	if len(c.addresses) < 2 {
+		return
+	}
+	c.setAddress((c.addressIndex + 1) % len(c.addresses))
+}
+
+// GetAddresses returns the server addresses in the order they are tried
+func (c *Server) GetAddresses() []config.ServerAddress {
+	return slices.Clone(c.addresses)
+}
+
+// GetCurrentAddress returns the server address currently being used
+func (c *Server) GetCurrentAddress() config.ServerAddress {
+	if len(c.addresses) == 0 {
+		return config.ServerAddress{}
+	}
+	return c.addresses[c.addressIndex]
+}
+
+func (c *Server) GetProxy() string {
+	return c.proxy
+}
+
 func (c *Server) resetReconnectValues() {
 	c.reconnecting = false
 	c.reconnectAttempts = 0
@@ -171,53 +311,98 @@ func (c *Server) resetReconnectValues() {
 	}
 }
 
+// CancelReconnection stops any pending reconnection attempt, and any further attempts until the user reconnects
 func (c *Server) CancelReconnection() {
 	c.mutex.Lock()
 	defer c.mutex.Unlock()
 	defer c.ut.SetPendingUpdate()
+	wasReconnecting := c.reconnecting
 	c.resetReconnectValues()
+	c.fatalError = "reconnection cancelled"
+	if !c.connection.Connected() {
+		c.setConnectionState(StateDisconnected)
+	}
+	if wasReconnecting {
+		c.AddMessage(NewEvent(EventConnecting, c.timestampFormat, false, "Reconnection cancelled"))
+	}
 }
 
 func (c *Server) scheduleReconnect() {
 	c.mutex.Lock()
 	defer c.mutex.Unlock()
 	defer c.ut.SetPendingUpdate()
-	if c.reconnecting {
+	if c.reconnecting || c.manualDisconnect {
+		return
+	}
+	if c.fatalError != "" {
+		c.AddMessage(NewError(c.timestampFormat, false, fmt.Sprintf("Not reconnecting: %s. Use /reconnect to try again", c.fatalError)))
+		return
+	}
+	if c.settings.ReconnectMaxAttempts > 0 && c.reconnectAttempts >= c.settings.ReconnectMaxAttempts {
+		c.AddMessage(NewError(c.timestampFormat, false, fmt.Sprintf("Giving up after %d reconnection attempts. Use /reconnect to try again", c.reconnectAttempts)))
 		return
 	}
 	c.reconnecting = true
 	c.reconnectAttempts++
+	c.rotateAddress()
 
-	// TODO: Look at something better?
-	delay := 1 << c.reconnectAttempts * time.Second
-	if delay > 1*time.Minute {
-		delay = 1 * time.Minute
+	delay := reconnectDelay(c.reconnectAttempts, len(c.addresses), c.settings.ReconnectMinDelay, c.settings.ReconnectMaxDelay, randomDuration)
+	if c.immediateReconnect {
+		c.immediateReconnect = false
+		delay = 0
 	}
 
-	c.AddMessage(NewEvent(EventConnecting, c.timestampFormat, false, fmt.Sprintf("Reconnection attempt %d scheduled in %v", c.reconnectAttempts, delay)))
+	c.AddMessage(NewEvent(EventConnecting, c.timestampFormat, false, fmt.Sprintf("Reconnection attempt %d to %s scheduled in %v", c.reconnectAttempts, c.connection.Server, delay.Round(time.Second))))
 
 	if c.reconnectTimer != nil {
 		c.reconnectTimer.Stop()
 	}
 
-	c.reconnectTimer = time.AfterFunc(delay, func() {
-		c.mutex.Lock()
-		defer c.mutex.Unlock()
-		defer c.ut.SetPendingUpdate()
-		c.reconnecting = false
+	c.setReconnectingState(time.Now().Add(delay))
+	c.reconnectTimer = time.AfterFunc(delay, c.attemptReconnect)
+}
 
-		c.AddMessage(NewEvent(EventConnecting, c.timestampFormat, false, fmt.Sprintf("Attempting to reconnect (attempt %d)...", c.reconnectAttempts)))
+func (c *Server) attemptReconnect() {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	defer c.ut.SetPendingUpdate()
+	c.reconnecting = false
+
+	c.AddMessage(NewEvent(EventConnecting, c.timestampFormat, false, fmt.Sprintf("Attempting to reconnect to %s (attempt %d)...", c.connection.Server, c.reconnectAttempts)))
 
-		if !c.connection.Connected() {
-			err := c.connection.Connect()
-			if err != nil {
-				c.AddMessage(NewError(c.timestampFormat, false,
-					fmt.Sprintf("Reconnection attempt %d failed: %s", c.reconnectAttempts, err.Error())))
-				go c.scheduleReconnect()
+	if !c.connection.Connected() {
+		c.setConnectionState(StateConnecting)
+		err := c.connection.Connect()
+		if err != nil {
+			c.setConnectionState(StateDisconnected)
+			c.AddMessage(NewError(c.timestampFormat, false,
+				fmt.Sprintf("Reconnection attempt %d failed: %s", c.reconnectAttempts, err.Error())))
+			if errors.Is(err, ircevent.ClientHasQuit) {
 				return
 			}
+			go c.scheduleReconnect()
+			return
 		}
-	})
+	}
+}
+
+// ReconnectNow drops any existing connection and reconnects straight away, clearing any reason reconnection was
+// stopped
+func (c *Server) ReconnectNow() {
+	c.mutex.Lock()
+	c.resetReconnectValues()
+	c.fatalError = ""
+	c.manualDisconnect = false
+	if c.connection.Connected() {
+		// The disconnect callback schedules the attempt, once the old connection has closed
+		c.immediateReconnect = true
+		c.mutex.Unlock()
+		c.connection.Reconnect()
+		return
+	}
+	c.reconnectAttempts = 1
+	c.mutex.Unlock()
+	c.attemptReconnect()
 }
 
 func (c *Server) AddConnectCallback(callback func(message ircmsg.Message)) {
@@ -246,15 +431,17 @@ func (c *Server) Disconnect() {
 	defer c.mutex.Unlock()
 	c.manualDisconnect = true
 	c.resetReconnectValues()
+	c.setConnectionState(StateDisconnected)
+	c.sendQueue.Clear()
+	if c.pingStop != nil {
+		close(c.pingStop)
+		c.pingStop = nil
+	}
 	c.connection.Quit()
 }
 
 func (c *Server) IsTargetChannel(target string) bool {
-	chanTypes := c.connection.ISupport()["CHANTYPES"]
-	if chanTypes == "" {
-		chanTypes = "#"
-	}
-	for _, char := range chanTypes {
+	for _, char := range c.getChanTypes() {
 		if strings.HasPrefix(target, string(char)) {
 			return true
 		}
@@ -262,6 +449,18 @@ func (c *Server) IsTargetChannel(target string) bool {
 	return false
 }
 
+// getChanTypes returns the characters channel names can start with
+func (c *Server) getChanTypes() string {
+	chanTypes := ""
+	if c.connection != nil {
+		chanTypes = c.connection.ISupport()["CHANTYPES"]
+	}
+	if chanTypes == "" {
+		chanTypes = "#"
+	}
+	return chanTypes
+}
+
 func (c *Server) IsValidChannel(target string) bool {
 	return c.IsTargetChannel(target)
 }
@@ -295,9 +494,10 @@ func (c *Server) GetChannelByName(name string) (*Channel, error) {
 
 func (c *Server) AddChannel(name string) *Channel {
 	defer c.ut.SetPendingUpdate()
+	channel := NewChannel(c, name)
+	c.loadHistory(channel.Window)
 	c.mutex.Lock()
 	defer c.mutex.Unlock()
-	channel := NewChannel(c, name)
 	c.channels[channel.id] = channel
 	return channel
 }
@@ -310,6 +510,10 @@ func (c *Server) RemoveChannel(s string) {
 	if channel != nil && c.windowRemovalCallback != nil {
 		c.windowRemovalCallback.OnWindowRemoved(channel.Window)
 	}
+	if channel != nil {
+		c.searchIndex.RemoveWindow(channel.Window)
+		c.messageLogger.CloseWindow(channel.Window)
+	}
 	c.PartChannel(s)
 	delete(c.channels, s)
 }
@@ -339,9 +543,10 @@ func (c *Server) GetQueryByName(name string) (*Query, error) {
 
 func (c *Server) AddQuery(name string) *Query {
 	defer c.ut.SetPendingUpdate()
+	pm := NewQuery(c, name)
+	c.loadHistory(pm.Window)
 	c.mutex.Lock()
 	defer c.mutex.Unlock()
-	pm := NewQuery(c, name)
 	c.pms[pm.id] = pm
 	return pm
 }
@@ -354,6 +559,10 @@ func (c *Server) RemoveQuery(id string) {
 	if query != nil && c.windowRemovalCallback != nil {
 		c.windowRemovalCallback.OnWindowRemoved(query.Window)
 	}
+	if query != nil {
+		c.searchIndex.RemoveWindow(query.Window)
+		c.messageLogger.CloseWindow(query.Window)
+	}
 	delete(c.pms, id)
 }
 
@@ -426,12 +635,13 @@ func (c *Server) SendMessage(window string, message string) error {
 
 	// PRIVMSG #channel :message == 10 + channel name
 	messageParts := c.SplitMessage(10+len(channel.name), message)
+	priority := messagePriority(messageParts)
 
 	for _, part := range messageParts {
 		if !c.HasCapability("echo-message") {
-			channel.AddMessage(NewMessage(c.timestampFormat, true, c.connection.CurrentNick(), part, nil))
+			channel.AddMessage(NewMessage(c.timestampFormat, true, c.connection.CurrentNick(), part, nil, nil))
 		}
-		err := c.connection.Send("PRIVMSG", channel.name, part)
+		err := c.send(priority, "PRIVMSG", channel.name, part)
 		if err != nil {
 			return err
 		}
@@ -449,12 +659,13 @@ func (c *Server) SendQuery(target string, message string) error {
 
 	// PRIVMSG nickname :message == 10 + nickname
 	messageParts := c.SplitMessage(10+len(target), message)
+	priority := messagePriority(messageParts)
 
 	for _, part := range messageParts {
 		if !c.HasCapability("echo-message") {
-			pm.AddMessage(NewMessage(c.timestampFormat, true, c.connection.CurrentNick(), part, nil))
+			pm.AddMessage(NewMessage(c.timestampFormat, true, c.connection.CurrentNick(), part, nil, nil))
 		}
-		err = c.connection.Send("PRIVMSG", target, part)
+		err = c.send(priority, "PRIVMSG", target, part)
 		if err != nil {
 			return err
 		}
@@ -475,12 +686,13 @@ func (c *Server) SendNotice(window string, message string) error {
 
 	// NOTICE #channel :message == 9 + channel name
 	messageParts := c.SplitMessage(9+len(channel.name), message)
+	priority := messagePriority(messageParts)
 
 	for _, part := range messageParts {
 		if !c.HasCapability("echo-message") {
-			channel.AddMessage(NewNotice(c.timestampFormat, true, c.connection.CurrentNick(), part, nil))
+			channel.AddMessage(NewNotice(c.timestampFormat, true, c.connection.CurrentNick(), part, nil, nil))
 		}
-		err := c.connection.Send("NOTICE", channel.name, part)
+		err := c.send(priority, "NOTICE", channel.name, part)
 		if err != nil {
 			return err
 		}
@@ -498,12 +710,13 @@ func (c *Server) SendQueryNotice(target string, message string) error {
 
 	// NOTICE nickname :message == 9 + nickname
 	messageParts := c.SplitMessage(9+len(target), message)
+	priority := messagePriority(messageParts)
 
 	for _, part := range messageParts {
 		if !c.HasCapability("echo-message") {
-			pm.AddMessage(NewNotice(c.timestampFormat, true, c.connection.CurrentNick(), part, nil))
+			pm.AddMessage(NewNotice(c.timestampFormat, true, c.connection.CurrentNick(), part, nil, nil))
 		}
-		err = c.connection.Send("NOTICE", target, part)
+		err = c.send(priority, "NOTICE", target, part)
 		if err != nil {
 			return err
 		}
@@ -517,13 +730,13 @@ func (c *Server) CurrentNick() string {
 }
 
 func (c *Server) JoinChannel(channel string, password string) error {
-	return c.connection.Join(channel)
+	return c.send(PriorityInteractive, "JOIN", channel)
 }
 
 func (c *Server) PartChannel(channel string) error {
 	channelInstance := c.GetChannel(channel)
 	if channelInstance != nil {
-		return c.connection.Part(channelInstance.GetName())
+		return c.send(PriorityInteractive, "PART", channelInstance.GetName())
 	}
 	return fmt.Errorf("channel %s not found", channel)
 }
@@ -552,8 +765,43 @@ func (c *Server) GetModeNameForMode(mode string) string {
 	return modes[1][index : index+1]
 }
 
+// SendRaw sends a line to the server via the flood protection queue, errors are shown in the server window
 func (c *Server) SendRaw(message string) {
-	c.connection.SendRaw(message)
+	err := c.sendQueue.Send(PriorityInteractive, func() error {
+		return c.connection.SendRaw(message)
+	})
+	if err != nil {
+		c.AddMessage(NewError(c.timestampFormat, false, fmt.Sprintf("Unable to send message: %s", err)))
+		if c.ut != nil {
+			c.ut.SetPendingUpdate()
+		}
+	}
+}
+
+// send sends a message to the server via the flood protection queue
+func (c *Server) send(priority SendPriority, command string, params ...string) error {
+	return c.sendQueue.Send(priority, func() error {
+		return c.connection.Send(command, params...)
+	})
+}
+
+// messagePriority treats messages that had to be split into several lines, such as pastes, as bulk so they don't
+// hold up anything else the user types
+func messagePriority(parts []string) SendPriority {
+	if len(parts) > 1 {
+		return PriorityBulk
+	}
+	return PriorityInteractive
+}
+
+// GetQueuedCount returns how many lines are waiting to be sent to the server
+func (c *Server) GetQueuedCount() int {
+	return c.sendQueue.Len()
+}
+
+// ClearSendQueue discards any lines waiting to be sent to the server, returning how many were removed
+func (c *Server) ClearSendQueue() int {
+	return c.sendQueue.Clear()
 }
 
 func (c *Server) ISupport(value string) string {
@@ -569,7 +817,17 @@ func (c *Server) SetNick(nick string) {
 }
 
 func (c *Server) SendTopic(channel string, topic string) error {
-	return c.connection.Send("TOPIC", channel, topic)
+	return c.send(PriorityInteractive, "TOPIC", channel, topic)
+}
+
+// RequestTopic asks the server for the current topic of a channel
+func (c *Server) RequestTopic(channel string) error {
+	return c.send(PriorityInteractive, "TOPIC", channel)
+}
+
+// RequestChannelModes asks the server for all the modes set on a channel, and when it was created
+func (c *Server) RequestChannelModes(channel string) error {
+	return c.send(PriorityInteractive, "MODE", channel)
 }
 
 func (c *Server) GetCurrentModes() string {
@@ -591,15 +849,8 @@ func (c *Server) GetChannelModeType(mode string) rune {
 		return 'P'
 	}
 
-	// Get modes, use default
-	chanModes := c.ISupport("CHANMODES")
-	if chanModes == "" {
-		chanModes = "beI,k,l,imnpst"
-	}
-
-	parts := strings.Split(chanModes, ",")
-	if len(parts) != 4 {
-		slog.Error("Invalid CHANMODES format", "CHANMODES", chanModes)
+	parts := c.getChanModes()
+	if parts == nil {
 		return '?'
 	}
 	if strings.Contains(parts[0], mode) {
@@ -614,6 +865,20 @@ func (c *Server) GetChannelModeType(mode string) rune {
 	return '?'
 }
 
+// getChanModes returns the network's channel modes split into types A, B, C and D, or nil if CHANMODES is invalid
+func (c *Server) getChanModes() []string {
+	chanModes := c.ISupport("CHANMODES")
+	if chanModes == "" {
+		chanModes = "beI,k,l,imnpst"
+	}
+	parts := strings.Split(chanModes, ",")
+	if len(parts) != 4 {
+		slog.Error("Invalid CHANMODES format", "CHANMODES", chanModes)
+		return nil
+	}
+	return parts
+}
+
 func (c *Server) SetWindowRemovalCallback(callback WindowRemovalCallback) {
 	c.windowRemovalCallback = callback
 }
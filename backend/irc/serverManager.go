@@ -25,6 +25,18 @@ type ServerManager struct {
 	updateTrigger         UpdateTrigger
 	notificationManager   NotificationManager
 	timestampFormat       string
+	connectionSettings    config.Connection
+	ignoreList            *IgnoreList
+	eventFilter           *EventFilter
+	windowSettings        *WindowSettingsList
+	highlightRules        *HighlightRules
+	mentions              *Window
+	searchIndex           *SearchIndex
+	messageLogger         *MessageLogger
+	linkPreviewer         *LinkPreviewer
+	htmlExporter          HTMLExporter
+	channelListViewer     ChannelListViewer
+	history               *History
 	linkRegex             *regexp.Regexp
 	windowRemovalCallback WindowRemovalCallback
 }
@@ -35,24 +47,39 @@ func NewServerManager(timestampFormat string, commandManager *CommandManager) *S
 		commandManager:  commandManager,
 		timestampFormat: timestampFormat,
 		linkRegex:       linkRegex,
+		mentions:        NewMentionsWindow(),
+		searchIndex:     NewSearchIndex(),
 	}
 }
 
 func (cm *ServerManager) AddConnection(
 	id string,
-	hostname string,
-	port int,
-	tls bool,
+	addresses []config.ServerAddress,
+	shuffle bool,
 	password string,
 	sasllogin string,
 	saslpassword string,
+	proxy string,
 	profile *Profile,
 	connect bool,
 ) string {
-	connection := NewServer(cm.timestampFormat, id, hostname, port, tls, password, sasllogin, saslpassword, profile, cm.updateTrigger, cm.notificationManager)
+	if proxy == "" {
+		proxy = cm.connectionSettings.Proxy
+	}
+	connection := NewServer(cm.timestampFormat, id, addresses, shuffle, password, sasllogin, saslpassword, proxy, cm.connectionSettings, profile, cm.updateTrigger, cm.notificationManager)
 	if cm.windowRemovalCallback != nil {
 		connection.SetWindowRemovalCallback(cm.windowRemovalCallback)
 	}
+	connection.SetIgnoreList(cm.ignoreList)
+	connection.SetEventFilter(cm.eventFilter)
+	connection.SetWindowSettings(cm.windowSettings)
+	connection.SetCommandManager(cm.commandManager)
+	connection.SetHighlightRules(cm.highlightRules)
+	connection.SetMentions(cm.mentions)
+	connection.SetSearchIndex(cm.searchIndex)
+	connection.SetMessageLogger(cm.messageLogger)
+	connection.SetLinkPreviewer(cm.linkPreviewer)
+	connection.SetHistory(cm.history)
 	cm.connections[connection.GetID()] = connection
 	if connect {
 		go func() {
@@ -70,6 +97,7 @@ func (cm *ServerManager) RemoveConnection(id string) {
 	}
 	if connection != nil {
 		connection.Disconnect()
+		cm.searchIndex.RemoveServer(connection)
 		delete(cm.connections, id)
 	}
 	cm.updateTrigger.SetPendingUpdate()
@@ -100,17 +128,108 @@ func (cm *ServerManager) Stop() {
 	for _, connection := range cm.connections {
 		connection.Disconnect()
 	}
+	cm.messageLogger.Close()
 }
 
 func (cm *ServerManager) Load(servers []config.Server) {
 	for _, server := range servers {
 		// Add any auto connect servers, but do not connect until start is called
 		if server.AutoConnect {
-			cm.AddConnection(server.ID, server.Hostname, server.Port, server.TLS, server.Password, server.SASLLogin, server.SASLPassword, NewProfile(server.Profile.Nickname), false)
+			cm.AddConnection(server.ID, server.GetAddresses(), server.Shuffle, server.Password, server.SASLLogin, server.SASLPassword, server.Proxy, NewProfile(server.Profile.Nickname), false)
 		}
 	}
 }
 
+// SetConnectionSettings sets the default proxy and reconnection settings used by new connections
+func (cm *ServerManager) SetConnectionSettings(settings config.Connection) {
+	cm.connectionSettings = settings
+}
+
+func (cm *ServerManager) SetIgnoreList(ignoreList *IgnoreList) {
+	cm.ignoreList = ignoreList
+}
+
+func (cm *ServerManager) GetIgnoreList() *IgnoreList {
+	return cm.ignoreList
+}
+
+// SetEventFilter sets the filter used to hide joins, parts and quits in every server's channels
+func (cm *ServerManager) SetEventFilter(filter *EventFilter) {
+	cm.eventFilter = filter
+}
+
+func (cm *ServerManager) GetEventFilter() *EventFilter {
+	return cm.eventFilter
+}
+
+// SetWindowSettings sets the settings for individual channels and queries on every server
+func (cm *ServerManager) SetWindowSettings(settings *WindowSettingsList) {
+	cm.windowSettings = settings
+}
+
+func (cm *ServerManager) GetWindowSettings() *WindowSettingsList {
+	return cm.windowSettings
+}
+
+func (cm *ServerManager) SetHighlightRules(rules *HighlightRules) {
+	cm.highlightRules = rules
+}
+
+func (cm *ServerManager) GetHighlightRules() *HighlightRules {
+	return cm.highlightRules
+}
+
+// SetMessageLogger sets the logger used to write messages from every server to disk
+func (cm *ServerManager) SetMessageLogger(logger *MessageLogger) {
+	cm.messageLogger = logger
+}
+
+func (cm *ServerManager) GetMessageLogger() *MessageLogger {
+	return cm.messageLogger
+}
+
+// SetLinkPreviewer sets the previewer used to fetch the titles and images of links in messages
+func (cm *ServerManager) SetLinkPreviewer(previewer *LinkPreviewer) {
+	cm.linkPreviewer = previewer
+}
+
+func (cm *ServerManager) GetLinkPreviewer() *LinkPreviewer {
+	return cm.linkPreviewer
+}
+
+// SetHistory sets the store of history imported from other clients, shown when channels and queries are opened
+func (cm *ServerManager) SetHistory(history *History) {
+	cm.history = history
+}
+
+// SetHTMLExporter sets the exporter used to render windows as HTML
+func (cm *ServerManager) SetHTMLExporter(exporter HTMLExporter) {
+	cm.htmlExporter = exporter
+}
+
+func (cm *ServerManager) GetHTMLExporter() HTMLExporter {
+	return cm.htmlExporter
+}
+
+// SetChannelListViewer sets the viewer used to show the channel list after /list
+func (cm *ServerManager) SetChannelListViewer(viewer ChannelListViewer) {
+	cm.channelListViewer = viewer
+}
+
+func (cm *ServerManager) GetChannelListViewer() ChannelListViewer {
+	return cm.channelListViewer
+}
+
+// Search searches the messages in every window of every server
+func (cm *ServerManager) Search(query SearchQuery) ([]SearchResult, error) {
+	return cm.searchIndex.Search(query)
+}
+
+// GetMentions returns the window collecting highlights and private messages from every server
+func (cm *ServerManager) GetMentions() *Window {
+	return cm.mentions
+}
+
 func (cm *ServerManager) SetUpdateTrigger(ut UpdateTrigger) {
 	cm.updateTrigger = ut
 }
@@ -0,0 +1,61 @@
+package irc
+
+import (
+	"github.com/greboid/tithon/config"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestServer_RotateAddress(t *testing.T) {
+	addresses := []config.ServerAddress{
+		{Hostname: "irc1.example.com", Port: 6697, TLS: true},
+		{Hostname: "irc2.example.com", Port: 6667, TLS: false},
+	}
+	server := NewServer("15:04:05", "test", addresses, false, "", "", "", "", config.Connection{}, NewProfile("nick"), nil, nil)
+
+	assert.Equal(t, "irc1.example.com:6697", server.connection.Server)
+	assert.True(t, server.connection.UseTLS)
+	assert.Equal(t, "Connecting to irc1.example.com:6697 (server 1 of 2)", server.connectingMessage())
+
+	server.rotateAddress()
+	assert.Equal(t, "irc2.example.com:6667", server.connection.Server)
+	assert.False(t, server.connection.UseTLS)
+	assert.Nil(t, server.connection.TLSConfig)
+	assert.Equal(t, addresses[1], server.GetCurrentAddress())
+	assert.Equal(t, "Connecting to irc2.example.com:6667 (server 2 of 2)", server.connectingMessage())
+	assert.Equal(t, "irc1.example.com", server.GetTitle(), "rotating shouldn't change the window title")
+
+	server.rotateAddress()
+	assert.Equal(t, "irc1.example.com:6697", server.connection.Server)
+	assert.Equal(t, addresses[0], server.GetCurrentAddress())
+}
+
+func TestServer_RotateAddress_Single(t *testing.T) {
+	addresses := []config.ServerAddress{
+		{Hostname: "irc.example.com", Port: 6697, TLS: true},
+	}
+	server := NewServer("15:04:05", "test", addresses, false, "", "", "", "", config.Connection{}, NewProfile("nick"), nil, nil)
+
+	server.rotateAddress()
+	assert.Equal(t, "irc.example.com:6697", server.connection.Server)
+	assert.Equal(t, "Connecting to irc.example.com:6697", server.connectingMessage())
+	assert.Equal(t, "irc.example.com", server.GetTitle())
+}
+
+func TestServer_Shuffle(t *testing.T) {
+	addresses := []config.ServerAddress{
+		{Hostname: "irc1.example.com", Port: 6697, TLS: true},
+		{Hostname: "irc2.example.com", Port: 6697, TLS: true},
+		{Hostname: "irc3.example.com", Port: 6697, TLS: true},
+	}
+	server := NewServer("15:04:05", "test", addresses, true, "", "", "", "", config.Connection{}, NewProfile("nick"), nil, nil)
+
+	assert.ElementsMatch(t, addresses, server.GetAddresses())
+	assert.Equal(t, "irc1.example.com", addresses[0].Hostname, "Shuffling should not modify the passed addresses")
+}
+
+func TestServer_ConnectingMessage_Proxy(t *testing.T) {
+	addresses := []config.ServerAddress{{Hostname: "irc.example.com", Port: 6697, TLS: true}}
+	server := NewServer("15:04:05", "test", addresses, false, "", "", "", "socks5://proxy.example.com:1080", config.Connection{}, NewProfile("nick"), nil, nil)
+	assert.Equal(t, "Connecting to irc.example.com:6697 via proxy proxy.example.com:1080", server.connectingMessage())
+}
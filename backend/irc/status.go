@@ -0,0 +1,119 @@
+package irc
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"time"
+)
+
+type ConnectionState string
+
+const (
+	StateDisconnected ConnectionState = "disconnected"
+	StateConnecting   ConnectionState = "connecting"
+	StateRegistering  ConnectionState = "registering"
+	StateConnected    ConnectionState = "connected"
+	StateReconnecting ConnectionState = "reconnecting"
+)
+
+// ConnectionStatus is a snapshot of the state of a server connection
+type ConnectionStatus struct {
+	State ConnectionState
+	// Lag is the round trip time of the last answered PING, zero if it hasn't been measured yet
+	Lag time.Duration
+	// ReconnectIn is how long until the next reconnection attempt when State is StateReconnecting
+	ReconnectIn time.Duration
+	// Queued is the number of lines waiting to be sent because of flood protection
+	Queued int
+}
+
+func (s ConnectionStatus) String() string {
+	if s.Queued == 1 {
+		return s.stateString() + ", 1 message queued"
+	} else if s.Queued > 1 {
+		return fmt.Sprintf("%s, %d messages queued", s.stateString(), s.Queued)
+	}
+	return s.stateString()
+}
+
+func (s ConnectionStatus) stateString() string {
+	switch s.State {
+	case StateConnecting:
+		return "Connecting"
+	case StateRegistering:
+		return "Registering"
+	case StateConnected:
+		if s.Lag > 0 {
+			return fmt.Sprintf("Connected (lag %s)", formatLag(s.Lag))
+		}
+		return "Connected"
+	case StateReconnecting:
+		return fmt.Sprintf("Reconnecting in %v", s.ReconnectIn.Round(time.Second))
+	default:
+		return "Disconnected"
+	}
+}
+
+func formatLag(lag time.Duration) string {
+	if lag < time.Second {
+		return lag.Round(time.Millisecond).String()
+	}
+	return lag.Round(100 * time.Millisecond).String()
+}
+
+// GetConnectionStatus returns the current state of the connection along with the measured lag
+func (c *Server) GetConnectionStatus() ConnectionStatus {
+	queued := c.sendQueue.Len()
+	c.statusMutex.Lock()
+	defer c.statusMutex.Unlock()
+	status := ConnectionStatus{
+		State:  c.connectionState,
+		Lag:    c.lag,
+		Queued: queued,
+	}
+	if status.State == StateReconnecting {
+		status.ReconnectIn = max(0, time.Until(c.reconnectAt))
+	}
+	return status
+}
+
+func (c *Server) setConnectionState(state ConnectionState) {
+	c.statusMutex.Lock()
+	defer c.statusMutex.Unlock()
+	c.connectionState = state
+	if state != StateConnected {
+		c.lag = 0
+	}
+}
+
+func (c *Server) setReconnectingState(at time.Time) {
+	c.statusMutex.Lock()
+	defer c.statusMutex.Unlock()
+	c.connectionState = StateReconnecting
+	c.reconnectAt = at
+	c.lag = 0
+}
+
+func (c *Server) setLag(lag time.Duration) {
+	c.statusMutex.Lock()
+	defer c.statusMutex.Unlock()
+	c.lag = lag
+}
+
+// statusDialer moves the connection into the registering state once the socket has been opened
+func (c *Server) statusDialer(dial DialContextFunc) DialContextFunc {
+	if dial == nil {
+		dial = (&net.Dialer{}).DialContext
+	}
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		conn, err := dial(ctx, network, addr)
+		if err == nil {
+			c.setConnectionState(StateRegistering)
+			if c.ut != nil {
+				c.ut.SetPendingUpdate()
+			}
+		}
+		return conn, err
+	}
+}
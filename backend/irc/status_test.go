@@ -0,0 +1,71 @@
+package irc
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/greboid/tithon/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConnectionStatus_String(t *testing.T) {
+	tests := []struct {
+		name   string
+		status ConnectionStatus
+		want   string
+	}{
+		{name: "Disconnected", status: ConnectionStatus{State: StateDisconnected}, want: "Disconnected"},
+		{name: "Connecting", status: ConnectionStatus{State: StateConnecting}, want: "Connecting"},
+		{name: "Registering", status: ConnectionStatus{State: StateRegistering}, want: "Registering"},
+		{name: "Connected without lag", status: ConnectionStatus{State: StateConnected}, want: "Connected"},
+		{name: "Connected with lag", status: ConnectionStatus{State: StateConnected, Lag: 123456 * time.Microsecond}, want: "Connected (lag 123ms)"},
+		{name: "Connected with high lag", status: ConnectionStatus{State: StateConnected, Lag: 2345 * time.Millisecond}, want: "Connected (lag 2.3s)"},
+		{name: "Reconnecting", status: ConnectionStatus{State: StateReconnecting, ReconnectIn: 4600 * time.Millisecond}, want: "Reconnecting in 5s"},
+		{name: "One message queued", status: ConnectionStatus{State: StateConnected, Queued: 1}, want: "Connected, 1 message queued"},
+		{name: "Messages queued", status: ConnectionStatus{State: StateConnected, Lag: 50 * time.Millisecond, Queued: 12}, want: "Connected (lag 50ms), 12 messages queued"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.status.String())
+		})
+	}
+}
+
+func TestServer_GetConnectionStatus(t *testing.T) {
+	server := NewServer("15:04:05", "test", []config.ServerAddress{{Hostname: "irc.example.com", Port: 6697, TLS: true}}, false, "", "", "", "", config.Connection{}, NewProfile("nick"), nil, nil)
+	assert.Equal(t, ConnectionStatus{State: StateDisconnected}, server.GetConnectionStatus())
+
+	server.setConnectionState(StateConnected)
+	server.setLag(50 * time.Millisecond)
+	assert.Equal(t, ConnectionStatus{State: StateConnected, Lag: 50 * time.Millisecond}, server.GetConnectionStatus())
+
+	server.setReconnectingState(time.Now().Add(time.Minute))
+	status := server.GetConnectionStatus()
+	assert.Equal(t, StateReconnecting, status.State)
+	assert.Zero(t, status.Lag)
+	assert.InDelta(t, time.Minute, status.ReconnectIn, float64(time.Second))
+}
+
+func TestServer_StatusDialer(t *testing.T) {
+	server := NewServer("15:04:05", "test", []config.ServerAddress{{Hostname: "irc.example.com", Port: 6697, TLS: true}}, false, "", "", "", "", config.Connection{}, NewProfile("nick"), nil, nil)
+	server.setConnectionState(StateConnecting)
+
+	failing := server.statusDialer(FailingDialer(errors.New("failed")))
+	_, err := failing(context.Background(), "tcp", "irc.example.com:6697")
+	assert.Error(t, err)
+	assert.Equal(t, StateConnecting, server.GetConnectionStatus().State)
+
+	client, remote := net.Pipe()
+	defer func() { _ = remote.Close() }()
+	working := server.statusDialer(func(_ context.Context, _, _ string) (net.Conn, error) {
+		return client, nil
+	})
+	conn, err := working(context.Background(), "tcp", "irc.example.com:6697")
+	require.NoError(t, err)
+	defer func() { _ = conn.Close() }()
+	assert.Equal(t, StateRegistering, server.GetConnectionStatus().State)
+}
@@ -1,76 +1,157 @@
 package irc
 
 import (
+	"cmp"
+	"maps"
+	"slices"
 	"strings"
+	"sync"
+	"time"
+
+	"github.com/enescakir/emoji"
 )
 
-type userList interface {
+// activityMessages is how many of the most recent messages in each window are used to order nicknames by activity
+const activityMessages = 200
+
+// completionWindow is the window a tab completer completes input for
+type completionWindow interface {
 	GetUsers() []*User
+	GetMessages() []*Message
+	GetServer() *Server
 }
 
 type TabCompleter interface {
 	Complete(input string, position int) (string, int)
 }
 
-type NoopTabCompleter struct{}
-
-type QueryTabCompleter struct{}
+// WindowTabCompleter completes the word before the cursor: commands and their arguments, channels, :emoji: and
+// nicknames.  Completing again without changing the input cycles through the other matches.
+type WindowTabCompleter struct {
+	window    completionWindow
+	nicknames func() []string
+	// lastInput and lastPosition are the result of the last completion, start is where the completed word began,
+	// matches are the candidates it was chosen from and nickname is set when they're nicknames
+	lastInput    string
+	lastPosition int
+	start        int
+	matches      []string
+	nickname     bool
+	index        int
+}
 
-type ChannelTabCompleter struct {
-	channel        userList
-	lastCompletion int
-	previousIndex  int
-	lastPosition   int
-	lastInput      string
-	original       string
-	orpos          int
+// NewChannelTabCompleter completes nicknames of the channel's users, those who spoke most recently first
+func NewChannelTabCompleter(channel completionWindow) TabCompleter {
+	return &WindowTabCompleter{
+		window: channel,
+		nicknames: func() []string {
+			return nicknamesByActivity(userNicknames(channel.GetUsers()), recentMessages(channel.GetMessages()))
+		},
+	}
 }
 
+// NewQueryTabCompleter completes the nickname of the user the query is with, then your own
 func NewQueryTabCompleter(query *Query) TabCompleter {
-	return &NoopTabCompleter{}
+	return &WindowTabCompleter{
+		window: query,
+		nicknames: func() []string {
+			nicknames := []string{query.GetName()}
+			if server := query.GetServer(); server != nil && server.connection != nil {
+				if nickname := cmp.Or(server.CurrentNick(), server.preferredNickname); nickname != "" {
+					nicknames = append(nicknames, nickname)
+				}
+			}
+			return nicknames
+		},
+	}
 }
 
+// NewServerTabCompleter completes nicknames of users in any of the server's channels, those who spoke most recently
+// first
 func NewServerTabCompleter(server *Server) TabCompleter {
-	return &NoopTabCompleter{}
-}
-
-func NewChannelTabCompleter(channel userList) TabCompleter {
-	return &ChannelTabCompleter{
-		channel:       channel,
-		previousIndex: -1,
+	return &WindowTabCompleter{
+		window: server,
+		nicknames: func() []string {
+			var nicknames []string
+			var messages []*Message
+			seen := make(map[string]bool)
+			for _, channel := range server.GetChannels() {
+				for _, nickname := range userNicknames(channel.GetUsers()) {
+					if lower := strings.ToLower(nickname); !seen[lower] {
+						seen[lower] = true
+						nicknames = append(nicknames, nickname)
+					}
+				}
+				messages = append(messages, recentMessages(channel.GetMessages())...)
+			}
+			return nicknamesByActivity(nicknames, messages)
+		},
 	}
 }
 
-func (t *NoopTabCompleter) Complete(input string, position int) (string, int) {
-	return input, position
-}
-
-func (t *ChannelTabCompleter) Complete(input string, position int) (string, int) {
-	users := t.nicknamesToString(t.channel.GetUsers())
-	lastSpace, nextSpace := t.surroundingSpacesIndexes(input, position)
-	var partial string
-	var lastCompletion int
-	if t.lastInput == input && t.lastPosition == position {
-		partial = t.original
-		position = t.orpos
-		input = t.lastInput
-		lastCompletion = t.lastCompletion
+func (t *WindowTabCompleter) Complete(input string, position int) (string, int) {
+	if position < 0 || position > len(input) {
+		return input, position
+	}
+	var end int
+	if t.lastInput == input && t.lastPosition == position && len(t.matches) > 0 {
+		t.index = (t.index + 1) % len(t.matches)
+		end = position
 	} else {
-		partial = input[lastSpace:nextSpace]
-		lastCompletion = -1
+		t.start, end = t.surroundingSpacesIndexes(input, position)
+		partial := input[t.start:end]
+		candidates, nickname := t.candidates(input[:t.start], partial)
+		t.matches = completePrefixInList(partial, candidates)
+		t.nickname = nickname
+		t.index = 0
+		if len(t.matches) == 0 {
+			t.lastInput = ""
+			return input, position
+		}
+	}
+	completion := t.matches[t.index]
+	if t.nickname && t.start == 0 {
+		suffix := t.window.GetServer().getNickCompletionSuffix()
+		if strings.HasPrefix(input[end:], " ") {
+			suffix = strings.TrimRight(suffix, " ")
+		}
+		completion += suffix
 	}
-	match, completion := t.completePrefixInList(partial, users, lastCompletion)
-	diff := len(match) - len(partial)
-	sentence := input[:lastSpace] + match + input[nextSpace:]
-	t.lastCompletion = completion
+	sentence := input[:t.start] + completion + input[end:]
 	t.lastInput = sentence
-	t.lastPosition = position + diff
-	t.orpos = position
-	t.original = partial
-	return sentence, position + diff
+	t.lastPosition = t.start + len(completion)
+	return sentence, t.lastPosition
+}
+
+// candidates returns the words that could complete partial, given the input before it, and whether they're nicknames
+func (t *WindowTabCompleter) candidates(before string, partial string) ([]string, bool) {
+	server := t.window.GetServer()
+	if before == "" && strings.HasPrefix(partial, "/") {
+		return server.getCommandManager().completeCommand(), false
+	}
+	if fields := strings.Fields(before); strings.HasPrefix(before, "/") && len(fields) > 0 {
+		arguments := server.getCommandManager().completeArgument(server, strings.TrimPrefix(fields[0], "/"), fields[1:])
+		if len(arguments) > 0 {
+			return arguments, false
+		}
+	}
+	if len(partial) > 1 && strings.HasPrefix(partial, ":") {
+		return emojiShortcodes(), false
+	}
+	if server != nil && server.IsTargetChannel(partial) {
+		var channels []string
+		for _, channel := range server.GetChannels() {
+			channels = append(channels, channel.GetName())
+		}
+		slices.SortFunc(channels, func(a, b string) int {
+			return strings.Compare(strings.ToLower(a), strings.ToLower(b))
+		})
+		return channels, false
+	}
+	return t.nicknames(), true
 }
 
-func (t *ChannelTabCompleter) surroundingSpacesIndexes(input string, position int) (int, int) {
+func (t *WindowTabCompleter) surroundingSpacesIndexes(input string, position int) (int, int) {
 	lastSpace := strings.LastIndex(input[:position], " ")
 	if lastSpace == -1 {
 		lastSpace = 0
@@ -80,24 +161,84 @@ func (t *ChannelTabCompleter) surroundingSpacesIndexes(input string, position in
 	nextSpace := strings.Index(input[lastSpace:], " ")
 	if nextSpace == -1 {
 		nextSpace = len(input)
+	} else {
+		nextSpace += lastSpace
 	}
 	return lastSpace, nextSpace
 }
 
-func (t *ChannelTabCompleter) nicknamesToString(nicknames []*User) []string {
-	output := make([]string, 0)
-	for i := range nicknames {
-		output = append(output, nicknames[i].nickname)
+// completePrefixInList returns the choices that start with the given text, ignoring case
+func completePrefixInList(start string, choices []string) []string {
+	var matches []string
+	for i := range choices {
+		if strings.HasPrefix(strings.ToLower(choices[i]), strings.ToLower(start)) {
+			matches = append(matches, choices[i])
+		}
+	}
+	return matches
+}
+
+func userNicknames(users []*User) []string {
+	output := make([]string, 0, len(users))
+	for i := range users {
+		output = append(output, users[i].GetNickname())
 	}
 	return output
 }
 
-func (t *ChannelTabCompleter) completePrefixInList(start string, choices []string, lastMatch int) (string, int) {
-	for i := range choices {
-		index := (i + lastMatch + 1) % len(choices)
-		if strings.HasPrefix(strings.ToLower(choices[index]), strings.ToLower(start)) {
-			return choices[index], index
+// recentMessages returns the last messages in a window, only recent activity matters when ordering nicknames
+func recentMessages(messages []*Message) []*Message {
+	return messages[max(0, len(messages)-activityMessages):]
+}
+
+// nicknamesByActivity moves the nicknames of users who sent any of the messages to the front, most recent first, the
+// rest keep their order
+func nicknamesByActivity(nicknames []string, messages []*Message) []string {
+	lastSpoke := make(map[string]time.Time)
+	for _, message := range messages {
+		if message.IsMe() || message.GetNickname() == "" || !isChatType(message.GetType()) {
+			continue
+		}
+		nickname := strings.ToLower(message.GetNickname())
+		if message.GetTime().After(lastSpoke[nickname]) {
+			lastSpoke[nickname] = message.GetTime()
 		}
 	}
-	return start, 0
+	ordered := slices.Clone(nicknames)
+	slices.SortStableFunc(ordered, func(a, b string) int {
+		return lastSpoke[strings.ToLower(b)].Compare(lastSpoke[strings.ToLower(a)])
+	})
+	return ordered
+}
+
+// isChatType checks if a message was sent by a user rather than being an event
+func isChatType(messageType MessageType) bool {
+	switch messageType {
+	case Normal, Action, Notice, Highlight, HighlightAction, HighlightNotice:
+		return true
+	default:
+		return false
+	}
+}
+
+// emojiShortcodes returns every :shortcode: that's replaced with an emoji when sending a message
+var emojiShortcodes = sync.OnceValue(func() []string {
+	return slices.Sorted(maps.Keys(emoji.Map()))
+})
+
+// getCommandManager returns the commands that can be completed, nil safe so windows without a server can complete
+func (c *Server) getCommandManager() *CommandManager {
+	if c == nil {
+		return nil
+	}
+	return c.commands
+}
+
+func (c *Server) getNickCompletionSuffix() string {
+	return c.getCommandManager().nickCompletionSuffix()
+}
+
+// SetCommandManager sets the commands that are tab completed in the server's windows
+func (c *Server) SetCommandManager(commands *CommandManager) {
+	c.commands = commands
 }
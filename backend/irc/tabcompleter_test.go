@@ -4,7 +4,9 @@ import (
 	"slices"
 	"strings"
 	"testing"
+	"time"
 
+	"github.com/greboid/tithon/config"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -12,7 +14,7 @@ import (
 func TestChannelTabCompleter_Complete(t1 *testing.T) {
 	tests := []struct {
 		name     string
-		channel  userList
+		channel  completionWindow
 		input    string
 		position int
 		runs     int
@@ -76,10 +78,7 @@ func TestChannelTabCompleter_Complete(t1 *testing.T) {
 	}
 	for _, tt := range tests {
 		t1.Run(tt.name, func(t1 *testing.T) {
-			t := &ChannelTabCompleter{
-				channel:       tt.channel,
-				previousIndex: -1,
-			}
+			t := NewChannelTabCompleter(tt.channel)
 			got := tt.input
 			got1 := tt.position
 			for range tt.runs {
@@ -94,10 +93,7 @@ func TestChannelTabCompleter_Complete(t1 *testing.T) {
 func TestChannelTabCompleter_MultipleInputs(t1 *testing.T) {
 	channel := newFakeUserList("dataforce", "demented", "md87")
 
-	t := &ChannelTabCompleter{
-		channel:       channel,
-		previousIndex: -1,
-	}
+	t := NewChannelTabCompleter(channel)
 	require.NotNil(t1, t, "Tab completer should not be nil")
 
 	input1, pos1 := t.Complete("d", 1)
@@ -110,7 +106,9 @@ func TestChannelTabCompleter_MultipleInputs(t1 *testing.T) {
 }
 
 type fakeUserListGetter struct {
-	users []*User
+	users    []*User
+	messages []*Message
+	server   *Server
 }
 
 func newFakeUserList(users ...string) *fakeUserListGetter {
@@ -131,3 +129,145 @@ func newFakeUserList(users ...string) *fakeUserListGetter {
 func (f fakeUserListGetter) GetUsers() []*User {
 	return f.users
 }
+
+func (f fakeUserListGetter) GetMessages() []*Message {
+	return f.messages
+}
+
+func (f fakeUserListGetter) GetServer() *Server {
+	return f.server
+}
+
+func newTabCompletionServer() *Server {
+	server := NewServer("15:04:05", "server1", []config.ServerAddress{{Hostname: "irc.example.com", Port: 6697, TLS: true}}, false, "", "", "", "", config.Connection{}, NewProfile("nick"), nil, nil)
+	server.SetCommandManager(NewCommandManager(&config.Config{UISettings: config.UISettings{NickCompletionSuffix: ": "}}, nil))
+	for _, name := range []string{"#tithon", "#go-nuts", "#Test"} {
+		channel := NewChannel(server, name)
+		server.channels[channel.id] = channel
+	}
+	return server
+}
+
+func completeTimes(t TabCompleter, input string, position int, runs int) (string, int) {
+	for range runs {
+		input, position = t.Complete(input, position)
+	}
+	return input, position
+}
+
+func TestWindowTabCompleter_Complete(t *testing.T) {
+	server := newTabCompletionServer()
+	channel := newFakeUserList("dataforce", "demented", "md87")
+	channel.server = server
+	tests := []struct {
+		name         string
+		input        string
+		position     int
+		runs         int
+		want         string
+		wantPosition int
+	}{
+		{name: "Nick suffix at line start", input: "dat", position: 3, runs: 1, want: "dataforce: ", wantPosition: 11},
+		{name: "Nick suffix cycles", input: "d", position: 1, runs: 2, want: "demented: ", wantPosition: 10},
+		{name: "Nick suffix before text", input: "dat hello", position: 3, runs: 1, want: "dataforce: hello", wantPosition: 10},
+		{name: "No suffix mid line", input: "hi md", position: 5, runs: 1, want: "hi md87", wantPosition: 7},
+		{name: "Word in the middle", input: "hi dat there", position: 6, runs: 1, want: "hi dataforce there", wantPosition: 12},
+		{name: "Command", input: "/wi", position: 3, runs: 1, want: "/window", wantPosition: 7},
+		{name: "Command cycles", input: "/un", position: 3, runs: 2, want: "/unignore", wantPosition: 9},
+		{name: "Command argument", input: "/window set no", position: 14, runs: 1, want: "/window set notify", wantPosition: 18},
+		{name: "Command argument value", input: "/window set notify h", position: 20, runs: 1, want: "/window set notify highlights", wantPosition: 29},
+		{name: "Help argument", input: "/help even", position: 10, runs: 1, want: "/help events", wantPosition: 12},
+		{name: "Nick as command argument", input: "/whois md", position: 9, runs: 1, want: "/whois md87", wantPosition: 11},
+		{name: "Channel", input: "join #ti", position: 8, runs: 1, want: "join #tithon", wantPosition: 12},
+		{name: "Channel ignores case", input: "#t", position: 2, runs: 2, want: "#tithon", wantPosition: 7},
+		{name: "Channel as command argument", input: "/part #go", position: 9, runs: 1, want: "/part #go-nuts", wantPosition: 14},
+		{name: "Emoji", input: "nice :thumbsu", position: 13, runs: 1, want: "nice :thumbsup:", wantPosition: 15},
+		{name: "No matches", input: "hi zz", position: 5, runs: 2, want: "hi zz", wantPosition: 5},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, gotPosition := completeTimes(NewChannelTabCompleter(channel), tt.input, tt.position, tt.runs)
+			assert.Equal(t, tt.want, got)
+			assert.Equal(t, tt.wantPosition, gotPosition)
+		})
+	}
+}
+
+func TestWindowTabCompleter_NoSuffix(t *testing.T) {
+	server := newTabCompletionServer()
+	server.SetCommandManager(NewCommandManager(&config.Config{UISettings: config.UISettings{NickCompletionSuffix: config.NickCompletionSuffixNone}}, nil))
+	channel := newFakeUserList("dataforce")
+	channel.server = server
+	got, position := NewChannelTabCompleter(channel).Complete("dat", 3)
+	assert.Equal(t, "dataforce", got)
+	assert.Equal(t, 9, position)
+}
+
+func TestRecentMessages(t *testing.T) {
+	messages := make([]*Message, activityMessages+10)
+	for i := range messages {
+		messages[i] = NewMessage("", false, "alice", "hello", nil, nil)
+	}
+	recent := recentMessages(messages)
+	assert.Len(t, recent, activityMessages)
+	assert.Same(t, messages[len(messages)-1], recent[len(recent)-1])
+	assert.Len(t, recentMessages(messages[:5]), 5)
+}
+
+func TestChannelTabCompleter_Activity(t *testing.T) {
+	channel := newFakeUserList("dataforce", "demented", "dumbo")
+	start := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	at := func(minutes int, message *Message) *Message {
+		message.timestamp = start.Add(time.Duration(minutes) * time.Minute)
+		return message
+	}
+	channel.messages = []*Message{
+		at(0, NewMessage("", false, "demented", "hello", nil, nil)),
+		at(1, NewMessage("", false, "Dumbo", "hi", nil, nil)),
+		at(2, NewMembershipEvent(EventJoin, "", "dataforce", "dataforce!d@example.com has joined #test")),
+		at(3, NewMessage("", true, "dataforce", "mine", nil, nil)),
+	}
+	completer := NewChannelTabCompleter(channel)
+
+	got, _ := completeTimes(completer, "d", 1, 1)
+	assert.Equal(t, "dumbo", got, "Most recent speaker should be first")
+	got, _ = completeTimes(completer, got, 5, 1)
+	assert.Equal(t, "demented", got)
+	got, _ = completeTimes(completer, got, 8, 1)
+	assert.Equal(t, "dataforce", got, "Users who haven't spoken should come last")
+}
+
+func TestQueryTabCompleter_Complete(t *testing.T) {
+	server := newTabCompletionServer()
+	query := NewQuery(server, "bob")
+
+	got, position := query.GetTabCompleter().Complete("b", 1)
+	assert.Equal(t, "bob: ", got)
+	assert.Equal(t, 5, position)
+	got, _ = query.GetTabCompleter().Complete("hi n", 4)
+	assert.Equal(t, "hi nick", got)
+	got, _ = query.GetTabCompleter().Complete("/ct", 3)
+	assert.Equal(t, "/ctcp", got)
+	got, _ = query.GetTabCompleter().Complete("/ctcp bob V", 11)
+	assert.Equal(t, "/ctcp bob VERSION", got)
+}
+
+func TestServerTabCompleter_Complete(t *testing.T) {
+	server := newTabCompletionServer()
+	channel, err := server.GetChannelByName("#tithon")
+	require.NoError(t, err)
+	channel.AddUser(NewUser("alice", ""))
+	channel.AddMessage(NewMessage("", false, "alice", "hello", nil, nil))
+	other, err := server.GetChannelByName("#go-nuts")
+	require.NoError(t, err)
+	other.AddUser(NewUser("alice", ""))
+
+	got, _ := server.GetTabCompleter().Complete("hi al", 5)
+	assert.Equal(t, "hi alice", got)
+	got, _ = server.GetTabCompleter().Complete(got, 8)
+	assert.Equal(t, "hi alice", got, "users in several channels should only be offered once")
+	got, _ = server.GetTabCompleter().Complete("#go", 3)
+	assert.Equal(t, "#go-nuts", got)
+	got, _ = server.GetTabCompleter().Complete("/recon", 6)
+	assert.Equal(t, "/reconnect", got)
+}
@@ -27,6 +27,11 @@ func (t *Topic) GetTopic() string {
 	return t.topic
 }
 
+// GetText returns the topic, or an empty string if there isn't one
+func (t *Topic) GetText() string {
+	return t.topic
+}
+
 func (t *Topic) GetSetBy() string {
 	return t.setBy
 }
@@ -1,8 +1,24 @@
 package irc
 
+import (
+	"strings"
+	"sync"
+)
+
 type User struct {
+	// mutex guards the user's details, they're changed by nick and mode changes, NAMES, WHO replies and away-notify
+	// while the nicklist is being shown
+	mutex    sync.Mutex
 	nickname string
 	modes    string
+	// ident and host are only known once the user has joined or been listed with userhost-in-names
+	ident string
+	host  string
+	// account, realname and away are only known once the user has been listed by WHO, away is also kept up to date
+	// by away-notify
+	account  string
+	realname string
+	away     bool
 }
 
 func NewUser(nickname string, modes string) *User {
@@ -10,9 +26,111 @@ func NewUser(nickname string, modes string) *User {
 }
 
 func (u *User) GetNickListDisplay() string {
-	return u.nickname
+	return u.GetNickname()
 }
 
 func (u *User) GetNickListModes() string {
+	u.mutex.Lock()
+	defer u.mutex.Unlock()
 	return u.modes
 }
+
+func (u *User) GetNickname() string {
+	u.mutex.Lock()
+	defer u.mutex.Unlock()
+	return u.nickname
+}
+
+func (u *User) setNickname(nickname string) {
+	u.mutex.Lock()
+	defer u.mutex.Unlock()
+	u.nickname = nickname
+}
+
+func (u *User) setModes(modes string) {
+	u.mutex.Lock()
+	defer u.mutex.Unlock()
+	u.modes = modes
+}
+
+// addMode gives the user a privilege, using its prefix
+func (u *User) addMode(mode string) {
+	u.mutex.Lock()
+	defer u.mutex.Unlock()
+	u.modes += mode
+}
+
+// removeMode takes a privilege away from the user, using its prefix
+func (u *User) removeMode(mode string) {
+	u.mutex.Lock()
+	defer u.mutex.Unlock()
+	u.modes = strings.Replace(u.modes, mode, "", -1)
+}
+
+// getUserHost returns the user's ident and host, they're empty if they aren't known
+func (u *User) getUserHost() (string, string) {
+	u.mutex.Lock()
+	defer u.mutex.Unlock()
+	return u.ident, u.host
+}
+
+// GetHostmask returns the user's nick!ident@host, or just the nickname if their host isn't known
+func (u *User) GetHostmask() string {
+	u.mutex.Lock()
+	defer u.mutex.Unlock()
+	if u.host == "" {
+		return u.nickname
+	}
+	return u.nickname + "!" + u.ident + "@" + u.host
+}
+
+// GetAccount returns the account the user is logged in to, or an empty string if they aren't or it isn't known
+func (u *User) GetAccount() string {
+	u.mutex.Lock()
+	defer u.mutex.Unlock()
+	return u.account
+}
+
+func (u *User) GetRealname() string {
+	u.mutex.Lock()
+	defer u.mutex.Unlock()
+	return u.realname
+}
+
+func (u *User) IsAway() bool {
+	u.mutex.Lock()
+	defer u.mutex.Unlock()
+	return u.away
+}
+
+func (u *User) setAway(away bool) {
+	u.mutex.Lock()
+	defer u.mutex.Unlock()
+	u.away = away
+}
+
+// setWhoReply updates the user with the details listed in a WHO reply
+func (u *User) setWhoReply(reply whoReply) {
+	u.setUserHost(reply.ident, reply.host)
+	u.mutex.Lock()
+	defer u.mutex.Unlock()
+	u.away = strings.HasPrefix(reply.flags, "G")
+	if reply.hasAccount {
+		u.account = reply.account
+	}
+	if reply.realname != "" {
+		u.realname = reply.realname
+	}
+}
+
+// setUserHost sets the ident and host of the user if they're known
+func (u *User) setUserHost(ident, host string) {
+	u.mutex.Lock()
+	defer u.mutex.Unlock()
+	if ident != "" {
+		u.ident = ident
+	}
+	if host != "" {
+		u.host = host
+	}
+}
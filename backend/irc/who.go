@@ -0,0 +1,266 @@
+package irc
+
+import (
+	"log/slog"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// whoxToken identifies replies to our WHOX requests
+	whoxToken = "745"
+	// whoxFields requests the token, channel, ident, host, nickname, flags, account and realname.  Replies always list
+	// the fields in this order.
+	whoxFields = "%tcuhnfar"
+	// whoTimeout is how long to wait for the end of a WHO before giving up on it, so a missing reply doesn't stop
+	// channels being refreshed
+	whoTimeout = 2 * time.Minute
+)
+
+// whoRequest is a WHO that has been sent to the server, show is set when the replies should be shown to the user
+// rather than only used to update the nicklists
+type whoRequest struct {
+	mask string
+	show bool
+	sent time.Time
+	// users indexes the users in every channel by lowercase nickname, it's built on the first reply so each reply
+	// doesn't need to search every channel
+	users map[string][]*User
+}
+
+// whoRequests tracks WHO requests in the order they were sent, servers reply to each in turn so the first request is the
+// one currently being answered
+type whoRequests struct {
+	lock     sync.Mutex
+	requests []whoRequest
+}
+
+func (q *whoRequests) add(request whoRequest) {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+	request.sent = time.Now()
+	q.requests = append(q.requests, request)
+}
+
+// cancel removes the most recent request for the mask, when it couldn't be sent
+func (q *whoRequests) cancel(mask string) {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+	for i := len(q.requests) - 1; i >= 0; i-- {
+		if q.requests[i].mask == mask {
+			q.requests = slices.Delete(q.requests, i, i+1)
+			return
+		}
+	}
+}
+
+// expire removes requests that were sent longer than timeout ago without the server finishing its reply
+func (q *whoRequests) expire(timeout time.Duration) {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+	q.requests = slices.DeleteFunc(q.requests, func(request whoRequest) bool {
+		return time.Since(request.sent) > timeout
+	})
+}
+
+// current returns the request being answered, replies to a WHO sent by something else aren't shown
+func (q *whoRequests) current() whoRequest {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+	if len(q.requests) == 0 {
+		return whoRequest{}
+	}
+	return q.requests[0]
+}
+
+// end removes the request for the mask once the server has finished replying, returning it
+func (q *whoRequests) end(mask string) whoRequest {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+	index := slices.IndexFunc(q.requests, func(request whoRequest) bool {
+		return strings.EqualFold(request.mask, mask)
+	})
+	if index == -1 && len(q.requests) > 0 {
+		// The server didn't echo the mask we sent, it's still replying in order
+		index = 0
+	} else if index == -1 {
+		return whoRequest{}
+	}
+	request := q.requests[index]
+	q.requests = slices.Delete(q.requests, index, index+1)
+	return request
+}
+
+// findUsers returns the user with the nickname in every channel, using the index for the request being answered
+func (q *whoRequests) findUsers(nickname string, getChannels func() []*Channel) []*User {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+	if len(q.requests) == 0 {
+		return indexUsers(getChannels())[strings.ToLower(nickname)]
+	}
+	if q.requests[0].users == nil {
+		q.requests[0].users = indexUsers(getChannels())
+	}
+	return q.requests[0].users[strings.ToLower(nickname)]
+}
+
+func (q *whoRequests) isEmpty() bool {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+	return len(q.requests) == 0
+}
+
+func (q *whoRequests) clear() {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+	q.requests = nil
+}
+
+// hasWhox checks if the server supports WHOX, which includes the user's account in replies
+func (c *Server) hasWhox() bool {
+	_, ok := c.connection.ISupport()["WHOX"]
+	return ok
+}
+
+// RequestWho sends a WHO for a channel or mask, using WHOX if the server supports it.  The replies update the users
+// in any channels we share with them, and are shown in the server window if show is set.
+func (c *Server) RequestWho(mask string, show bool, priority SendPriority) error {
+	c.whoQueue.add(whoRequest{mask: mask, show: show})
+	var err error
+	if c.hasWhox() {
+		err = c.send(priority, "WHO", mask, whoxFields+","+whoxToken)
+	} else {
+		err = c.send(priority, "WHO", mask)
+	}
+	if err != nil {
+		c.whoQueue.cancel(mask)
+	}
+	return err
+}
+
+// findWhoUsers returns the user with the nickname in every channel, for updating from WHO replies
+func (c *Server) findWhoUsers(nickname string) []*User {
+	return c.whoQueue.findUsers(nickname, c.GetChannels)
+}
+
+// RequestWhowas asks the server about a nickname that's no longer in use
+func (c *Server) RequestWhowas(nickname string) error {
+	return c.send(PriorityInteractive, "WHOWAS", nickname)
+}
+
+// requestChannelState asks for the modes of a channel we've just joined, its users are requested by requestJoinWho
+// once NAMES has said how many there are
+func (c *Server) requestChannelState(channel string) error {
+	if window, err := c.GetChannelByName(channel); err == nil {
+		window.setWhoRefreshed(time.Now())
+		window.setJoinWho()
+	}
+	return c.RequestChannelModes(channel)
+}
+
+// requestJoinWho sends a WHO for a channel we've just joined, skipping channels with more users than the WHO refresh
+// allows
+func (c *Server) requestJoinWho(channel string) error {
+	window, err := c.GetChannelByName(channel)
+	if err != nil || !window.takeJoinWho() {
+		return nil
+	}
+	if c.settings.WhoMaxUsers > 0 && len(window.GetUsers()) > c.settings.WhoMaxUsers {
+		return nil
+	}
+	return c.RequestWho(channel, false, PriorityBulk)
+}
+
+// indexUsers maps the lowercase nickname of every user in the channels to the users, there's one for each channel
+// they're in
+func indexUsers(channels []*Channel) map[string][]*User {
+	users := make(map[string][]*User)
+	for _, channel := range channels {
+		for _, user := range channel.GetUsers() {
+			nickname := strings.ToLower(user.GetNickname())
+			users[nickname] = append(users[nickname], user)
+		}
+	}
+	return users
+}
+
+// updateUsers applies a change to the user with the nickname in every channel
+func updateUsers(channels []*Channel, nickname string, update func(*User)) {
+	for _, channel := range channels {
+		for _, user := range channel.GetUsers() {
+			if strings.EqualFold(user.GetNickname(), nickname) {
+				update(user)
+			}
+		}
+	}
+}
+
+func (c *Server) startWhoLoop() {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if c.whoStop != nil {
+		close(c.whoStop)
+		c.whoStop = nil
+	}
+	if c.settings.WhoInterval <= 0 {
+		return
+	}
+	c.whoStop = make(chan struct{})
+	go c.whoLoop(c.whoStop, c.settings.WhoInterval)
+}
+
+func (c *Server) stopWhoLoop() {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if c.whoStop != nil {
+		close(c.whoStop)
+		c.whoStop = nil
+	}
+	c.whoQueue.clear()
+}
+
+func (c *Server) whoLoop(stop chan struct{}, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			c.refreshWho()
+		}
+	}
+}
+
+// refreshWho sends a WHO for the channel that was refreshed longest ago.  Only one channel is refreshed at a time, and
+// not at all when away-notify keeps away status up to date.
+func (c *Server) refreshWho() {
+	c.whoQueue.expire(whoTimeout)
+	if c.HasCapability("away-notify") || !c.whoQueue.isEmpty() {
+		return
+	}
+	channel := nextWhoRefresh(c.GetChannels(), c.settings.WhoMaxUsers)
+	if channel == nil {
+		return
+	}
+	channel.setWhoRefreshed(time.Now())
+	if err := c.RequestWho(channel.GetName(), false, PriorityBulk); err != nil {
+		slog.Debug("Unable to refresh users", "channel", channel.GetName(), "error", err)
+	}
+}
+
+// nextWhoRefresh returns the channel that was refreshed longest ago, skipping channels with more than maxUsers users
+func nextWhoRefresh(channels []*Channel, maxUsers int) *Channel {
+	var next *Channel
+	for _, channel := range channels {
+		if maxUsers > 0 && len(channel.GetUsers()) > maxUsers {
+			continue
+		}
+		if next == nil || channel.getWhoRefreshed().Before(next.getWhoRefreshed()) {
+			next = channel
+		}
+	}
+	return next
+}
@@ -0,0 +1,100 @@
+package irc
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ergochat/irc-go/ircevent"
+	"github.com/greboid/tithon/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWhoRequests(t *testing.T) {
+	queue := &whoRequests{}
+	assert.True(t, queue.isEmpty())
+	assert.Equal(t, whoRequest{}, queue.current())
+	queue.add(whoRequest{mask: "#tithon"})
+	queue.add(whoRequest{mask: "alice", show: true})
+	assert.Equal(t, "#tithon", queue.current().mask)
+	assert.False(t, queue.current().sent.IsZero())
+
+	assert.Equal(t, "#tithon", queue.end("#TITHON").mask)
+	assert.Equal(t, "alice", queue.current().mask)
+	request := queue.end("Alice!*@*")
+	assert.True(t, request.show, "unknown masks end the current request")
+	assert.Equal(t, "alice", request.mask)
+	assert.True(t, queue.isEmpty())
+	assert.Equal(t, whoRequest{}, queue.end("#tithon"))
+}
+
+func TestWhoRequests_Cancel(t *testing.T) {
+	queue := &whoRequests{}
+	queue.add(whoRequest{mask: "#tithon"})
+	queue.add(whoRequest{mask: "alice"})
+	queue.add(whoRequest{mask: "#tithon", show: true})
+	queue.cancel("#tithon")
+	queue.cancel("bob")
+	require.Len(t, queue.requests, 2)
+	assert.Equal(t, "#tithon", queue.requests[0].mask)
+	assert.False(t, queue.requests[0].show, "the most recent request should be removed")
+	assert.Equal(t, "alice", queue.requests[1].mask)
+}
+
+func TestWhoRequests_Expire(t *testing.T) {
+	queue := &whoRequests{}
+	queue.add(whoRequest{mask: "#old"})
+	queue.add(whoRequest{mask: "#new"})
+	queue.requests[0].sent = time.Now().Add(-2 * whoTimeout)
+	queue.expire(whoTimeout)
+	require.Len(t, queue.requests, 1)
+	assert.Equal(t, "#new", queue.current().mask)
+}
+
+func TestServer_RequestWhoFailed(t *testing.T) {
+	server := &Server{connection: &ircevent.Connection{}, sendQueue: NewSendQueue(1, 0, nil, nil)}
+	assert.Error(t, server.RequestWho("#tithon", false, PriorityBulk))
+	assert.True(t, server.whoQueue.isEmpty(), "Requests that weren't sent shouldn't be waiting for a reply")
+}
+
+func TestNextWhoRefresh(t *testing.T) {
+	newChannel := func(name string, users int, refreshed time.Time) *Channel {
+		channel := &Channel{Window: &Window{name: name, hasUsers: true}, whoRefreshed: refreshed}
+		for range users {
+			channel.users = append(channel.users, &User{})
+		}
+		return channel
+	}
+	now := time.Now()
+	recent := newChannel("#recent", 5, now)
+	old := newChannel("#old", 5, now.Add(-time.Hour))
+	large := newChannel("#large", 50, time.Time{})
+	assert.Equal(t, old, nextWhoRefresh([]*Channel{recent, old, large}, 10))
+	assert.Equal(t, large, nextWhoRefresh([]*Channel{recent, old, large}, 0), "0 doesn't limit the number of users")
+	assert.Nil(t, nextWhoRefresh([]*Channel{large}, 10))
+}
+
+func TestServer_RequestJoinWho(t *testing.T) {
+	newChannel := func(name string, users int, joined bool) *Channel {
+		channel := &Channel{Window: &Window{id: name, name: name, hasUsers: true}, joinWho: joined}
+		for range users {
+			channel.users = append(channel.users, &User{})
+		}
+		return channel
+	}
+	server := &Server{
+		connection: &ircevent.Connection{},
+		sendQueue:  NewSendQueue(1, 0, nil, nil),
+		settings:   config.Connection{WhoMaxUsers: 10},
+		channels: map[string]*Channel{
+			"#small": newChannel("#small", 5, true),
+			"#large": newChannel("#large", 50, true),
+			"#names": newChannel("#names", 5, false),
+		},
+	}
+	assert.Error(t, server.requestJoinWho("#small"), "joined channels should be sent a WHO")
+	assert.NoError(t, server.requestJoinWho("#small"), "only the first NAMES after joining should send a WHO")
+	assert.NoError(t, server.requestJoinWho("#large"), "channels with too many users shouldn't be sent a WHO")
+	assert.NoError(t, server.requestJoinWho("#names"), "NAMES for channels that weren't just joined shouldn't send a WHO")
+	assert.True(t, server.whoQueue.isEmpty())
+}
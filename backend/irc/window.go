@@ -29,6 +29,8 @@ type Window struct {
 	isServer     bool
 	isChannel    bool
 	isQuery      bool
+	isMentions   bool
+	unread       int
 	tabCompleter TabCompleter
 }
 
@@ -52,8 +54,32 @@ func (c *Window) SetName(name string) {
 }
 
 func (c *Window) AddMessage(message *Message) {
+	if message.window == nil && c.connection != nil {
+		message.parseTargets(c.getTargetNicknames(), c.connection.getChanTypes())
+	}
+	c.addMessage(message)
+	if c.connection == nil || message.GetWindow() != c {
+		return
+	}
+	c.connection.searchIndex.Add(c, message)
+	c.connection.messageLogger.Log(c, message)
+	c.connection.linkPreviewer.Preview(message, c.connection.ut)
+	if c.connection.mentions != nil && c.isMention(message) {
+		c.connection.mentions.AddMessage(message)
+	}
+}
+
+func (c *Window) addMessage(message *Message) {
+	settings := c.GetSettings()
+	var filter *EventFilter
+	if c.connection != nil {
+		filter = c.connection.eventFilter
+	}
 	c.stateSync.Lock()
 	defer c.stateSync.Unlock()
+	if message.window == nil {
+		message.window = c
+	}
 	c.messages = append(c.messages, message)
 	if c.state == Active {
 		return
@@ -61,17 +87,56 @@ func (c *Window) AddMessage(message *Message) {
 	if message.tags["chathistory"] != "true" {
 		switch message.messageType {
 		case Error, Event:
-			if !message.IsMe() {
+			if !message.IsMe() && !settings.Muted && !filter.hides(settings.Membership, message, c.messages[:len(c.messages)-1]) {
 				c.state = UnreadEvent
 			}
 		case Normal, Notice, Action:
-			c.state = UnreadMessage
+			if !settings.Muted {
+				c.state = UnreadMessage
+				c.unread++
+			}
 		case Highlight, HighlightNotice, HighlightAction:
 			c.state = UnreadHighlight
+			c.unread++
 		}
 	}
 }
 
+// isMention checks if a message should also be shown in the mentions window, which collects highlights and private
+// messages from every server
+func (c *Window) isMention(message *Message) bool {
+	if message.IsMe() || message.tags["chathistory"] == "true" {
+		return false
+	}
+	switch message.messageType {
+	case Highlight, HighlightNotice, HighlightAction:
+		return true
+	case Normal, Notice, Action:
+		return c.isQuery
+	default:
+		return false
+	}
+}
+
+// getTargetNicknames returns the nicknames that can be clicked in messages in this window, the users in a channel or
+// the other user in a query
+func (c *Window) getTargetNicknames() []string {
+	if c.isQuery {
+		return []string{c.GetName()}
+	}
+	currentNick := ""
+	if c.connection.connection != nil {
+		currentNick = c.connection.CurrentNick()
+	}
+	var nicknames []string
+	for _, user := range c.GetUsers() {
+		if nickname := user.GetNickname(); !strings.EqualFold(nickname, currentNick) {
+			nicknames = append(nicknames, nickname)
+		}
+	}
+	return nicknames
+}
+
 func (c *Window) GetMessages() []*Message {
 	c.stateSync.Lock()
 	defer c.stateSync.Unlock()
@@ -80,6 +145,15 @@ func (c *Window) GetMessages() []*Message {
 	return messages
 }
 
+// GetDisplayMessages returns the messages to show in the window, with joins, parts and quits filtered in channels
+func (c *Window) GetDisplayMessages() []*Message {
+	messages := c.GetMessages()
+	if !c.isChannel || c.connection == nil {
+		return messages
+	}
+	return c.connection.eventFilter.Filter(c.GetSettings().Membership, messages)
+}
+
 func (c *Window) GetServer() *Server {
 	return c.connection
 }
@@ -89,11 +163,19 @@ func (c *Window) SetActive(b bool) {
 	defer c.stateSync.Unlock()
 	if b {
 		c.state = Active
+		c.unread = 0
 	} else {
 		c.state = Read
 	}
 }
 
+// GetUnreadCount returns the number of messages added since the window was last active
+func (c *Window) GetUnreadCount() int {
+	c.stateSync.Lock()
+	defer c.stateSync.Unlock()
+	return c.unread
+}
+
 func (c *Window) GetState() string {
 	c.stateSync.Lock()
 	defer c.stateSync.Unlock()
@@ -126,11 +208,11 @@ func (c *Window) GetUsers() []*User {
 func (c *Window) SortUsers() {
 	// TODO: Pull out info function
 	slices.SortFunc(c.users, func(a, b *User) int {
-		modeCmp := strings.Compare(b.modes, a.modes)
+		modeCmp := strings.Compare(b.GetNickListModes(), a.GetNickListModes())
 		if modeCmp != 0 {
 			return modeCmp
 		}
-		return strings.Compare(a.nickname, b.nickname)
+		return strings.Compare(a.GetNickname(), b.GetNickname())
 	})
 }
 
@@ -151,6 +233,9 @@ func (c *Window) IsChannel() bool {
 func (c *Window) IsQuery() bool {
 	return c.isQuery
 }
+func (c *Window) IsMentions() bool {
+	return c.isMentions
+}
 
 func (c *Window) GetTabCompleter() TabCompleter {
 	return c.tabCompleter
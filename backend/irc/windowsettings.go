@@ -0,0 +1,120 @@
+package irc
+
+import (
+	"errors"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/greboid/tithon/config"
+)
+
+// WindowSettingsList holds the settings for every channel and query that has its own, changes are passed to the save
+// callback so they can be persisted
+type WindowSettingsList struct {
+	mutex    sync.RWMutex
+	settings []config.WindowSettings
+	save     func([]config.WindowSettings)
+}
+
+func NewWindowSettingsList(settings []config.WindowSettings, save func([]config.WindowSettings)) *WindowSettingsList {
+	return &WindowSettingsList{settings: slices.Clone(settings), save: save}
+}
+
+// Get returns the settings for a window, windows without their own settings get the defaults
+func (l *WindowSettingsList) Get(serverID string, window string) config.WindowSettings {
+	if l == nil {
+		return config.WindowSettings{Network: serverID, Window: window}
+	}
+	l.mutex.RLock()
+	defer l.mutex.RUnlock()
+	for i := range l.settings {
+		if l.settings[i].Network == serverID && strings.EqualFold(l.settings[i].Window, window) {
+			settings := l.settings[i]
+			settings.Highlights = slices.Clone(settings.Highlights)
+			return settings
+		}
+	}
+	return config.WindowSettings{Network: serverID, Window: window}
+}
+
+// Set replaces the settings for a window, settings that don't change anything are removed
+func (l *WindowSettingsList) Set(settings config.WindowSettings) error {
+	if l == nil {
+		return errors.New("window settings unavailable")
+	}
+	if settings.Network == "" || settings.Window == "" {
+		return errors.New("no window specified")
+	}
+	if settings.Notify != "" && !slices.Contains(config.NotifyLevels, settings.Notify) {
+		return errors.New("unknown notification level: " + settings.Notify)
+	}
+	if settings.Membership != "" && !slices.Contains(config.MembershipModes, settings.Membership) {
+		return errors.New("unknown mode: " + settings.Membership)
+	}
+	settings.Highlights = slices.DeleteFunc(slices.Clone(settings.Highlights), func(word string) bool {
+		return strings.TrimSpace(word) == ""
+	})
+	l.mutex.Lock()
+	l.settings = slices.DeleteFunc(l.settings, func(existing config.WindowSettings) bool {
+		return existing.Network == settings.Network && strings.EqualFold(existing.Window, settings.Window)
+	})
+	if !settings.IsDefault() {
+		l.settings = append(l.settings, settings)
+	}
+	l.mutex.Unlock()
+	l.persist()
+	return nil
+}
+
+// Entries returns the settings for every window that has its own
+func (l *WindowSettingsList) Entries() []config.WindowSettings {
+	if l == nil {
+		return nil
+	}
+	l.mutex.RLock()
+	defer l.mutex.RUnlock()
+	return slices.Clone(l.settings)
+}
+
+func (l *WindowSettingsList) persist() {
+	if l.save != nil {
+		l.save(l.Entries())
+	}
+}
+
+// shouldNotify checks if a message in the window can show a notification at its notification level
+func (l *WindowSettingsList) shouldNotify(serverID string, window string, highlight bool) bool {
+	switch l.Get(serverID, window).Notify {
+	case config.NotifyNone:
+		return false
+	case config.NotifyHighlights:
+		return highlight
+	default:
+		return true
+	}
+}
+
+// SetWindowSettings sets the settings used for the server's channels and queries
+func (c *Server) SetWindowSettings(settings *WindowSettingsList) {
+	c.windowSettings = settings
+}
+
+// GetWindowSettings returns the settings for one of the server's channels or queries
+func (c *Server) GetWindowSettings(window string) config.WindowSettings {
+	return c.windowSettings.Get(c.GetID(), window)
+}
+
+// UpdateWindowSettings replaces the settings for one of the server's channels or queries
+func (c *Server) UpdateWindowSettings(settings config.WindowSettings) error {
+	settings.Network = c.GetID()
+	return c.windowSettings.Set(settings)
+}
+
+// GetSettings returns the window's own settings, server windows don't have any
+func (c *Window) GetSettings() config.WindowSettings {
+	if c.connection == nil || c.connection.windowSettings == nil || c.isServer || c.isMentions {
+		return config.WindowSettings{}
+	}
+	return c.connection.GetWindowSettings(c.GetName())
+}
@@ -0,0 +1,116 @@
+package irc
+
+import (
+	"testing"
+	"time"
+
+	"github.com/greboid/tithon/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWindowSettingsList_Set(t *testing.T) {
+	var saved []config.WindowSettings
+	list := NewWindowSettingsList(nil, func(settings []config.WindowSettings) {
+		saved = settings
+	})
+
+	require.NoError(t, list.Set(config.WindowSettings{Network: "libera", Window: "#tithon", Notify: config.NotifyNone, Highlights: []string{"go", " "}}))
+	want := config.WindowSettings{Network: "libera", Window: "#tithon", Notify: config.NotifyNone, Highlights: []string{"go"}}
+	assert.Equal(t, want, list.Get("libera", "#TITHON"))
+	assert.Equal(t, []config.WindowSettings{want}, saved)
+	assert.Equal(t, config.WindowSettings{Network: "oftc", Window: "#tithon"}, list.Get("oftc", "#tithon"))
+
+	require.NoError(t, list.Set(config.WindowSettings{Network: "libera", Window: "#Tithon", Muted: true}))
+	assert.Equal(t, []config.WindowSettings{{Network: "libera", Window: "#Tithon", Muted: true}}, saved, "Settings should be replaced")
+
+	require.NoError(t, list.Set(config.WindowSettings{Network: "libera", Window: "#tithon"}))
+	assert.Empty(t, saved, "Default settings shouldn't be stored")
+
+	assert.Error(t, list.Set(config.WindowSettings{Network: "libera"}))
+	assert.Error(t, list.Set(config.WindowSettings{Network: "libera", Window: "#tithon", Notify: "sometimes"}))
+	assert.Error(t, list.Set(config.WindowSettings{Network: "libera", Window: "#tithon", Membership: "sometimes"}))
+}
+
+func TestWindowSettingsList_ShouldNotify(t *testing.T) {
+	list := NewWindowSettingsList([]config.WindowSettings{
+		{Network: "libera", Window: "#quiet", Notify: config.NotifyNone},
+		{Network: "libera", Window: "#busy", Notify: config.NotifyHighlights},
+	}, nil)
+	tests := []struct {
+		window    string
+		highlight bool
+		want      bool
+	}{
+		{window: "#other", want: true},
+		{window: "#quiet", highlight: true, want: false},
+		{window: "#busy", want: false},
+		{window: "#busy", highlight: true, want: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.window, func(t *testing.T) {
+			assert.Equal(t, tt.want, list.shouldNotify("libera", tt.window, tt.highlight))
+		})
+	}
+	var empty *WindowSettingsList
+	assert.True(t, empty.shouldNotify("libera", "#quiet", false))
+}
+
+func TestNotificationManager_WindowSettings(t *testing.T) {
+	pending := make(chan Notification, 1)
+	list := NewWindowSettingsList([]config.WindowSettings{{Network: "id", Window: "#quiet", Notify: config.NotifyNone}}, nil)
+	nm := NewNotificationManager(pending, []config.NotificationTrigger{{Popup: true}}, list)
+
+	nm.CheckAndNotify("network", "id", "#quiet", "alice", "hi", true)
+	assert.Empty(t, pending, "Windows with notifications off shouldn't notify")
+	nm.CheckAndNotify("network", "id", "#other", "alice", "hi", false)
+	assert.Len(t, pending, 1)
+}
+
+func TestWindow_AddMessage_Muted(t *testing.T) {
+	server := &Server{Window: &Window{id: "libera"}}
+	server.SetWindowSettings(NewWindowSettingsList([]config.WindowSettings{{Network: "libera", Window: "#tithon", Muted: true}}, nil))
+	window := &Window{name: "#tithon", isChannel: true, connection: server}
+
+	window.addMessage(NewMessage(time.TimeOnly, false, "alice", "hello", nil, nil))
+	window.addMessage(NewEvent(EventTopic, time.TimeOnly, false, "topic changed"))
+	assert.Equal(t, "", window.GetState(), "Muted windows shouldn't be marked unread")
+	assert.Equal(t, 0, window.GetUnreadCount())
+
+	window.addMessage(NewMessage(time.TimeOnly, false, "alice", "hello", nil, HighlightWords{"hello"}))
+	assert.Equal(t, UnreadHighlight, window.GetState(), "Highlights should still mark muted windows")
+	assert.Equal(t, 1, window.GetUnreadCount())
+}
+
+func TestSetWindowSetting(t *testing.T) {
+	tests := []struct {
+		name    string
+		setting string
+		value   string
+		want    config.WindowSettings
+		wantErr bool
+	}{
+		{name: "Notify", setting: "notify", value: "none", want: config.WindowSettings{Notify: "none"}},
+		{name: "Muted", setting: "muted", value: "on", want: config.WindowSettings{Muted: true}},
+		{name: "Unmuted", setting: "muted", value: "off", want: config.WindowSettings{}},
+		{name: "Invalid muted", setting: "muted", value: "yes", wantErr: true},
+		{name: "Events", setting: "events", value: "smart", want: config.WindowSettings{Membership: "smart"}},
+		{name: "Default events", setting: "events", value: "default", want: config.WindowSettings{}},
+		{name: "Highlights", setting: "highlights", value: "go, tithon,,", want: config.WindowSettings{Highlights: []string{"go", "tithon"}}},
+		{name: "Logging off", setting: "logging", value: "off", want: config.WindowSettings{NoLog: true}},
+		{name: "Logging on", setting: "logging", value: "on", want: config.WindowSettings{}},
+		{name: "Invalid logging", setting: "logging", value: "maybe", wantErr: true},
+		{name: "Unknown", setting: "colour", value: "red", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := setWindowSetting(config.WindowSettings{}, tt.setting, tt.value)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
@@ -29,6 +29,10 @@ func main() {
 
 	config.SetConfigNames(*ConfigDirName, *ConfigFilename)
 
+	if flag.Arg(0) == "import" {
+		os.Exit(runImport(flag.Args()[1:]))
+	}
+
 	provider, err := config.NewDefaultConfigProvider()
 	if err != nil {
 		slog.Error("Unable to load config", "error", err)
@@ -49,13 +53,37 @@ func main() {
 	}()
 	showSettings := make(chan bool)
 	pendingNotifications := make(chan irc.Notification, 10000)
-	notificationManager := irc.NewNotificationManager(pendingNotifications, conf.Notifications.Triggers)
+	windowSettings := irc.NewWindowSettingsList(conf.Windows, func(windows []config.WindowSettings) {
+		conf.Windows = windows
+		if err := conf.Save(); err != nil {
+			slog.Error("Unable to save window settings", "error", err)
+		}
+	})
+	notificationManager := irc.NewNotificationManager(pendingNotifications, conf.Notifications.Triggers, windowSettings)
 	commandManager := irc.NewCommandManager(conf, showSettings)
 	connectionManager := irc.NewServerManager(conf.UISettings.TimestampFormat, commandManager)
+	connectionManager.SetConnectionSettings(conf.Connection)
+	connectionManager.SetIgnoreList(irc.NewIgnoreList(conf.Ignores, func(ignores []config.Ignore) {
+		conf.Ignores = ignores
+		if err := conf.Save(); err != nil {
+			slog.Error("Unable to save ignore list", "error", err)
+		}
+	}))
+	connectionManager.SetEventFilter(irc.NewEventFilter(conf.EventFilter, func(filter config.EventFilter) {
+		conf.EventFilter = filter
+		if err := conf.Save(); err != nil {
+			slog.Error("Unable to save event filter", "error", err)
+		}
+	}))
+	connectionManager.SetWindowSettings(windowSettings)
+	connectionManager.SetHighlightRules(irc.NewHighlightRules(conf.Highlights))
+	connectionManager.SetMessageLogger(irc.NewMessageLogger(conf.Logging))
+	connectionManager.SetLinkPreviewer(irc.NewLinkPreviewer(conf.LinkPreviews, config.GetLinkPreviewDir()))
+	connectionManager.SetHistory(irc.NewHistory(config.GetHistoryDir()))
 	defer connectionManager.Stop()
 
 	settingsService := services.NewSettingsService(conf)
-	inputHistoryService := services.NewInputHistoryService()
+	inputHistoryService := services.NewInputHistoryService(config.GetInputHistoryFile(), conf.InputHistory)
 	serverListService := services.NewServerListService()
 	notificationService := services.NewNotificationService(pendingNotifications)
 
@@ -78,6 +106,8 @@ func main() {
 	connectionManager.SetUpdateTrigger(server)
 	connectionManager.SetNotificationManager(notificationManager)
 	connectionManager.SetWindowRemovalCallback(server)
+	connectionManager.SetHTMLExporter(server)
+	connectionManager.SetChannelListViewer(server)
 	connectionManager.Load(conf.Servers)
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
@@ -1,111 +1,305 @@
 package services
 
 import (
+	"encoding/json"
+	"log/slog"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
 	"sync"
+
+	"github.com/greboid/tithon/config"
 )
 
+// globalHistory is the key used for the shared history when InputHistory.Global is set
+const globalHistory = ""
+
+type inputHistory struct {
+	lines    []string
+	position int
+	// pending is what was in the input before moving up through the history, it's restored after moving back down
+	// past the most recent line
+	pending string
+}
+
+// InputHistoryService keeps the lines sent from the input, and any unsent draft, for each window.  Windows are
+// identified by a key that stays the same across restarts so the history can be saved to path, lines that contain
+// credentials and drafts are only kept in memory.
 type InputHistoryService struct {
-	inputHistory    []string
-	historyPosition int
-	historyLock     sync.RWMutex
+	histories   map[string]*inputHistory
+	drafts      map[string]string
+	global      bool
+	size        int
+	path        string
+	historyLock sync.RWMutex
+	saveLock    sync.Mutex
 }
 
-func NewInputHistoryService() *InputHistoryService {
-	return &InputHistoryService{
-		inputHistory:    make([]string, 0),
-		historyPosition: -1,
+func NewInputHistoryService(path string, settings config.InputHistory) *InputHistoryService {
+	ihs := &InputHistoryService{
+		histories: make(map[string]*inputHistory),
+		drafts:    make(map[string]string),
+		global:    settings.Global,
+		size:      settings.Size,
+	}
+	if !settings.NoSave {
+		ihs.path = path
 	}
+	ihs.load()
+	return ihs
 }
 
-func (ihs *InputHistoryService) AddToHistory(input string) {
-	ihs.historyLock.Lock()
-	defer ihs.historyLock.Unlock()
-
-	if input == "" || (len(ihs.inputHistory) > 0 && ihs.inputHistory[len(ihs.inputHistory)-1] == input) {
+func (ihs *InputHistoryService) load() {
+	if ihs.path == "" {
 		return
 	}
-
-	ihs.inputHistory = append(ihs.inputHistory, input)
-	ihs.historyPosition = -1
+	data, err := os.ReadFile(ihs.path)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			slog.Error("Unable to read input history", "error", err)
+		}
+		return
+	}
+	var saved map[string][]string
+	if err = json.Unmarshal(data, &saved); err != nil {
+		slog.Error("Unable to parse input history", "error", err)
+		return
+	}
+	for key, lines := range saved {
+		history := ihs.getHistory(key)
+		for _, line := range lines {
+			ihs.append(history, line)
+		}
+	}
 }
 
-func (ihs *InputHistoryService) GetHistoryItem(position int) string {
-	ihs.historyLock.Lock()
-	defer ihs.historyLock.Unlock()
+func (ihs *InputHistoryService) save() {
+	if ihs.path == "" {
+		return
+	}
+	ihs.historyLock.RLock()
+	saved := make(map[string][]string, len(ihs.histories))
+	for key, history := range ihs.histories {
+		lines := slices.DeleteFunc(slices.Clone(history.lines), isSensitiveInput)
+		if len(lines) > 0 {
+			saved[key] = lines
+		}
+	}
+	data, err := json.Marshal(saved)
+	ihs.historyLock.RUnlock()
+	if err != nil {
+		slog.Error("Unable to save input history", "error", err)
+		return
+	}
+	ihs.saveLock.Lock()
+	defer ihs.saveLock.Unlock()
+	if err = ihs.write(data); err != nil {
+		slog.Error("Unable to save input history", "error", err)
+	}
+}
 
-	if position < 0 || position >= len(ihs.inputHistory) {
-		return ""
+// write replaces the history file, writing to a temporary file first so a failure doesn't lose the existing history
+func (ihs *InputHistoryService) write(data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(ihs.path), 0700); err != nil {
+		return err
+	}
+	file, err := os.CreateTemp(filepath.Dir(ihs.path), ".input_history-*")
+	if err != nil {
+		return err
+	}
+	defer func() { _ = os.Remove(file.Name()) }()
+	if _, err = file.Write(data); err != nil {
+		_ = file.Close()
+		return err
+	}
+	if err = file.Close(); err != nil {
+		return err
 	}
+	return os.Rename(file.Name(), ihs.path)
+}
 
-	reverseIndex := len(ihs.inputHistory) - 1 - position
-	if reverseIndex < 0 || reverseIndex >= len(ihs.inputHistory) {
-		return ""
+// isSensitiveInput checks if a line might contain a password, so it shouldn't be written to disk: /oper, raw PASS,
+// OPER and AUTHENTICATE commands, anything sent to NickServ, identify commands, channel keys and servers added with a
+// password or a proxy that has one
+func isSensitiveInput(input string) bool {
+	fields := strings.Fields(strings.ToLower(input))
+	if len(fields) == 0 {
+		return false
+	}
+	// Raw lines put the message after a colon, eg PRIVMSG NickServ :IDENTIFY
+	for i := range fields {
+		fields[i] = strings.TrimPrefix(fields[i], ":")
+	}
+	if slices.Contains(fields, "identify") {
+		return true
+	}
+	command := strings.TrimPrefix(fields[0], "/")
+	if !strings.HasPrefix(fields[0], "/") {
+		return false
 	}
+	switch command {
+	case "oper", "pass", "ns", "nickserv", "identify":
+		return true
+	case "join":
+		return len(fields) > 2
+	case "quote", "raw":
+		if len(fields) < 2 {
+			return false
+		}
+		switch fields[1] {
+		case "pass", "oper", "authenticate", "nickserv", "ns":
+			return true
+		case "privmsg", "notice":
+			return slices.ContainsFunc(fields[2:], func(field string) bool {
+				return strings.Contains(field, "nickserv")
+			})
+		case "join":
+			return len(fields) > 3
+		}
+	case "msg", "query", "notice":
+		return len(fields) > 1 && strings.HasPrefix(fields[1], "nickserv")
+	case "addserver":
+		return slices.ContainsFunc(fields, func(field string) bool {
+			return strings.HasPrefix(field, "--password=") || strings.HasPrefix(field, "--sasl=") ||
+				hasProxyPassword(field)
+		})
+	}
+	return false
+}
 
-	return ihs.inputHistory[reverseIndex]
+// hasProxyPassword checks if an --proxy= argument has a username or password in the URL
+func hasProxyPassword(field string) bool {
+	proxy, ok := strings.CutPrefix(field, "--proxy=")
+	if !ok {
+		return false
+	}
+	proxyURL, err := url.Parse(proxy)
+	return err != nil || proxyURL.User != nil
 }
 
-func (ihs *InputHistoryService) GetHistoryLength() int {
-	ihs.historyLock.RLock()
-	defer ihs.historyLock.RUnlock()
-	return len(ihs.inputHistory)
+// getHistory returns the history used by the window with the given key, must be called with the lock held
+func (ihs *InputHistoryService) getHistory(key string) *inputHistory {
+	if ihs.global {
+		key = globalHistory
+	}
+	history, ok := ihs.histories[key]
+	if !ok {
+		history = &inputHistory{position: -1}
+		ihs.histories[key] = history
+	}
+	return history
 }
 
-func (ihs *InputHistoryService) ResetPosition() {
-	ihs.historyLock.RLock()
-	defer ihs.historyLock.RUnlock()
-	ihs.historyPosition = -1
+// append adds a line to the history, removing the oldest lines when it's full
+func (ihs *InputHistoryService) append(history *inputHistory, input string) {
+	history.lines = append(history.lines, input)
+	if len(history.lines) > ihs.size {
+		history.lines = history.lines[len(history.lines)-ihs.size:]
+	}
 }
 
-func (ihs *InputHistoryService) GetCurrentPosition() int {
-	ihs.historyLock.RLock()
-	defer ihs.historyLock.RUnlock()
-	return ihs.historyPosition
+func (ihs *InputHistoryService) AddToHistory(key string, input string) {
+	ihs.historyLock.Lock()
+	history := ihs.getHistory(key)
+	if input == "" || (len(history.lines) > 0 && history.lines[len(history.lines)-1] == input) {
+		ihs.historyLock.Unlock()
+		return
+	}
+	ihs.append(history, input)
+	history.position = -1
+	history.pending = ""
+	ihs.historyLock.Unlock()
+	ihs.save()
 }
 
-func (ihs *InputHistoryService) NavigateUp(currentInput string) string {
+func (ihs *InputHistoryService) GetHistoryItem(key string, position int) string {
 	ihs.historyLock.Lock()
 	defer ihs.historyLock.Unlock()
 
-	if len(ihs.inputHistory) == 0 {
+	history := ihs.getHistory(key)
+	if position < 0 || position >= len(history.lines) {
 		return ""
 	}
+	return history.lines[len(history.lines)-1-position]
+}
+
+func (ihs *InputHistoryService) GetHistoryLength(key string) int {
+	ihs.historyLock.Lock()
+	defer ihs.historyLock.Unlock()
+	return len(ihs.getHistory(key).lines)
+}
+
+func (ihs *InputHistoryService) ResetPosition(key string) {
+	ihs.historyLock.Lock()
+	defer ihs.historyLock.Unlock()
+	ihs.getHistory(key).position = -1
+}
+
+func (ihs *InputHistoryService) GetCurrentPosition(key string) int {
+	ihs.historyLock.Lock()
+	defer ihs.historyLock.Unlock()
+	return ihs.getHistory(key).position
+}
 
-	if currentInput != "" && (len(ihs.inputHistory) == 0 || ihs.inputHistory[len(ihs.inputHistory)-1] != currentInput) {
-		ihs.inputHistory = append(ihs.inputHistory, currentInput)
-		ihs.historyPosition = len(ihs.inputHistory) - 1
-		return currentInput
+// NavigateUp returns the previous line in the history, currentInput is kept so it can be restored by NavigateDown
+func (ihs *InputHistoryService) NavigateUp(key string, currentInput string) string {
+	ihs.historyLock.Lock()
+	defer ihs.historyLock.Unlock()
+
+	history := ihs.getHistory(key)
+	if len(history.lines) == 0 {
+		return ""
 	}
 
-	if ihs.historyPosition == -1 {
-		ihs.historyPosition = len(ihs.inputHistory) - 1
-	} else if ihs.historyPosition > 0 {
-		ihs.historyPosition--
+	if history.position == -1 {
+		history.pending = currentInput
+		history.position = len(history.lines) - 1
+	} else if history.position > 0 {
+		history.position--
 	}
 
-	if ihs.historyPosition >= 0 && ihs.historyPosition < len(ihs.inputHistory) {
-		return ihs.inputHistory[ihs.historyPosition]
+	if history.position >= 0 && history.position < len(history.lines) {
+		return history.lines[history.position]
 	}
 	return ""
 }
 
-func (ihs *InputHistoryService) NavigateDown() string {
+func (ihs *InputHistoryService) NavigateDown(key string) string {
 	ihs.historyLock.Lock()
 	defer ihs.historyLock.Unlock()
 
-	if len(ihs.inputHistory) == 0 || ihs.historyPosition == -1 {
+	history := ihs.getHistory(key)
+	if len(history.lines) == 0 || history.position == -1 {
 		return ""
 	}
 
-	if ihs.historyPosition < len(ihs.inputHistory)-1 {
-		ihs.historyPosition++
-		if ihs.historyPosition < len(ihs.inputHistory) {
-			return ihs.inputHistory[ihs.historyPosition]
-		}
+	if history.position < len(history.lines)-1 {
+		history.position++
+		return history.lines[history.position]
+	}
+	history.position = -1
+	pending := history.pending
+	history.pending = ""
+	return pending
+}
+
+// SetDraft stores the unsent input for a window, drafts are always kept for each window even when the history is
+// global
+func (ihs *InputHistoryService) SetDraft(key string, input string) {
+	ihs.historyLock.Lock()
+	defer ihs.historyLock.Unlock()
+	if input == "" {
+		delete(ihs.drafts, key)
 	} else {
-		ihs.historyPosition = -1
-		return ""
+		ihs.drafts[key] = input
 	}
+}
 
-	return ""
+// GetDraft returns the unsent input for a window
+func (ihs *InputHistoryService) GetDraft(key string) string {
+	ihs.historyLock.RLock()
+	defer ihs.historyLock.RUnlock()
+	return ihs.drafts[key]
 }
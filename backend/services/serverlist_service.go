@@ -94,5 +94,15 @@ func (sls *ServerListService) GetServerList(connectionManager *irc.ServerManager
 		}
 	}
 
+	if mentions := connectionManager.GetMentions(); mentions != nil {
+		item := &ServerListItem{
+			Window: mentions,
+			Link:   irc.MentionsID,
+			Name:   mentions.GetName(),
+		}
+		serverList.Parents = append(serverList.Parents, item)
+		serverList.OrderedList = append(serverList.OrderedList, item)
+	}
+
 	return serverList
 }
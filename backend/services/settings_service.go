@@ -14,7 +14,9 @@ type SettingsData struct {
 	ShowNicklist    bool
 	Servers         []config.Server
 	Notifications   []config.NotificationTrigger
+	Highlights      []config.HighlightRule
 	Theme           string
+	Proxy           string
 }
 
 // SettingsService manages UI settings and configuration
@@ -32,7 +34,9 @@ func NewSettingsService(conf *config.Config) *SettingsService {
 			ShowNicklist:    conf.UISettings.ShowNicklist,
 			Servers:         conf.Servers,
 			Notifications:   conf.Notifications.Triggers,
+			Highlights:      conf.Highlights,
 			Theme:           conf.UISettings.Theme,
+			Proxy:           conf.Connection.Proxy,
 		},
 	}
 }
@@ -44,10 +48,13 @@ func (ss *SettingsService) GetFromConfig() *SettingsData {
 		ShowNicklist:    ss.conf.UISettings.ShowNicklist,
 		Servers:         make([]config.Server, len(ss.conf.Servers)),
 		Notifications:   make([]config.NotificationTrigger, len(ss.conf.Notifications.Triggers)),
+		Highlights:      make([]config.HighlightRule, len(ss.conf.Highlights)),
 		Theme:           ss.conf.UISettings.Theme,
+		Proxy:           ss.conf.Connection.Proxy,
 	}
 	copy(ss.settingsData.Servers, ss.conf.Servers)
 	copy(ss.settingsData.Notifications, ss.conf.Notifications.Triggers)
+	copy(ss.settingsData.Highlights, ss.conf.Highlights)
 	return ss.settingsData
 }
 
@@ -59,8 +66,11 @@ func (ss *SettingsService) SaveSettingsToConfig() error {
 	ss.conf.UISettings.TimestampFormat = ss.settingsData.TimestampFormat
 	ss.conf.UISettings.ShowNicklist = ss.settingsData.ShowNicklist
 	ss.conf.UISettings.Theme = ss.settingsData.Theme
+	ss.conf.Connection.Proxy = ss.settingsData.Proxy
 	ss.conf.Notifications.Triggers = make([]config.NotificationTrigger, len(ss.settingsData.Notifications))
 	copy(ss.conf.Notifications.Triggers, ss.settingsData.Notifications)
+	ss.conf.Highlights = make([]config.HighlightRule, len(ss.settingsData.Highlights))
+	copy(ss.conf.Highlights, ss.settingsData.Highlights)
 	ss.conf.Servers = make([]config.Server, len(ss.settingsData.Servers))
 	copy(ss.conf.Servers, ss.settingsData.Servers)
 	return ss.conf.Save()
@@ -151,6 +151,7 @@ func TestSettingsService_SaveSettingsToConfig(t *testing.T) {
 		Popup:   false,
 	}
 	settingsData.Notifications = append(settingsData.Notifications, newTrigger)
+	settingsData.Highlights = append(settingsData.Highlights, config.HighlightRule{Pattern: "tithon", WholeWord: true})
 
 	err := service.SaveSettingsToConfig()
 	assert.NoError(t, err)
@@ -170,6 +171,7 @@ func TestSettingsService_SaveSettingsToConfig(t *testing.T) {
 	assert.Equal(t, "new", mockConfig.Notifications.Triggers[1].Message)
 	assert.True(t, mockConfig.Notifications.Triggers[1].Sound)
 	assert.False(t, mockConfig.Notifications.Triggers[1].Popup)
+	assert.Equal(t, []config.HighlightRule{{Pattern: "tithon", WholeWord: true}}, mockConfig.Highlights)
 
 	assert.True(t, provider.saveCalled)
 }
@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
 	uniqueid "github.com/albinj12/unique-id"
 	"github.com/greboid/tithon/config"
@@ -24,6 +25,7 @@ import (
 
 func (s *WebClient) addRoutes(mux *http.ServeMux) {
 	static, usercss := s.setupFsAndGetWatchers()
+	s.static = static
 	mux.HandleFunc("GET /static/", s.handleStatic(static))
 	mux.HandleFunc("GET /static/user.css", s.handleUserCSS(usercss))
 	mux.HandleFunc("GET /{$}", s.handleIndex)
@@ -36,9 +38,13 @@ func (s *WebClient) addRoutes(mux *http.ServeMux) {
 	mux.HandleFunc("GET /showEditServer", s.handleShowEditServer)
 	mux.HandleFunc("GET /editServer", s.handleEditServer)
 	mux.HandleFunc("GET /connectServer", s.handleConnectServer)
+	mux.HandleFunc("GET /addHighlight", s.handleAddHighlight)
+	mux.HandleFunc("GET /deleteHighlight", s.handleDeleteHighlight)
 	mux.HandleFunc("GET /cancelEditServer", s.handleDefaultSettings)
+	mux.HandleFunc("GET /changeWindow/mentions", s.handleChangeMentions)
 	mux.HandleFunc("GET /changeWindow/{server}", s.handleChangeServer)
 	mux.HandleFunc("GET /changeWindow/{server}/{channel}", s.handleChangeChannel)
+	mux.HandleFunc("GET /s/mentions", s.handleMentions)
 	mux.HandleFunc("GET /s/{server}", s.handleServer)
 	mux.HandleFunc("GET /s/{server}/{channel}", s.handleChannel)
 	mux.HandleFunc("GET /input", s.handleInput)
@@ -52,6 +58,29 @@ func (s *WebClient) addRoutes(mux *http.ServeMux) {
 	mux.HandleFunc("GET /historyUp", s.handleHistoryUp)
 	mux.HandleFunc("GET /historyDown", s.handleHistoryDown)
 	mux.HandleFunc("GET /notificationClick", s.handleNotificationClick)
+	mux.HandleFunc("GET /showSearch", s.handleShowSearch)
+	mux.HandleFunc("GET /search", s.handleSearch)
+	mux.HandleFunc("GET /export", s.handleExport)
+	mux.HandleFunc("GET /previewImage", s.handlePreviewImage)
+	mux.HandleFunc("GET /nick/{server}", s.handleNick)
+	mux.HandleFunc("GET /nick/{server}/query", s.handleNickQuery)
+	mux.HandleFunc("GET /nick/{server}/whois", s.handleNickWhois)
+	mux.HandleFunc("GET /channel/{server}", s.handleChannelLink)
+	mux.HandleFunc("GET /showChannelSettings", s.handleShowChannelSettings)
+	mux.HandleFunc("GET /saveChannelSettings", s.handleSaveChannelSettings)
+	mux.HandleFunc("GET /showWindowSettings", s.handleShowWindowSettings)
+	mux.HandleFunc("GET /saveWindowSettings", s.handleSaveWindowSettings)
+	mux.HandleFunc("GET /showChannelList", s.handleShowChannelList)
+	mux.HandleFunc("GET /channelList", s.handleChannelList)
+	mux.HandleFunc("GET /fetchChannelList", s.handleFetchChannelList)
+	mux.HandleFunc("GET /joinListedChannel", s.handleJoinListedChannel)
+	mux.HandleFunc("GET /closeChannelList", s.handleCloseChannelList)
+	mux.HandleFunc("GET /showLists", s.handleShowLists)
+	mux.HandleFunc("GET /lists", s.handleLists)
+	mux.HandleFunc("GET /refreshList", s.handleRefreshList)
+	mux.HandleFunc("GET /removeListEntry", s.handleRemoveListEntry)
+	mux.HandleFunc("GET /cleanupList", s.handleCleanupList)
+	mux.HandleFunc("GET /closeLists", s.handleCloseLists)
 }
 
 func (s *WebClient) handleIndex(w http.ResponseWriter, _ *http.Request) {
@@ -106,6 +135,8 @@ func (s *WebClient) handleUpdate(w http.ResponseWriter, r *http.Request) {
 			}
 		case <-s.showSettings:
 			s.handleShowSettings(w, r)
+		case serverID := <-s.showChannelList:
+			s.openChannelList(w, r, serverID)
 		case notification := <-s.notificationService.GetNotificationChannel():
 			slog.Debug("Sending notification", "notification", notification)
 			err := datastar.NewSSE(w, r).ExecuteScript(
@@ -146,6 +177,657 @@ func (s *WebClient) handleShowSettings(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+func (s *WebClient) handleShowSearch(w http.ResponseWriter, r *http.Request) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	sse := datastar.NewSSE(w, r)
+	slog.Debug("Showing search")
+	var data bytes.Buffer
+
+	err := s.templates.ExecuteTemplate(&data, "SearchPage.gohtml", nil)
+	if err != nil {
+		slog.Debug("Error generating template", "error", err)
+	}
+	err = s.templates.ExecuteTemplate(&data, "SearchContent.gohtml", SearchData{})
+	if err != nil {
+		slog.Debug("Error generating template", "error", err)
+	}
+	err = sse.MergeFragments(data.String())
+	if err != nil {
+		slog.Debug("Error merging fragments", "error", err)
+		return
+	}
+}
+
+func (s *WebClient) handleSearch(w http.ResponseWriter, r *http.Request) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	sse := datastar.NewSSE(w, r)
+	slog.Debug("Searching messages")
+	var data bytes.Buffer
+
+	search := SearchData{
+		Query: irc.SearchQuery{
+			Text:    r.URL.Query().Get("text"),
+			Regex:   r.URL.Query().Get("regex") == "on",
+			Nick:    r.URL.Query().Get("nick"),
+			Network: r.URL.Query().Get("network"),
+			Window:  r.URL.Query().Get("window"),
+			Limit:   searchLimit,
+		},
+		From:     r.URL.Query().Get("from"),
+		To:       r.URL.Query().Get("to"),
+		Searched: true,
+	}
+	var err error
+	if search.Query.From, err = parseSearchDate(search.From); err != nil {
+		search.Error = "Invalid from date"
+	} else if search.Query.To, err = parseSearchDate(search.To); err != nil {
+		search.Error = "Invalid to date"
+	} else {
+		if !search.Query.To.IsZero() {
+			// The end date is inclusive
+			search.Query.To = search.Query.To.AddDate(0, 0, 1)
+		}
+		search.Results, err = s.connectionManager.Search(search.Query)
+		if err != nil {
+			search.Error = err.Error()
+		}
+	}
+
+	err = s.templates.ExecuteTemplate(&data, "SearchContent.gohtml", search)
+	if err != nil {
+		slog.Debug("Error generating template", "error", err)
+	}
+	err = sse.MergeFragments(data.String())
+	if err != nil {
+		slog.Debug("Error merging fragments", "error", err)
+		return
+	}
+}
+
+func (s *WebClient) handleExport(w http.ResponseWriter, r *http.Request) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	window := s.getActiveWindow()
+	if window == nil {
+		http.Error(w, "No active window", http.StatusNotFound)
+		return
+	}
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = irc.ExportHTML
+	}
+	now := time.Now()
+	exportRange, err := irc.ParseExportRange(r.URL.Query().Get("range"), now)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	data, err := irc.Export(format, window, irc.ExportMessages(window, exportRange), s)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	switch format {
+	case irc.ExportHTML:
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	case irc.ExportJSON:
+		w.Header().Set("Content-Type", "application/json")
+	default:
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	}
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, irc.ExportFilename(window, format, now)))
+	if _, err = w.Write(data); err != nil {
+		slog.Debug("Error writing export", "error", err)
+	}
+}
+
+// handlePreviewImage serves the image for a link preview, so the browser never connects to the image's host.  It
+// doesn't take the lock as fetching the image can be slow.
+func (s *WebClient) handlePreviewImage(w http.ResponseWriter, r *http.Request) {
+	contentType, data, err := s.connectionManager.GetLinkPreviewer().Image(r.URL.Query().Get("link"))
+	if err != nil {
+		slog.Debug("Unable to serve preview image", "error", err)
+		http.Error(w, "Preview image not available", http.StatusNotFound)
+		return
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Security-Policy", "default-src 'none'")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.Header().Set("Cache-Control", "private, max-age=86400")
+	if _, err = w.Write(data); err != nil {
+		slog.Debug("Error writing preview image", "error", err)
+	}
+}
+
+// getLinkTarget returns the server and nickname or channel name from a clicked link in a message
+func (s *WebClient) getLinkTarget(r *http.Request, param string) (*irc.Server, string) {
+	connection := s.connectionManager.GetConnection(r.PathValue("server"))
+	target := r.URL.Query().Get(param)
+	if connection == nil || target == "" || strings.ContainsAny(target, " \r\n") {
+		slog.Debug("Invalid link target", "server", r.PathValue("server"), param, target)
+		return nil, ""
+	}
+	return connection, target
+}
+
+func (s *WebClient) handleNick(w http.ResponseWriter, r *http.Request) {
+	connection, nick := s.getLinkTarget(r, "nick")
+	if connection == nil {
+		return
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	sse := datastar.NewSSE(w, r)
+	var data bytes.Buffer
+	err := s.templates.ExecuteTemplate(&data, "NickPage.gohtml", NickData{Server: connection.GetID(), Nick: nick})
+	if err != nil {
+		slog.Debug("Error generating template", "error", err)
+	}
+	err = sse.MergeFragments(data.String())
+	if err != nil {
+		slog.Debug("Error merging fragments", "error", err)
+		return
+	}
+}
+
+func (s *WebClient) handleNickQuery(w http.ResponseWriter, r *http.Request) {
+	connection, nick := s.getLinkTarget(r, "nick")
+	if connection == nil {
+		return
+	}
+	query, err := connection.GetQueryByName(nick)
+	if err != nil {
+		query = connection.AddQuery(nick)
+	}
+	s.switchWindow(w, r, query.Window)
+	s.closeDialog(w, r)
+	s.updateURL(w, r)
+	s.UpdateUI(w, r)
+}
+
+func (s *WebClient) handleNickWhois(w http.ResponseWriter, r *http.Request) {
+	connection, nick := s.getLinkTarget(r, "nick")
+	if connection == nil {
+		return
+	}
+	connection.SendRaw("whois :" + nick)
+	s.closeDialog(w, r)
+}
+
+func (s *WebClient) handleChannelLink(w http.ResponseWriter, r *http.Request) {
+	connection, name := s.getLinkTarget(r, "channel")
+	if connection == nil {
+		return
+	}
+	channel, err := connection.GetChannelByName(name)
+	if err != nil {
+		if err = connection.JoinChannel(name, ""); err != nil {
+			slog.Debug("Error joining channel", "error", err)
+		}
+		return
+	}
+	s.switchWindow(w, r, channel.Window)
+	s.updateURL(w, r)
+	s.UpdateUI(w, r)
+}
+
+// getChannelFromQuery returns the server and channel named in the request's query
+func (s *WebClient) getChannelFromQuery(r *http.Request) (*irc.Server, *irc.Channel, error) {
+	connection := s.connectionManager.GetConnection(r.URL.Query().Get("server"))
+	if connection == nil {
+		return nil, nil, irc.ErrNoServer
+	}
+	channel, err := connection.GetChannelByName(r.URL.Query().Get("channel"))
+	if err != nil {
+		return nil, nil, err
+	}
+	return connection, channel, nil
+}
+
+func (s *WebClient) handleShowChannelSettings(w http.ResponseWriter, r *http.Request) {
+	connection, channel, err := s.getChannelFromQuery(r)
+	if err != nil {
+		slog.Debug("Invalid channel for settings", "error", err)
+		return
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	sse := datastar.NewSSE(w, r)
+	var data bytes.Buffer
+	err = s.templates.ExecuteTemplate(&data, "ChannelSettingsPage.gohtml", nil)
+	if err != nil {
+		slog.Debug("Error generating template", "error", err)
+	}
+	err = s.templates.ExecuteTemplate(&data, "ChannelSettingsContent.gohtml", getChannelSettingsData(connection, channel))
+	if err != nil {
+		slog.Debug("Error generating template", "error", err)
+	}
+	err = sse.MergeFragments(data.String())
+	if err != nil {
+		slog.Debug("Error merging fragments", "error", err)
+		return
+	}
+}
+
+func (s *WebClient) handleSaveChannelSettings(w http.ResponseWriter, r *http.Request) {
+	connection, channel, err := s.getChannelFromQuery(r)
+	if err != nil {
+		slog.Debug("Invalid channel for settings", "error", err)
+		return
+	}
+	current := connection.GetChannelSettings(channel)
+	wanted := make([]irc.ChannelSetting, len(current))
+	for i := range current {
+		wanted[i] = current[i]
+		value := r.URL.Query().Get("mode" + current[i].Mode)
+		if current[i].HasParameter() {
+			wanted[i].Parameter = strings.TrimSpace(value)
+			wanted[i].Set = wanted[i].Parameter != ""
+			if strings.ContainsAny(wanted[i].Parameter, " \r\n") {
+				err = fmt.Errorf("%s can't contain spaces", current[i].Name)
+			}
+		} else {
+			wanted[i].Set = value == "on"
+		}
+	}
+	topic := r.URL.Query().Get("topic")
+	originalTopic := r.URL.Query().Get("originalTopic")
+	if err == nil && topic != originalTopic {
+		if strings.ContainsAny(topic, "\r\n") {
+			err = errors.New("topic can't contain new lines")
+		} else {
+			err = connection.SendTopic(channel.GetName(), topic)
+		}
+	}
+	if err == nil {
+		err = connection.SendChannelSettings(channel.GetName(), current, wanted)
+	}
+	if err == nil {
+		s.closeDialog(w, r)
+		return
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	sse := datastar.NewSSE(w, r)
+	settings := getChannelSettingsData(connection, channel)
+	settings.Topic = topic
+	settings.OriginalTopic = originalTopic
+	settings.Settings = wanted
+	settings.Error = err.Error()
+	var data bytes.Buffer
+	err = s.templates.ExecuteTemplate(&data, "ChannelSettingsContent.gohtml", settings)
+	if err != nil {
+		slog.Debug("Error generating template", "error", err)
+	}
+	err = sse.MergeFragments(data.String())
+	if err != nil {
+		slog.Debug("Error merging fragments", "error", err)
+		return
+	}
+}
+
+// getWindowFromQuery returns the server and the channel or query named in the request's query
+func (s *WebClient) getWindowFromQuery(r *http.Request) (*irc.Server, string, error) {
+	connection := s.connectionManager.GetConnection(r.URL.Query().Get("server"))
+	if connection == nil {
+		return nil, "", irc.ErrNoServer
+	}
+	name := r.URL.Query().Get("window")
+	if channel, err := connection.GetChannelByName(name); err == nil {
+		return connection, channel.GetName(), nil
+	}
+	query, err := connection.GetQueryByName(name)
+	if err != nil {
+		return nil, "", err
+	}
+	return connection, query.GetName(), nil
+}
+
+func (s *WebClient) handleShowWindowSettings(w http.ResponseWriter, r *http.Request) {
+	connection, name, err := s.getWindowFromQuery(r)
+	if err != nil {
+		slog.Debug("Invalid window for settings", "error", err)
+		return
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	sse := datastar.NewSSE(w, r)
+	var data bytes.Buffer
+	err = s.templates.ExecuteTemplate(&data, "WindowSettingsPage.gohtml", nil)
+	if err != nil {
+		slog.Debug("Error generating template", "error", err)
+	}
+	err = s.templates.ExecuteTemplate(&data, "WindowSettingsContent.gohtml", getWindowSettingsData(connection.GetID(), connection.GetWindowSettings(name)))
+	if err != nil {
+		slog.Debug("Error generating template", "error", err)
+	}
+	err = sse.MergeFragments(data.String())
+	if err != nil {
+		slog.Debug("Error merging fragments", "error", err)
+		return
+	}
+}
+
+func (s *WebClient) handleSaveWindowSettings(w http.ResponseWriter, r *http.Request) {
+	connection, name, err := s.getWindowFromQuery(r)
+	if err != nil {
+		slog.Debug("Invalid window for settings", "error", err)
+		return
+	}
+	settings := config.WindowSettings{
+		Window:     name,
+		Notify:     r.URL.Query().Get("notify"),
+		Muted:      r.URL.Query().Get("muted") == "on",
+		Membership: r.URL.Query().Get("membership"),
+		NoLog:      r.URL.Query().Get("logging") != "on",
+	}
+	if settings.Notify == config.NotifyAll {
+		settings.Notify = ""
+	}
+	for _, word := range strings.Split(r.URL.Query().Get("highlights"), ",") {
+		if word = strings.TrimSpace(word); word != "" {
+			settings.Highlights = append(settings.Highlights, word)
+		}
+	}
+	if err = connection.UpdateWindowSettings(settings); err == nil {
+		s.closeDialog(w, r)
+		return
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	sse := datastar.NewSSE(w, r)
+	data := getWindowSettingsData(connection.GetID(), settings)
+	data.Notify = r.URL.Query().Get("notify")
+	data.Error = err.Error()
+	var output bytes.Buffer
+	err = s.templates.ExecuteTemplate(&output, "WindowSettingsContent.gohtml", data)
+	if err != nil {
+		slog.Debug("Error generating template", "error", err)
+	}
+	err = sse.MergeFragments(output.String())
+	if err != nil {
+		slog.Debug("Error merging fragments", "error", err)
+		return
+	}
+}
+
+func (s *WebClient) handleShowChannelList(w http.ResponseWriter, r *http.Request) {
+	connection := s.connectionManager.GetConnection(r.URL.Query().Get("server"))
+	if connection == nil {
+		slog.Debug("Invalid server for channel list", "server", r.URL.Query().Get("server"))
+		return
+	}
+	list := connection.GetChannelList()
+	if _, total := list.Search("", irc.ChannelListSortUsers, 0); total == 0 && !list.IsLoading() {
+		if err := connection.RequestChannelList(""); err != nil {
+			slog.Debug("Error requesting channel list", "error", err)
+			return
+		}
+	}
+	s.openChannelList(w, r, connection.GetID())
+}
+
+// openChannelList shows the channel list dialog for a server
+func (s *WebClient) openChannelList(w http.ResponseWriter, r *http.Request, serverID string) {
+	connection := s.connectionManager.GetConnection(serverID)
+	if connection == nil {
+		return
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	slog.Debug("Showing channel list", "server", connection.GetName())
+	s.channelListDialog = &ChannelListDialog{ServerID: serverID, Sort: irc.ChannelListSortUsers}
+	sse := datastar.NewSSE(w, r)
+	var data bytes.Buffer
+	err := s.templates.ExecuteTemplate(&data, "ChannelListPage.gohtml", ChannelListPageData{
+		Server: connection.GetName(),
+		Filter: connection.GetChannelList().GetFilter().String(),
+	})
+	if err != nil {
+		slog.Debug("Error generating template", "error", err)
+	}
+	s.outputChannelListContent(&data, true)
+	err = sse.MergeFragments(data.String())
+	if err != nil {
+		slog.Debug("Error merging fragments", "error", err)
+		return
+	}
+}
+
+func (s *WebClient) handleChannelList(w http.ResponseWriter, r *http.Request) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.channelListDialog == nil {
+		return
+	}
+	if r.URL.Query().Has("search") {
+		s.channelListDialog.Search = strings.TrimSpace(r.URL.Query().Get("search"))
+	}
+	switch sort := r.URL.Query().Get("sort"); sort {
+	case irc.ChannelListSortUsers, irc.ChannelListSortName:
+		s.channelListDialog.Sort = sort
+	}
+	s.mergeChannelListContent(w, r)
+}
+
+func (s *WebClient) handleFetchChannelList(w http.ResponseWriter, r *http.Request) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.channelListDialog == nil {
+		return
+	}
+	connection := s.connectionManager.GetConnection(s.channelListDialog.ServerID)
+	if connection == nil {
+		return
+	}
+	s.channelListDialog.Search = strings.TrimSpace(r.URL.Query().Get("search"))
+	if err := connection.RequestChannelList(r.URL.Query().Get("filter")); err != nil {
+		slog.Debug("Error requesting channel list", "error", err)
+		return
+	}
+	s.mergeChannelListContent(w, r)
+}
+
+func (s *WebClient) handleJoinListedChannel(w http.ResponseWriter, r *http.Request) {
+	s.lock.Lock()
+	dialog := s.channelListDialog
+	// The dialog is replaced rather than closed, so it won't ask for the channel list to be closed itself
+	s.channelListDialog = nil
+	s.lastChannelList = ""
+	s.lock.Unlock()
+	channel := r.URL.Query().Get("channel")
+	if dialog == nil || channel == "" || strings.ContainsAny(channel, " ,\r\n") {
+		return
+	}
+	connection := s.connectionManager.GetConnection(dialog.ServerID)
+	if connection == nil {
+		return
+	}
+	if err := connection.JoinChannel(channel, ""); err != nil {
+		slog.Debug("Error joining channel", "channel", channel, "error", err)
+		return
+	}
+	s.closeDialog(w, r)
+}
+
+func (s *WebClient) handleCloseChannelList(_ http.ResponseWriter, _ *http.Request) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.channelListDialog = nil
+	s.lastChannelList = ""
+}
+
+// mergeChannelListContent re-renders the open channel list
+func (s *WebClient) mergeChannelListContent(w http.ResponseWriter, r *http.Request) {
+	sse := datastar.NewSSE(w, r)
+	var data bytes.Buffer
+	s.outputChannelListContent(&data, true)
+	err := sse.MergeFragments(data.String())
+	if err != nil {
+		slog.Debug("Error merging fragments", "error", err)
+		return
+	}
+}
+
+func (s *WebClient) handleShowLists(w http.ResponseWriter, r *http.Request) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	connection := s.connectionManager.GetConnection(r.URL.Query().Get("server"))
+	if connection == nil {
+		slog.Debug("Invalid server for lists", "server", r.URL.Query().Get("server"))
+		return
+	}
+	channel := r.URL.Query().Get("channel")
+	modes := connection.GetListModes()
+	if len(modes) == 0 {
+		slog.Debug("Server doesn't support any lists", "server", connection.GetName())
+		return
+	}
+	for i := range modes {
+		if err := connection.RequestList(channel, modes[i].Mode); err != nil {
+			slog.Debug("Error requesting list", "channel", channel, "mode", modes[i].Mode, "error", err)
+			return
+		}
+	}
+	slog.Debug("Showing lists", "channel", channel)
+	s.listDialog = &ListDialog{ServerID: connection.GetID(), Channel: channel, Mode: modes[0].Mode}
+	sse := datastar.NewSSE(w, r)
+	var data bytes.Buffer
+	err := s.templates.ExecuteTemplate(&data, "ListPage.gohtml", nil)
+	if err != nil {
+		slog.Debug("Error generating template", "error", err)
+	}
+	s.outputListContent(&data, true)
+	err = sse.MergeFragments(data.String())
+	if err != nil {
+		slog.Debug("Error merging fragments", "error", err)
+		return
+	}
+}
+
+func (s *WebClient) handleLists(w http.ResponseWriter, r *http.Request) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.listDialog == nil {
+		return
+	}
+	mode := r.URL.Query().Get("mode")
+	data, err := s.getListData(s.listDialog)
+	if err != nil || !slices.ContainsFunc(data.Modes, func(listMode irc.ListMode) bool {
+		return listMode.Mode == mode
+	}) {
+		slog.Debug("Invalid list mode", "mode", mode, "error", err)
+		return
+	}
+	s.listDialog.Mode = mode
+	s.mergeListContent(w, r)
+}
+
+func (s *WebClient) handleRefreshList(w http.ResponseWriter, r *http.Request) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	connection := s.getListConnection()
+	if connection == nil {
+		return
+	}
+	if err := connection.RequestList(s.listDialog.Channel, s.listDialog.Mode); err != nil {
+		slog.Debug("Error requesting list", "error", err)
+		return
+	}
+	s.mergeListContent(w, r)
+}
+
+func (s *WebClient) handleRemoveListEntry(w http.ResponseWriter, r *http.Request) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	connection := s.getListConnection()
+	mask := r.URL.Query().Get("mask")
+	if connection == nil || mask == "" || strings.ContainsAny(mask, " \r\n") {
+		return
+	}
+	err := connection.SendChannelModes(s.listDialog.Channel, false, s.listDialog.Mode, []string{mask})
+	if err != nil {
+		slog.Debug("Error removing list entry", "mask", mask, "error", err)
+	}
+}
+
+func (s *WebClient) handleCleanupList(w http.ResponseWriter, r *http.Request) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	connection := s.getListConnection()
+	if connection == nil {
+		return
+	}
+	days, err := strconv.Atoi(r.URL.Query().Get("days"))
+	if err != nil || days < 1 {
+		slog.Debug("Invalid number of days", "days", r.URL.Query().Get("days"))
+		return
+	}
+	channel, err := connection.GetChannelByName(s.listDialog.Channel)
+	if err != nil {
+		return
+	}
+	masks := channel.GetListEntriesBefore(s.listDialog.Mode, time.Now().AddDate(0, 0, -days))
+	slog.Debug("Cleaning up list", "channel", s.listDialog.Channel, "mode", s.listDialog.Mode, "count", len(masks))
+	err = connection.SendChannelModes(s.listDialog.Channel, false, s.listDialog.Mode, masks)
+	if err != nil {
+		slog.Debug("Error cleaning up list", "error", err)
+	}
+}
+
+func (s *WebClient) handleCloseLists(_ http.ResponseWriter, _ *http.Request) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.listDialog = nil
+	s.lastListContent = ""
+}
+
+// getListConnection returns the server for the open lists dialog, or nil if there isn't one
+func (s *WebClient) getListConnection() *irc.Server {
+	if s.listDialog == nil {
+		return nil
+	}
+	return s.connectionManager.GetConnection(s.listDialog.ServerID)
+}
+
+// mergeListContent re-renders the open lists dialog
+func (s *WebClient) mergeListContent(w http.ResponseWriter, r *http.Request) {
+	sse := datastar.NewSSE(w, r)
+	var data bytes.Buffer
+	s.outputListContent(&data, true)
+	err := sse.MergeFragments(data.String())
+	if err != nil {
+		slog.Debug("Error merging fragments", "error", err)
+		return
+	}
+}
+
+// closeDialog replaces the open dialog with an empty one
+func (s *WebClient) closeDialog(w http.ResponseWriter, r *http.Request) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	sse := datastar.NewSSE(w, r)
+	var data bytes.Buffer
+	err := s.templates.ExecuteTemplate(&data, "EmptyDialog.gohtml", nil)
+	if err != nil {
+		slog.Debug("Error generating template", "error", err)
+	}
+	err = sse.MergeFragments(data.String())
+	if err != nil {
+		slog.Debug("Error merging fragments", "error", err)
+		return
+	}
+}
+
+func parseSearchDate(date string) (time.Time, error) {
+	if date == "" {
+		return time.Time{}, nil
+	}
+	return time.ParseInLocation(time.DateOnly, date, time.Local)
+}
+
 func (s *WebClient) handleDefaultSettings(w http.ResponseWriter, r *http.Request) {
 	s.lock.Lock()
 	defer s.lock.Unlock()
@@ -188,6 +870,64 @@ func (s *WebClient) handleDeleteServer(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+func (s *WebClient) handleAddHighlight(w http.ResponseWriter, r *http.Request) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	sse := datastar.NewSSE(w, r)
+	slog.Debug("Adding highlight rule")
+	var data bytes.Buffer
+
+	rule := config.HighlightRule{
+		Pattern:       r.URL.Query().Get("highlightPattern"),
+		Regex:         r.URL.Query().Get("highlightRegex") == "on",
+		WholeWord:     r.URL.Query().Get("highlightWholeWord") == "on",
+		CaseSensitive: r.URL.Query().Get("highlightCaseSensitive") == "on",
+		Network:       r.URL.Query().Get("highlightNetwork"),
+		Channel:       r.URL.Query().Get("highlightChannel"),
+		ExcludeNick:   r.URL.Query().Get("highlightExcludeNick") == "on",
+	}
+	if err := config.ValidateHighlightRule(rule); err != nil {
+		slog.Error("Invalid highlight rule", "error", err)
+	} else {
+		settingsData := s.settingsService.GetSettingsData()
+		settingsData.Highlights = append(settingsData.Highlights, rule)
+	}
+
+	err := s.templates.ExecuteTemplate(&data, "SettingsContent.gohtml", s.settingsService.GetSettingsData())
+	if err != nil {
+		slog.Debug("Error generating template", "error", err)
+	}
+	err = sse.MergeFragments(data.String())
+	if err != nil {
+		slog.Debug("Error merging fragments", "error", err)
+		return
+	}
+}
+
+func (s *WebClient) handleDeleteHighlight(w http.ResponseWriter, r *http.Request) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	sse := datastar.NewSSE(w, r)
+	slog.Debug("Deleting highlight rule")
+	var data bytes.Buffer
+
+	settingsData := s.settingsService.GetSettingsData()
+	index, err := strconv.Atoi(r.URL.Query().Get("index"))
+	if err == nil && index >= 0 && index < len(settingsData.Highlights) {
+		settingsData.Highlights = slices.Delete(settingsData.Highlights, index, index+1)
+	}
+
+	err = s.templates.ExecuteTemplate(&data, "SettingsContent.gohtml", s.settingsService.GetSettingsData())
+	if err != nil {
+		slog.Debug("Error generating template", "error", err)
+	}
+	err = sse.MergeFragments(data.String())
+	if err != nil {
+		slog.Debug("Error merging fragments", "error", err)
+		return
+	}
+}
+
 func (s *WebClient) handleConnectServer(w http.ResponseWriter, r *http.Request) {
 	s.lock.Lock()
 	defer s.lock.Unlock()
@@ -208,12 +948,12 @@ func (s *WebClient) handleConnectServer(w http.ResponseWriter, r *http.Request)
 
 	s.connectionManager.AddConnection(
 		settingsData.Servers[index].ID,
-		settingsData.Servers[index].Hostname,
-		settingsData.Servers[index].Port,
-		settingsData.Servers[index].TLS,
+		settingsData.Servers[index].GetAddresses(),
+		settingsData.Servers[index].Shuffle,
 		settingsData.Servers[index].Password,
 		settingsData.Servers[index].SASLLogin,
 		settingsData.Servers[index].SASLPassword,
+		settingsData.Servers[index].Proxy,
 		irc.NewProfile(settingsData.Servers[index].Profile.Nickname),
 		true,
 	)
@@ -236,7 +976,7 @@ func (s *WebClient) handleShowAddServer(w http.ResponseWriter, r *http.Request)
 	sse := datastar.NewSSE(w, r)
 	slog.Debug("Showing settings")
 	var data bytes.Buffer
-	err := s.templates.ExecuteTemplate(&data, "AddServerPage.gohtml", nil)
+	err := s.templates.ExecuteTemplate(&data, "AddServerPage.gohtml", ServerFormData{Server: config.Server{Port: 6697, TLS: true}})
 	if err != nil {
 		slog.Debug("Error generating template", "error", err)
 	}
@@ -263,26 +1003,39 @@ func (s *WebClient) handleAddServer(w http.ResponseWriter, r *http.Request) {
 	sasllogin := r.URL.Query().Get("sasllogin")
 	saslpassword := r.URL.Query().Get("saslpassword")
 	password := r.URL.Query().Get("password")
+	proxy := r.URL.Query().Get("proxy")
+	alternates := parseAlternates(r.URL.Query().Get("alternates"), tlsBool)
+	shuffle := r.URL.Query().Get("shuffle") != ""
 	autoConnectBool := true
 	autoConnect := r.URL.Query().Get("connect")
 	if autoConnect == "" {
 		autoConnectBool = false
 	}
 	id, _ := uniqueid.Generateid("a", 5, "s")
-	settingsData := s.settingsService.GetSettingsData()
-	settingsData.Servers = append(settingsData.Servers, config.Server{
+	server := config.Server{
 		Hostname:     hostname,
 		Port:         portInt,
 		TLS:          tlsBool,
 		Password:     password,
 		SASLLogin:    sasllogin,
 		SASLPassword: saslpassword,
+		Proxy:        proxy,
+		Alternates:   alternates,
+		Shuffle:      shuffle,
 		Profile: config.Profile{
 			Nickname: nickname,
 		},
 		AutoConnect: autoConnectBool,
 		ID:          id,
-	})
+	}
+	if proxy != "" {
+		if err = config.ValidateProxy(proxy); err != nil {
+			s.showServerForm(w, r, "AddServerPage.gohtml", server, err)
+			return
+		}
+	}
+	settingsData := s.settingsService.GetSettingsData()
+	settingsData.Servers = append(settingsData.Servers, server)
 
 	s.lock.Lock()
 	defer s.lock.Unlock()
@@ -324,6 +1077,9 @@ func (s *WebClient) handleShowEditServer(w http.ResponseWriter, r *http.Request)
 		Password:     con.Password,
 		SASLLogin:    con.SASLLogin,
 		SASLPassword: con.SASLPassword,
+		Proxy:        con.Proxy,
+		Alternates:   con.Alternates,
+		Shuffle:      con.Shuffle,
 		Profile: config.Profile{
 			Nickname: con.Profile.Nickname,
 		},
@@ -332,7 +1088,7 @@ func (s *WebClient) handleShowEditServer(w http.ResponseWriter, r *http.Request)
 	}
 
 	var data bytes.Buffer
-	err := s.templates.ExecuteTemplate(&data, "EditServerPage.gohtml", serverToEdit)
+	err := s.templates.ExecuteTemplate(&data, "EditServerPage.gohtml", ServerFormData{Server: serverToEdit})
 	if err != nil {
 		slog.Debug("Error generating template", "error", err)
 	}
@@ -360,6 +1116,9 @@ func (s *WebClient) handleEditServer(w http.ResponseWriter, r *http.Request) {
 	sasllogin := r.URL.Query().Get("sasllogin")
 	saslpassword := r.URL.Query().Get("saslpassword")
 	password := r.URL.Query().Get("password")
+	proxy := r.URL.Query().Get("proxy")
+	alternates := parseAlternates(r.URL.Query().Get("alternates"), tlsBool)
+	shuffle := r.URL.Query().Get("shuffle") != ""
 	autoConnectBool := true
 	autoConnect := r.URL.Query().Get("connect")
 	if autoConnect == "" {
@@ -367,6 +1126,25 @@ func (s *WebClient) handleEditServer(w http.ResponseWriter, r *http.Request) {
 	}
 
 	settingsData := s.settingsService.GetSettingsData()
+	if proxy != "" {
+		if err = config.ValidateProxy(proxy); err != nil {
+			s.showServerForm(w, r, "EditServerPage.gohtml", config.Server{
+				ID:           id,
+				Hostname:     hostname,
+				Port:         portInt,
+				TLS:          tlsBool,
+				Password:     password,
+				SASLLogin:    sasllogin,
+				SASLPassword: saslpassword,
+				Proxy:        proxy,
+				Alternates:   alternates,
+				Shuffle:      shuffle,
+				Profile:      config.Profile{Nickname: nickname},
+				AutoConnect:  autoConnectBool,
+			}, err)
+			return
+		}
+	}
 	for i := range settingsData.Servers {
 		if settingsData.Servers[i].ID == id {
 			settingsData.Servers[i].Hostname = hostname
@@ -375,6 +1153,9 @@ func (s *WebClient) handleEditServer(w http.ResponseWriter, r *http.Request) {
 			settingsData.Servers[i].Password = password
 			settingsData.Servers[i].SASLLogin = sasllogin
 			settingsData.Servers[i].SASLPassword = saslpassword
+			settingsData.Servers[i].Proxy = proxy
+			settingsData.Servers[i].Alternates = alternates
+			settingsData.Servers[i].Shuffle = shuffle
 			settingsData.Servers[i].Profile.Nickname = nickname
 			settingsData.Servers[i].AutoConnect = autoConnectBool
 		}
@@ -396,6 +1177,46 @@ func (s *WebClient) handleEditServer(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// ServerFormData is shown on the add and edit server pages, Error is set when what was entered couldn't be saved
+type ServerFormData struct {
+	config.Server
+	Error string
+}
+
+// showServerForm shows the add or edit server page again with an error, keeping what was entered
+func (s *WebClient) showServerForm(w http.ResponseWriter, r *http.Request, page string, server config.Server, formErr error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	sse := datastar.NewSSE(w, r)
+	var data bytes.Buffer
+	err := s.templates.ExecuteTemplate(&data, page, ServerFormData{Server: server, Error: formErr.Error()})
+	if err != nil {
+		slog.Debug("Error generating template", "error", err)
+	}
+	err = sse.MergeFragments(data.String())
+	if err != nil {
+		slog.Debug("Error merging fragments", "error", err)
+		return
+	}
+}
+
+// parseAlternates parses alternate servers entered one per line, skipping any that are invalid
+func parseAlternates(input string, defaultTLS bool) []config.ServerAddress {
+	var alternates []config.ServerAddress
+	for _, line := range strings.Split(input, "\n") {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		address, err := config.ParseServerAddress(line, defaultTLS)
+		if err != nil {
+			slog.Debug("Ignoring invalid alternate server", "address", line, "error", err)
+			continue
+		}
+		alternates = append(alternates, address)
+	}
+	return alternates
+}
+
 func (s *WebClient) handleSaveSettings(w http.ResponseWriter, r *http.Request) {
 	s.lock.Lock()
 	defer s.lock.Unlock()
@@ -406,16 +1227,24 @@ func (s *WebClient) handleSaveSettings(w http.ResponseWriter, r *http.Request) {
 	if theme == "" {
 		theme = "auto"
 	}
+	proxy := r.URL.Query().Get("proxy")
+	if err := config.ValidateProxy(proxy); proxy != "" && err != nil {
+		slog.Error("Invalid default proxy", "error", err)
+		proxy = s.conf.Connection.Proxy
+	}
 
 	settingsData := s.settingsService.GetSettingsData()
 	settingsData.TimestampFormat = timestampFormat
 	settingsData.ShowNicklist = showNicklist
 	settingsData.Theme = theme
+	settingsData.Proxy = proxy
 
 	err := s.settingsService.SaveSettingsToConfig()
 	if err != nil {
 		slog.Error("Error saving config", "error", err)
 	}
+	s.connectionManager.SetConnectionSettings(s.conf.Connection)
+	s.connectionManager.GetHighlightRules().SetRules(s.conf.Highlights)
 
 	sse := datastar.NewSSE(w, r)
 	var data bytes.Buffer
@@ -472,14 +1301,15 @@ func (s *WebClient) handleChangeChannel(w http.ResponseWriter, r *http.Request)
 			slog.Debug("Invalid change channel call, unknown channel or private message", "server", serverID, "id", channelID)
 			return
 		}
-		s.setActiveWindow(privateMessage.Window)
+		s.switchWindow(w, r, privateMessage.Window)
 		slog.Debug("Changing Window to private message", "window", privateMessage.Window.GetID())
 	} else {
-		s.setActiveWindow(channel.Window)
+		s.switchWindow(w, r, channel.Window)
 		slog.Debug("Changing Window to channel", "window", channel.Window.GetID())
 	}
 	s.updateURL(w, r)
 	s.UpdateUI(w, r)
+	s.scrollToMessage(w, r)
 }
 
 func (s *WebClient) handleServer(w http.ResponseWriter, r *http.Request) {
@@ -503,10 +1333,40 @@ func (s *WebClient) handleChangeServer(w http.ResponseWriter, r *http.Request) {
 		slog.Debug("Invalid change server call, unknown server", "server", serverID)
 		return
 	}
-	s.setActiveWindow(connection.GetWindow())
+	s.switchWindow(w, r, connection.GetWindow())
 	slog.Debug("Changing Window", "window", connection.GetID())
 	s.updateURL(w, r)
 	s.UpdateUI(w, r)
+	s.scrollToMessage(w, r)
+}
+
+func (s *WebClient) handleMentions(w http.ResponseWriter, r *http.Request) {
+	s.setActiveWindow(s.connectionManager.GetMentions())
+	slog.Debug("Changing Window", "window", irc.MentionsID)
+	s.handleIndex(w, r)
+}
+
+func (s *WebClient) handleChangeMentions(w http.ResponseWriter, r *http.Request) {
+	s.switchWindow(w, r, s.connectionManager.GetMentions())
+	slog.Debug("Changing Window", "window", irc.MentionsID)
+	s.updateURL(w, r)
+	s.UpdateUI(w, r)
+}
+
+// scrollToMessage scrolls to and marks the message given in the request, used when clicking through from the
+// mentions window
+func (s *WebClient) scrollToMessage(w http.ResponseWriter, r *http.Request) {
+	messageID := r.URL.Query().Get("message")
+	if messageID == "" {
+		return
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	sse := datastar.NewSSE(w, r)
+	err := sse.ExecuteScript(fmt.Sprintf(`scrollToMessage(%q)`, "m"+messageID), datastar.WithExecuteScriptAutoRemove(true))
+	if err != nil {
+		slog.Debug("Error scrolling to message", "error", err)
+	}
 }
 
 func (s *WebClient) handleInput(w http.ResponseWriter, r *http.Request) {
@@ -524,9 +1384,11 @@ func (s *WebClient) handleInput(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	s.inputHistoryService.AddToHistory(input)
+	window := s.getActiveWindow()
+	s.inputHistoryService.AddToHistory(inputKey(window), input)
+	s.inputHistoryService.SetDraft(inputKey(window), "")
 
-	s.commands.Execute(s.connectionManager, s.getActiveWindow(), input)
+	s.commands.Execute(s.connectionManager, window, input)
 	s.lock.Lock()
 	sse := datastar.NewSSE(w, r)
 	var data bytes.Buffer
@@ -540,7 +1402,7 @@ func (s *WebClient) handleInput(w http.ResponseWriter, r *http.Request) {
 		s.lock.Unlock()
 		return
 	}
-	err = sse.MergeSignals([]byte("{input: ''}"))
+	err = sse.MarshalAndMergeSignals(&inputValues{Input: s.inputHistoryService.GetDraft(inputKey(s.getActiveWindow()))})
 	if err != nil {
 		slog.Debug("Error merging fragments", "error", err)
 		s.lock.Unlock()
@@ -660,7 +1522,7 @@ func (s *WebClient) handleUpload(w http.ResponseWriter, r *http.Request) {
 }
 
 func (s *WebClient) handleJoin(w http.ResponseWriter, r *http.Request) {
-	if s.getActiveWindow() == nil {
+	if s.getActiveWindow() == nil || s.getActiveWindow().GetServer() == nil {
 		return
 	}
 	err := s.getActiveWindow().GetServer().JoinChannel(r.URL.Query().Get("channel"), r.URL.Query().Get("key"))
@@ -684,7 +1546,7 @@ func (s *WebClient) handleJoin(w http.ResponseWriter, r *http.Request) {
 }
 
 func (s *WebClient) handlePart(w http.ResponseWriter, r *http.Request) {
-	if s.getActiveWindow() == nil {
+	if s.getActiveWindow() == nil || s.getActiveWindow().GetServer() == nil {
 		return
 	}
 	err := s.getActiveWindow().GetServer().PartChannel(r.URL.Query().Get("channel"))
@@ -696,12 +1558,12 @@ func (s *WebClient) handlePart(w http.ResponseWriter, r *http.Request) {
 }
 
 func (s *WebClient) handleNextWindowUp(w http.ResponseWriter, r *http.Request) {
-	s.changeWindow(-1)
+	s.changeWindow(w, r, -1)
 	s.updateURL(w, r)
 	s.UpdateUI(w, r)
 }
 func (s *WebClient) handleNextWindowDown(w http.ResponseWriter, r *http.Request) {
-	s.changeWindow(+1)
+	s.changeWindow(w, r, +1)
 	s.updateURL(w, r)
 	s.UpdateUI(w, r)
 }
@@ -712,25 +1574,25 @@ func (s *WebClient) updateURL(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	sse := datastar.NewSSE(w, r)
-	if activeWindow.IsServer() {
+	if activeWindow.IsServer() || activeWindow.IsMentions() {
 		_ = sse.ExecuteScript("window.history.replaceState({}, '', '/s/"+activeWindow.GetID()+"')", datastar.WithExecuteScriptAutoRemove(true))
 	} else {
 		_ = sse.ExecuteScript("window.history.replaceState({}, '', '/s/"+activeWindow.GetServer().GetID()+"/"+url.QueryEscape(activeWindow.GetName())+"')", datastar.WithExecuteScriptAutoRemove(true))
 	}
 }
 
-func (s *WebClient) changeWindow(change int) {
+func (s *WebClient) changeWindow(w http.ResponseWriter, r *http.Request, change int) {
 	serverList := s.getServerList()
 	index := slices.IndexFunc(serverList.OrderedList, func(item *services.ServerListItem) bool {
 		return item.Window == s.getActiveWindow()
 	})
 	if len(serverList.OrderedList) > 0 {
 		if index+change < 0 {
-			s.setActiveWindow(serverList.OrderedList[len(serverList.OrderedList)-1].Window)
+			s.switchWindow(w, r, serverList.OrderedList[len(serverList.OrderedList)-1].Window)
 		} else if index+change >= len(serverList.OrderedList) {
-			s.setActiveWindow(serverList.OrderedList[0].Window)
+			s.switchWindow(w, r, serverList.OrderedList[0].Window)
 		} else {
-			s.setActiveWindow(serverList.OrderedList[index+change].Window)
+			s.switchWindow(w, r, serverList.OrderedList[index+change].Window)
 		}
 	}
 }
@@ -755,6 +1617,9 @@ func (s *WebClient) handleTab(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	tc := aw.GetTabCompleter()
+	if tc == nil {
+		return
+	}
 	input, position := tc.Complete(data.Input, data.Position)
 	sse := datastar.NewSSE(w, r)
 	output := outputValues{
@@ -787,12 +1652,13 @@ func (s *WebClient) handleUpdateNicklist(_ http.ResponseWriter, r *http.Request)
 }
 
 func (s *WebClient) handleHistoryUp(w http.ResponseWriter, r *http.Request) {
-	if s.inputHistoryService.GetHistoryLength() == 0 {
+	key := inputKey(s.getActiveWindow())
+	if s.inputHistoryService.GetHistoryLength(key) == 0 {
 		return
 	}
 
 	var currentInput string
-	if s.inputHistoryService.GetCurrentPosition() == -1 {
+	if s.inputHistoryService.GetCurrentPosition(key) == -1 {
 		inputData := &inputValues{}
 		err := datastar.ReadSignals(r, inputData)
 		if err != nil {
@@ -802,7 +1668,7 @@ func (s *WebClient) handleHistoryUp(w http.ResponseWriter, r *http.Request) {
 		currentInput = inputData.Input
 	}
 
-	historyItem := s.inputHistoryService.NavigateUp(currentInput)
+	historyItem := s.inputHistoryService.NavigateUp(key, currentInput)
 	inputData := &inputValues{
 		Input: historyItem,
 	}
@@ -815,11 +1681,12 @@ func (s *WebClient) handleHistoryUp(w http.ResponseWriter, r *http.Request) {
 }
 
 func (s *WebClient) handleHistoryDown(w http.ResponseWriter, r *http.Request) {
-	if s.inputHistoryService.GetHistoryLength() == 0 || s.inputHistoryService.GetCurrentPosition() == -1 {
+	key := inputKey(s.getActiveWindow())
+	if s.inputHistoryService.GetHistoryLength(key) == 0 || s.inputHistoryService.GetCurrentPosition(key) == -1 {
 		return
 	}
 
-	historyItem := s.inputHistoryService.NavigateDown()
+	historyItem := s.inputHistoryService.NavigateDown(key)
 	inputData := &inputValues{
 		Input: historyItem,
 	}
@@ -830,6 +1697,41 @@ func (s *WebClient) handleHistoryDown(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// inputKey identifies a window's input history and draft, it stays the same across restarts so history can be saved
+func inputKey(window *irc.Window) string {
+	switch {
+	case window == nil:
+		return ""
+	case window.IsServer() || window.IsMentions():
+		return window.GetID()
+	default:
+		return window.GetServer().GetID() + "/" + strings.ToLower(window.GetName())
+	}
+}
+
+// switchWindow makes window active, keeping the unsent input of the previous window as its draft and replacing the
+// input with the new window's draft
+func (s *WebClient) switchWindow(w http.ResponseWriter, r *http.Request, window *irc.Window) {
+	inputData := &inputValues{}
+	if err := datastar.ReadSignals(r, inputData); err != nil {
+		slog.Debug("Error reading input", "error", err)
+		s.setActiveWindow(window)
+		return
+	}
+	previous := s.getActiveWindow()
+	s.inputHistoryService.SetDraft(inputKey(previous), inputData.Input)
+	s.inputHistoryService.ResetPosition(inputKey(previous))
+	s.setActiveWindow(window)
+	if previous == window {
+		return
+	}
+	sse := datastar.NewSSE(w, r)
+	err := sse.MarshalAndMergeSignals(&inputValues{Input: s.inputHistoryService.GetDraft(inputKey(window))})
+	if err != nil {
+		slog.Debug("Error merging signals", "error", err)
+	}
+}
+
 func (s *WebClient) handleNotificationClick(w http.ResponseWriter, r *http.Request) {
 	serverID := r.URL.Query().Get("serverId")
 	source := r.URL.Query().Get("source")
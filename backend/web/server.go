@@ -9,6 +9,7 @@ import (
 	"github.com/greboid/tithon/irc"
 	"github.com/greboid/tithon/services"
 	"html/template"
+	"io/fs"
 	"log/slog"
 	"net"
 	"net/http"
@@ -33,6 +34,7 @@ type WebClient struct {
 	commands            *irc.CommandManager
 	fixedPort           int
 	templates           *template.Template
+	static              fs.FS
 	pendingUpdate       atomic.Bool
 	windowChanged       atomic.Bool
 	uiUpdate            atomic.Bool
@@ -44,6 +46,11 @@ type WebClient struct {
 	notificationService *services.NotificationService
 	settingsService     *services.SettingsService
 	inputHistoryService *services.InputHistoryService
+	listDialog          *ListDialog
+	lastListContent     string
+	showChannelList     chan string
+	channelListDialog   *ChannelListDialog
+	lastChannelList     string
 }
 
 type inputValues struct {
@@ -78,6 +85,7 @@ func NewWebClient(
 		notificationService: notificationService,
 		settingsService:     settingsService,
 		inputHistoryService: inputHistoryService,
+		showChannelList:     make(chan string, 1),
 	}
 	client.addRoutes(mux)
 	return client
@@ -144,6 +152,14 @@ func (s *WebClient) getActiveWindow() *irc.Window {
 	return nil
 }
 
+// ShowChannelList opens the channel list for the server once the UI is next updated
+func (s *WebClient) ShowChannelList(server *irc.Server) {
+	select {
+	case s.showChannelList <- server.GetID():
+	default:
+	}
+}
+
 func (s *WebClient) SetPendingUpdate() {
 	s.pendingUpdate.Store(true)
 }
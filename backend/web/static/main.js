@@ -36,3 +36,26 @@ const notify = (title, text, popup, sound, noise, serverID, source) => {
 }
 
 Notification.requestPermission().catch(e => console.log(e))
+
+const scrollToMessage = (id) => {
+  const message = document.getElementById(id)
+  if (!message) return
+  document.querySelectorAll('#messages p.selected').forEach(p => p.classList.remove('selected'))
+  message.classList.add('selected')
+  message.firstElementChild?.scrollIntoView({block: "center"})
+}
+
+// Grouped events are re-rendered as more users are added, keep the ones that have been expanded open
+const openGroups = new Set()
+
+document.addEventListener('toggle', (e) => {
+  const group = e.target.dataset?.group
+  if (!group) return
+  e.target.open ? openGroups.add(group) : openGroups.delete(group)
+}, true)
+
+new MutationObserver(() => {
+  openGroups.forEach(group => {
+    document.querySelectorAll(`details[data-group="${group}"]:not([open])`).forEach(details => details.open = true)
+  })
+}).observe(document.documentElement, {subtree: true, childList: true, attributes: true, attributeFilter: ['open']})
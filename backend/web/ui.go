@@ -2,11 +2,18 @@ package web
 
 import (
 	"bytes"
+	"cmp"
 	"encoding/json"
+	"github.com/greboid/tithon/config"
+	"github.com/greboid/tithon/irc"
 	datastar "github.com/starfederation/datastar/sdk/go"
+	"html/template"
 	"io"
+	"io/fs"
 	"log/slog"
 	"net/http"
+	"strings"
+	"time"
 )
 
 func (s *WebClient) UpdateUI(w http.ResponseWriter, r *http.Request) {
@@ -25,14 +32,19 @@ func (s *WebClient) UpdateUI(w http.ResponseWriter, r *http.Request) {
 	s.outputTemplate(&data, "Serverlist.gohtml", s.getServerList())
 	s.outputTemplate(&data, "Nicksettings.gohtml", nil)
 	if s.getActiveWindow() == nil {
-		s.outputTemplate(&data, "WindowInfo.gohtml", "")
-		s.outputTemplate(&data, "Messages.gohtml", nil)
+		s.outputTemplate(&data, "WindowInfo.gohtml", WindowInfo{})
+		s.outputTemplate(&data, "Messages.gohtml", MessageList{})
 		s.outputTemplate(&data, "Nicklist.gohtml", nil)
 	} else {
-		s.outputTemplate(&data, "WindowInfo.gohtml", s.getActiveWindow().GetTitle())
-		s.outputTemplate(&data, "Messages.gohtml", s.getActiveWindow().GetMessages())
+		s.outputTemplate(&data, "WindowInfo.gohtml", s.getWindowInfo(s.getActiveWindow()))
+		s.outputTemplate(&data, "Messages.gohtml", MessageList{
+			Messages:   s.getActiveWindow().GetDisplayMessages(),
+			ShowSource: s.getActiveWindow().IsMentions(),
+		})
 		s.outputTemplate(&data, "Nicklist.gohtml", s.getActiveWindow().GetUsers())
 	}
+	s.outputListContent(&data, false)
+	s.outputChannelListContent(&data, false)
 
 	err = sse.MergeFragments(data.String())
 	if err != nil {
@@ -63,6 +75,205 @@ func (s *WebClient) UpdateUI(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+type MessageList struct {
+	Messages []*irc.Message
+	// ShowSource adds a link to the window each message was sent to
+	ShowSource bool
+}
+
+// searchLimit is the maximum number of results shown on the search page
+const searchLimit = 500
+
+type SearchData struct {
+	Query    irc.SearchQuery
+	From     string
+	To       string
+	Results  []irc.SearchResult
+	Searched bool
+	Error    string
+}
+
+type NickData struct {
+	Server string
+	Nick   string
+}
+
+// exportStylesheets are included in HTML exports so they look the same as the client
+var exportStylesheets = []string{"reset.css", "main.css"}
+
+type ExportData struct {
+	Title    string
+	CSS      template.CSS
+	Messages MessageList
+}
+
+// ExportHTML renders messages as a standalone page with the stylesheets inlined
+func (s *WebClient) ExportHTML(window *irc.Window, messages []*irc.Message) ([]byte, error) {
+	var css strings.Builder
+	for _, stylesheet := range exportStylesheets {
+		content, err := fs.ReadFile(s.static, stylesheet)
+		if err != nil {
+			return nil, err
+		}
+		css.Write(content)
+	}
+	title := window.GetName()
+	if server := window.GetServer(); server != nil && !window.IsServer() {
+		title = server.GetName() + " " + title
+	}
+	s.templateLock.Lock()
+	templates := s.templates
+	s.templateLock.Unlock()
+	var data bytes.Buffer
+	err := templates.ExecuteTemplate(&data, "Export.gohtml", ExportData{
+		Title:    title,
+		CSS:      template.CSS(css.String()),
+		Messages: MessageList{Messages: messages},
+	})
+	return data.Bytes(), err
+}
+
+type WindowInfo struct {
+	Title  string
+	Status *irc.ConnectionStatus
+	// ServerID is set for servers, channels and queries, Channel for channels and Window for channels and queries,
+	// they're used to open the channel list, the channel's settings and the window's settings
+	ServerID string
+	Channel  string
+	Window   string
+}
+
+func (s *WebClient) getWindowInfo(window *irc.Window) WindowInfo {
+	info := WindowInfo{Title: window.GetTitle()}
+	if server := window.GetServer(); server != nil {
+		status := server.GetConnectionStatus()
+		info.Status = &status
+		if window.IsServer() {
+			info.ServerID = server.GetID()
+		} else if window.IsChannel() {
+			info.ServerID = server.GetID()
+			info.Channel = window.GetName()
+			info.Window = window.GetName()
+		} else if window.IsQuery() {
+			info.ServerID = server.GetID()
+			info.Window = window.GetName()
+		}
+	}
+	return info
+}
+
+type ChannelSettingsData struct {
+	ServerID string
+	Channel  string
+	Topic    string
+	// OriginalTopic is the topic when the dialog was opened, the topic is only changed if the user edits it
+	OriginalTopic string
+	Created       string
+	Settings      []irc.ChannelSetting
+	Privileges    []irc.ChannelPrivilege
+	Error         string
+}
+
+func getChannelSettingsData(connection *irc.Server, channel *irc.Channel) ChannelSettingsData {
+	data := ChannelSettingsData{
+		ServerID:      connection.GetID(),
+		Channel:       channel.GetName(),
+		Topic:         channel.GetTopic().GetText(),
+		OriginalTopic: channel.GetTopic().GetText(),
+		Settings:      connection.GetChannelSettings(channel),
+		Privileges:    connection.GetChannelPrivileges(channel),
+	}
+	if created := channel.GetCreated(); !created.IsZero() {
+		data.Created = created.Format(time.DateTime)
+	}
+	return data
+}
+
+type WindowSettingsData struct {
+	ServerID   string
+	Window     string
+	Notify     string
+	Muted      bool
+	Membership string
+	Highlights string
+	Logging    bool
+	Levels     []string
+	Modes      []string
+	Error      string
+}
+
+func getWindowSettingsData(serverID string, settings config.WindowSettings) WindowSettingsData {
+	return WindowSettingsData{
+		ServerID:   serverID,
+		Window:     settings.Window,
+		Notify:     cmp.Or(settings.Notify, config.NotifyAll),
+		Muted:      settings.Muted,
+		Membership: settings.Membership,
+		Highlights: strings.Join(settings.Highlights, ", "),
+		Logging:    !settings.NoLog,
+		Levels:     config.NotifyLevels,
+		Modes:      config.MembershipModes,
+	}
+}
+
+// ListDialog is the channel list that's open in the lists dialog
+type ListDialog struct {
+	ServerID string
+	Channel  string
+	Mode     string
+}
+
+type ListData struct {
+	ServerID string
+	Channel  string
+	Mode     string
+	Modes    []irc.ListMode
+	Entries  []*irc.ListEntry
+	Loaded   bool
+	Loading  bool
+}
+
+func (s *WebClient) getListData(dialog *ListDialog) (ListData, error) {
+	connection := s.connectionManager.GetConnection(dialog.ServerID)
+	if connection == nil {
+		return ListData{}, irc.ErrNoServer
+	}
+	channel, err := connection.GetChannelByName(dialog.Channel)
+	if err != nil {
+		return ListData{}, err
+	}
+	entries, loaded := channel.GetList(dialog.Mode)
+	return ListData{
+		ServerID: dialog.ServerID,
+		Channel:  dialog.Channel,
+		Mode:     dialog.Mode,
+		Modes:    connection.GetListModes(),
+		Entries:  entries,
+		Loaded:   loaded,
+		Loading:  channel.IsListLoading(dialog.Mode),
+	}, nil
+}
+
+// outputListContent renders the open lists dialog, it's skipped if nothing has changed so the form isn't reset
+// while it's being filled in
+func (s *WebClient) outputListContent(wr io.Writer, force bool) {
+	if s.listDialog == nil {
+		return
+	}
+	data, err := s.getListData(s.listDialog)
+	if err != nil {
+		slog.Debug("Unable to get list", "error", err)
+		return
+	}
+	var content bytes.Buffer
+	s.outputTemplate(&content, "ListContent.gohtml", data)
+	if !force && content.String() == s.lastListContent {
+		return
+	}
+	s.lastListContent = content.String()
+	_, _ = wr.Write(content.Bytes())
+}
+
 func (s *WebClient) outputTemplate(wr io.Writer, name string, data any) {
 	s.templateLock.Lock()
 	defer s.templateLock.Unlock()
@@ -72,3 +283,51 @@ func (s *WebClient) outputTemplate(wr io.Writer, name string, data any) {
 		return
 	}
 }
+
+// channelListLimit is the most channels shown in the channel list at once, the search narrows it down
+const channelListLimit = 500
+
+// ChannelListDialog is the server whose channel list is open, and how it's being searched and sorted
+type ChannelListDialog struct {
+	ServerID string
+	Search   string
+	Sort     string
+}
+
+type ChannelListPageData struct {
+	Server string
+	Filter string
+}
+
+type ChannelListData struct {
+	Sort     string
+	Entries  []irc.ChannelListEntry
+	Total    int
+	Loading  bool
+	Searched bool
+}
+
+// outputChannelListContent renders the open channel list, it's skipped if nothing has changed
+func (s *WebClient) outputChannelListContent(wr io.Writer, force bool) {
+	if s.channelListDialog == nil {
+		return
+	}
+	connection := s.connectionManager.GetConnection(s.channelListDialog.ServerID)
+	if connection == nil {
+		return
+	}
+	list := connection.GetChannelList()
+	data := ChannelListData{
+		Sort:     s.channelListDialog.Sort,
+		Loading:  list.IsLoading(),
+		Searched: s.channelListDialog.Search != "",
+	}
+	data.Entries, data.Total = list.Search(s.channelListDialog.Search, s.channelListDialog.Sort, channelListLimit)
+	var content bytes.Buffer
+	s.outputTemplate(&content, "ChannelListContent.gohtml", data)
+	if !force && content.String() == s.lastChannelList {
+		return
+	}
+	s.lastChannelList = content.String()
+	_, _ = wr.Write(content.Bytes())
+}
@@ -4,6 +4,7 @@ import (
 	"errors"
 	"github.com/fsnotify/fsnotify"
 	"github.com/greboid/tithon/config"
+	"github.com/greboid/tithon/irc"
 	"html/template"
 	"io/fs"
 	"log/slog"
@@ -132,9 +133,18 @@ func getTemplateFuncs() template.FuncMap {
 		"unsafe": func(input string) template.HTML {
 			return template.HTML(input)
 		},
+		"windowLink": windowLink,
 	}
 }
 
+// windowLink returns the path used to link to a window, matching the links in the server list
+func windowLink(window *irc.Window) string {
+	if window.IsServer() || window.IsMentions() || window.GetServer() == nil {
+		return window.GetID()
+	}
+	return window.GetServer().GetID() + "/" + window.GetID()
+}
+
 func (s *WebClient) noCacheMiddleware(h http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Cache-Control", "no-cache, no-store, must-revalidate, max-age=0")